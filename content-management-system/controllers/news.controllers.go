@@ -1,20 +1,33 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/content-management-system/models"
 	"github.com/joey1123455/news-aggregator-service/content-management-system/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type NewsController struct {
-	service services.ArticleServices
+	service  services.ArticleServices
+	stories  services.StoryServices
+	trending services.TrendingServices
 }
 
-func NewNewsController(service services.ArticleServices) NewsController {
+func NewNewsController(service services.ArticleServices, stories services.StoryServices, trending services.TrendingServices) NewsController {
 	return NewsController{
-		service: service,
+		service:  service,
+		stories:  stories,
+		trending: trending,
 	}
 }
 
@@ -24,8 +37,20 @@ func NewNewsController(service services.ArticleServices) NewsController {
 // @Produce json
 // @Param page query string true "amount of page results to return"
 // @Param limit query string true "limit per page"
-// @Success 201 {object} SearchResponse
-// @Failure 404 {object} string "querry not passed"
+// @Param from query string false "earliest publish date, RFC 3339 or YYYY-MM-DD"
+// @Param to query string false "latest publish date, RFC 3339 or YYYY-MM-DD"
+// @Param sort query string false "published_at (default), ingested_at, reading_time, word_count or reading_ease"
+// @Param min_reading_time query string false "only articles taking at least this many minutes to read"
+// @Param max_reading_time query string false "only articles read in at most this many minutes, articles without a reading time are left out"
+// @Param max_grade query string false "only articles at or below this Flesch-Kincaid grade level"
+// @Param entity query string false "only articles mentioning this entity id, can be repeated"
+// @Param tone query string false "only articles with this tone, very_negative to very_positive, can be repeated"
+// @Param min_sentiment query string false "hide articles with a sentiment below this, between -1 and 1"
+// @Param summary query string false "long (default) or short summary"
+// @Param thumbnail_width query string false "pick the thumbnail for this display width in pixels, defaults to the widest"
+// @Param language query string false "only articles in this ISO 639-1 language, can be repeated, defaults to the profile languages"
+// @Success 201 {object} FeedResponse
+// @Failure 400 {object} string "invalid filter"
 // @Failure 502 {object} string "error message"
 // @Router /news/feed [get]
 func (nc NewsController) Feed(ctx *gin.Context) {
@@ -45,33 +70,414 @@ func (nc NewsController) Feed(ctx *gin.Context) {
 		return
 	}
 
-	posts, err := nc.service.NewsFeed(prefrence, intLimit, intPage)
+	filter := models.FeedFilter{
+		Categories: prefrence,
+		Entities:   ctx.QueryArray("entity"),
+		Tones:      ctx.QueryArray("tone"),
+		Languages:  preferredLanguages(ctx),
+		SortBy:     ctx.DefaultQuery("sort", models.SortPublished),
+		Limit:      intLimit,
+		Page:       intPage,
+	}
+
+	if _, ok := models.SortOrders[filter.SortBy]; !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "sort must be published_at, ingested_at, reading_time, word_count or reading_ease"})
+		return
+	}
+
+	for key, bound := range map[string]*int{"min_reading_time": &filter.MinReadingTime, "max_reading_time": &filter.MaxReadingTime} {
+		value := ctx.Query(key)
+		if value == "" {
+			continue
+		}
+		if *bound, err = strconv.Atoi(value); err != nil || *bound < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": key + " must be a positive number of minutes"})
+			return
+		}
+	}
+
+	if value := ctx.Query("max_grade"); value != "" {
+		maxGrade, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "max_grade must be a number"})
+			return
+		}
+		filter.MaxGradeLevel = &maxGrade
+	}
+
+	summary := ctx.DefaultQuery("summary", models.SummaryLong)
+	if summary != models.SummaryLong && summary != models.SummaryShort {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "summary must be long or short"})
+		return
+	}
+
+	width, err := thumbnailWidth(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	for _, tone := range filter.Tones {
+		if !slices.Contains(models.Tones, tone) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "tone must be one of " + strings.Join(models.Tones, ", ")})
+			return
+		}
+	}
+
+	if value := ctx.Query("min_sentiment"); value != "" {
+		minSentiment, err := strconv.ParseFloat(value, 64)
+		if err != nil || minSentiment < -1 || minSentiment > 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "min_sentiment must be a number between -1 and 1"})
+			return
+		}
+		filter.MinSentiment = &minSentiment
+	}
+
+	if filter.From, err = parseDateQuery(ctx, "from", false); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	if filter.To, err = parseDateQuery(ctx, "to", true); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	posts, err := nc.service.NewsFeed(filter)
 	if err != nil {
 		ctx.JSON(http.StatusBadGateway, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
+	for i := range posts {
+		posts[i].UseSummary(summary)
+		posts[i].UseImage(width)
+	}
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(posts), "articles": posts})
 }
 
 // @Summary Search
-// @Description Searches database for articles containing the required keywords.
+// @Description Searches database for articles containing the required keywords, or mentioning the given entities.
 // @Security ApiKeyAuth
 // @Produce json
-// @Param q query string true "Search query"
+// @Param q query string false "Search query, required without entity"
+// @Param entity query string false "only articles mentioning this entity id, can be repeated"
+// @Param language query string false "only articles in this ISO 639-1 language, can be repeated, defaults to the profile languages"
+// @Param thumbnail_width query string false "pick the thumbnail for this display width in pixels, defaults to the widest"
 // @Success 201 {object} SearchResponse
 // @Failure 404 {object} string "querry not passed"
 // @Failure 502 {object} string "error message"
 // @Router /news/search [get]
 func (nc NewsController) Search(ctx *gin.Context) {
 	query := ctx.Query("q")
-	if query == "" {
+	entities := ctx.QueryArray("entity")
+	if query == "" && len(entities) == 0 {
 		ctx.JSON(http.StatusBadRequest, gin.H{"status": "failed", "message": "querry not passed"})
+		return
+	}
+
+	width, err := thumbnailWidth(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "failed", "message": err.Error()})
+		return
 	}
 
-	articles, err := nc.service.Search(query)
+	articles, err := nc.service.Search(models.SearchFilter{
+		Query:     query,
+		Entities:  entities,
+		Languages: preferredLanguages(ctx),
+	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "failed", "message": err.Error()})
 		return
 	}
+	for i := range articles {
+		articles[i].UseImage(width)
+	}
 	ctx.JSON(http.StatusOK, gin.H{"status": "failed", "articles": articles})
 }
+
+// @Summary Related
+// @Description Returns the articles sharing the most heavily weighted keywords with an article.
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "article id"
+// @Param limit query string false "amount of articles to return, defaults to 10"
+// @Param thumbnail_width query string false "pick the thumbnail for this display width in pixels, defaults to the widest"
+// @Success 200 {object} SearchResponse
+// @Failure 400 {object} string "invalid article id"
+// @Failure 404 {object} string "article not found"
+// @Failure 500 {object} string "error message"
+// @Router /news/related/{id} [get]
+func (nc NewsController) Related(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "limit must be a positive number"})
+		return
+	}
+
+	width, err := thumbnailWidth(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	articles, err := nc.service.Related(ctx.Param("id"), limit)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "article not found"})
+		return
+	}
+	if errors.Is(err, primitive.ErrInvalidHex) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "invalid article id"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	for i := range articles {
+		articles[i].UseImage(width)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "articles": articles})
+}
+
+// @Summary Entities
+// @Description Looks up the people, organisations and locations with a word of their name starting with the query, with the number of articles mentioning them.
+// @Security ApiKeyAuth
+// @Produce json
+// @Param q query string true "start of a word of the entity name"
+// @Param type query string false "person, organisation or location"
+// @Param limit query string false "amount of entities to return, defaults to 20"
+// @Success 200 {object} EntitiesResponse
+// @Failure 400 {object} string "invalid filter"
+// @Failure 500 {object} string "error message"
+// @Router /news/entities [get]
+func (nc NewsController) Entities(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "limit must be a positive number"})
+		return
+	}
+
+	query := strings.TrimSpace(ctx.Query("q"))
+	if query == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "q is required"})
+		return
+	}
+
+	kind := ctx.Query("type")
+	if kind != "" && !slices.Contains(models.EntityTypes, kind) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "type must be person, organisation or location"})
+		return
+	}
+
+	entities, err := nc.service.Entities(query, kind, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(entities), "entities": entities})
+}
+
+// @Summary Sentiment
+// @Description Returns the average sentiment and tone counts of the articles in every category, for the last 7 days unless a range is given.
+// @Security ApiKeyAuth
+// @Produce json
+// @Param from query string false "earliest publish date, RFC 3339 or YYYY-MM-DD"
+// @Param to query string false "latest publish date, RFC 3339 or YYYY-MM-DD"
+// @Success 200 {object} SentimentResponse
+// @Failure 400 {object} string "invalid range"
+// @Failure 500 {object} string "error message"
+// @Router /news/sentiment [get]
+func (nc NewsController) Sentiment(ctx *gin.Context) {
+	to, err := parseDateQuery(ctx, "to", true)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+
+	from, err := parseDateQuery(ctx, "from", false)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	if from.IsZero() {
+		from = to.Add(-7 * 24 * time.Hour)
+	}
+	if from.After(to) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "from must be before to"})
+		return
+	}
+
+	categories, err := nc.service.SentimentByCategory(from, to)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "from": from, "to": to, "categories": categories})
+}
+
+// @Summary Stories
+// @Description Returns the most recently updated stories, each grouping the articles that report on the same event.
+// @Security ApiKeyAuth
+// @Produce json
+// @Param page query string false "page of results, defaults to 1"
+// @Param limit query string false "limit per page, defaults to 10"
+// @Param category query string false "only stories with this category, can be repeated"
+// @Param min_sources query string false "only stories covered by at least this many sources"
+// @Success 200 {object} StoriesResponse
+// @Failure 400 {object} string "invalid filter"
+// @Failure 500 {object} string "error message"
+// @Router /news/stories [get]
+func (nc NewsController) Stories(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "page must be a positive number"})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "limit must be a positive number"})
+		return
+	}
+
+	minSources, err := strconv.Atoi(ctx.DefaultQuery("min_sources", "1"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "min_sources must be a number"})
+		return
+	}
+
+	stories, err := nc.stories.Stories(models.StoryFilter{
+		Categories: ctx.QueryArray("category"),
+		MinSources: minSources,
+		Limit:      limit,
+		Page:       page,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(stories), "stories": stories})
+}
+
+// @Summary Story
+// @Description Returns a story with its member articles, newest first.
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "story id"
+// @Param thumbnail_width query string false "pick the thumbnail for this display width in pixels, defaults to the widest"
+// @Success 200 {object} StoryResponse
+// @Failure 400 {object} string "invalid story id"
+// @Failure 404 {object} string "story not found"
+// @Failure 500 {object} string "error message"
+// @Router /news/stories/{id} [get]
+func (nc NewsController) Story(ctx *gin.Context) {
+	width, err := thumbnailWidth(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	story, articles, err := nc.stories.Story(ctx.Param("id"))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "story not found"})
+		return
+	}
+	if errors.Is(err, primitive.ErrInvalidHex) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "invalid story id"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	for i := range articles {
+		articles[i].UseImage(width)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "story": story, "articles": articles})
+}
+
+// @Summary Trending
+// @Description Returns the keywords, entities and stories whose coverage in the window spikes furthest above their usual rate.
+// @Security ApiKeyAuth
+// @Produce json
+// @Param window query string false "window such as 1h, 6h or 24h, defaults to 6h"
+// @Param category query string false "only articles with this category"
+// @Param limit query string false "results per kind, defaults to 10"
+// @Success 200 {object} TrendingResponse
+// @Failure 400 {object} string "unknown window"
+// @Failure 500 {object} string "error message"
+// @Router /news/trending [get]
+func (nc NewsController) Trending(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "limit must be a positive number"})
+		return
+	}
+
+	window := ctx.DefaultQuery("window", "6h")
+	trending, err := nc.trending.Trending(window, ctx.Query("category"), limit)
+	if errors.Is(err, services.ErrUnknownWindow) {
+		windows, _ := nc.trending.Windows()
+		sort.Strings(windows)
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": fmt.Sprintf("unknown window %s", window), "windows": windows})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "trending": trending})
+}
+
+// preferredLanguages returns the languages asked for in the query, or the
+// ones in the user's profile
+func preferredLanguages(ctx *gin.Context) []string {
+	if languages := ctx.QueryArray("language"); len(languages) > 0 {
+		return languages
+	}
+
+	return ctx.GetStringSlice("currentUserLanguages")
+}
+
+// thumbnailWidth reads the optional display width thumbnails are picked for
+func thumbnailWidth(ctx *gin.Context) (int, error) {
+	value := ctx.Query("thumbnail_width")
+	if value == "" {
+		return 0, nil
+	}
+
+	width, err := strconv.Atoi(value)
+	if err != nil || width < 1 {
+		return 0, errors.New("thumbnail_width must be a positive number of pixels")
+	}
+
+	return width, nil
+}
+
+// parseDateQuery reads an optional RFC 3339 or YYYY-MM-DD query parameter, a
+// bare date used as an upper bound covers the whole day
+func parseDateQuery(ctx *gin.Context, key string, endOfDay bool) (time.Time, error) {
+	value := ctx.Query(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.UTC(), nil
+	}
+
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be RFC 3339 or YYYY-MM-DD", key)
+	}
+
+	if endOfDay {
+		parsed = parsed.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	return parsed, nil
+}
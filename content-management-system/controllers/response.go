@@ -1,6 +1,10 @@
 package controllers
 
-import "github.com/joey1123455/news-aggregator-service/content-management-system/models"
+import (
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/content-management-system/models"
+)
 
 type EgFilteredRes struct {
 	Username   string           `json:"user_name"`
@@ -13,12 +17,42 @@ type Profile struct {
 }
 
 type SearchResponse struct {
-	Status   string                `json:"status"`
-	Articles []models.MongoArticle `json:"articles"`
+	Status   string           `json:"status"`
+	Articles []models.Article `json:"articles"`
 }
 
 type FeedResponse struct {
-	Status   string                `json:"status"`
-	Length   int                   `json:"results"`
-	Articles []models.MongoArticle `json:"articles"`
+	Status   string           `json:"status"`
+	Length   int              `json:"results"`
+	Articles []models.Article `json:"articles"`
+}
+
+type EntitiesResponse struct {
+	Status   string                 `json:"status"`
+	Length   int                    `json:"results"`
+	Entities []models.EntitySummary `json:"entities"`
+}
+
+type SentimentResponse struct {
+	Status     string                     `json:"status"`
+	From       time.Time                  `json:"from"`
+	To         time.Time                  `json:"to"`
+	Categories []models.CategorySentiment `json:"categories"`
+}
+
+type StoriesResponse struct {
+	Status  string         `json:"status"`
+	Length  int            `json:"results"`
+	Stories []models.Story `json:"stories"`
+}
+
+type StoryResponse struct {
+	Status   string           `json:"status"`
+	Story    models.Story     `json:"story"`
+	Articles []models.Article `json:"articles"`
+}
+
+type TrendingResponse struct {
+	Status   string          `json:"status"`
+	Trending models.Trending `json:"trending"`
 }
@@ -23,6 +23,61 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/news/entities": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Looks up the people, organisations and locations with a word of their name starting with the query, with the number of articles mentioning them.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Entities",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start of a word of the entity name",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "person, organisation or location",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "amount of entities to return, defaults to 20",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.EntitiesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "error message",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/news/feed": {
             "get": {
                 "security": [
@@ -49,22 +104,155 @@ const docTemplate = `{
                         "name": "limit",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "earliest publish date, RFC 3339 or YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "latest publish date, RFC 3339 or YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "published_at (default), ingested_at, reading_time, word_count or reading_ease",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only articles taking at least this many minutes to read",
+                        "name": "min_reading_time",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only articles read in at most this many minutes, articles without a reading time are left out",
+                        "name": "max_reading_time",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only articles at or below this Flesch-Kincaid grade level",
+                        "name": "max_grade",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only articles mentioning this entity id, can be repeated",
+                        "name": "entity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only articles with this tone, very_negative to very_positive, can be repeated",
+                        "name": "tone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hide articles with a sentiment below this, between -1 and 1",
+                        "name": "min_sentiment",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "long (default) or short summary",
+                        "name": "summary",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "pick the thumbnail for this display width in pixels, defaults to the widest",
+                        "name": "thumbnail_width",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only articles in this ISO 639-1 language, can be repeated, defaults to the profile languages",
+                        "name": "language",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.FeedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "error message",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/news/related/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the articles sharing the most heavily weighted keywords with an article.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Related",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "article id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "amount of articles to return, defaults to 10",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "pick the thumbnail for this display width in pixels, defaults to the widest",
+                        "name": "thumbnail_width",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/controllers.SearchResponse"
                         }
                     },
+                    "400": {
+                        "description": "invalid article id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
-                        "description": "querry not passed",
+                        "description": "article not found",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "502": {
+                    "500": {
                         "description": "error message",
                         "schema": {
                             "type": "string"
@@ -80,7 +268,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Searches database for articles containing the required keywords.",
+                "description": "Searches database for articles containing the required keywords, or mentioning the given entities.",
                 "produces": [
                     "application/json"
                 ],
@@ -88,10 +276,27 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Search query",
+                        "description": "Search query, required without entity",
                         "name": "q",
-                        "in": "query",
-                        "required": true
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only articles mentioning this entity id, can be repeated",
+                        "name": "entity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only articles in this ISO 639-1 language, can be repeated, defaults to the profile languages",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "pick the thumbnail for this display width in pixels, defaults to the widest",
+                        "name": "thumbnail_width",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -116,46 +321,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/profile/create": {
-            "post": {
+        "/news/sentiment": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Create a users profile.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the average sentiment and tone counts of the articles in every category, for the last 7 days unless a range is given.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Create User Profile",
+                "summary": "Sentiment",
                 "parameters": [
                     {
-                        "description": "User information for profile creation",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controllers.Profile"
-                        }
+                        "type": "string",
+                        "description": "earliest publish date, RFC 3339 or YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "latest publish date, RFC 3339 or YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controllers.Profile"
+                            "$ref": "#/definitions/controllers.SentimentResponse"
                         }
                     },
-                    "409": {
-                        "description": "profile already exists",
+                    "400": {
+                        "description": "invalid range",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "502": {
+                    "500": {
                         "description": "error message",
                         "schema": {
                             "type": "string"
@@ -164,33 +369,59 @@ const docTemplate = `{
                 }
             }
         },
-        "/profile/delete": {
-            "delete": {
+        "/news/stories": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Delete a users profile.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the most recently updated stories, each grouping the articles that report on the same event.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Delete User Profile",
+                "summary": "Stories",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "page of results, defaults to 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "limit per page, defaults to 10",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only stories with this category, can be repeated",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only stories covered by at least this many sources",
+                        "name": "min_sources",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.StoriesResponse"
+                        }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "400": {
+                        "description": "invalid filter",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "502": {
-                        "description": "Error while signing up new user",
+                    "500": {
+                        "description": "error message",
                         "schema": {
                             "type": "string"
                         }
@@ -198,36 +429,54 @@ const docTemplate = `{
                 }
             }
         },
-        "/profile/me": {
+        "/news/stories/{id}": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves a users profile.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns a story with its member articles, newest first.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Retrieve User Profile",
+                "summary": "Story",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "story id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "pick the thumbnail for this display width in pixels, defaults to the widest",
+                        "name": "thumbnail_width",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controllers.Profile"
+                            "$ref": "#/definitions/controllers.StoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid story id",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "404": {
-                        "description": "User not found",
+                        "description": "story not found",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "502": {
-                        "description": "Error while signing up new user",
+                    "500": {
+                        "description": "error message",
                         "schema": {
                             "type": "string"
                         }
@@ -235,58 +484,231 @@ const docTemplate = `{
                 }
             }
         },
-        "/profile/update": {
-            "patch": {
+        "/news/trending": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Update a users profile.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the keywords, entities and stories whose coverage in the window spikes furthest above their usual rate.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Update User Profile",
+                "summary": "Trending",
                 "parameters": [
                     {
-                        "description": "User information for profile update",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.UpdateUser"
-                        }
+                        "type": "string",
+                        "description": "window such as 1h, 6h or 24h, defaults to 6h",
+                        "name": "window",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only articles with this category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "results per kind, defaults to 10",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controllers.Profile"
+                            "$ref": "#/definitions/controllers.TrendingResponse"
                         }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "400": {
+                        "description": "unknown window",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "502": {
-                        "description": "Error while signing up new user",
+                    "500": {
+                        "description": "error message",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "controllers.EgFilteredRes": {
-            "type": "object",
+        },
+        "/profile/create": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create a users profile.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create User Profile",
+                "parameters": [
+                    {
+                        "description": "User information for profile creation",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.Profile"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.Profile"
+                        }
+                    },
+                    "409": {
+                        "description": "profile already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "error message",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/delete": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete a users profile.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete User Profile",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "Error while signing up new user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/me": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves a users profile.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Retrieve User Profile",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.Profile"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "Error while signing up new user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/update": {
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update a users profile.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update User Profile",
+                "parameters": [
+                    {
+                        "description": "User information for profile update",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateUser"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.Profile"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "Error while signing up new user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "controllers.EgFilteredRes": {
+            "type": "object",
             "properties": {
                 "prefrence": {
                     "$ref": "#/definitions/models.Prefrence"
@@ -296,6 +718,40 @@ const docTemplate = `{
                 }
             }
         },
+        "controllers.EntitiesResponse": {
+            "type": "object",
+            "properties": {
+                "entities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.EntitySummary"
+                    }
+                },
+                "results": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.FeedResponse": {
+            "type": "object",
+            "properties": {
+                "articles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Article"
+                    }
+                },
+                "results": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
         "controllers.Profile": {
             "type": "object",
             "properties": {
@@ -313,11 +769,76 @@ const docTemplate = `{
                 "articles": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.MongoArticle"
+                        "$ref": "#/definitions/models.Article"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.SentimentResponse": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CategorySentiment"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.StoriesResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "stories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Story"
+                    }
+                }
+            }
+        },
+        "controllers.StoryResponse": {
+            "type": "object",
+            "properties": {
+                "articles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Article"
                     }
                 },
                 "status": {
                     "type": "string"
+                },
+                "story": {
+                    "$ref": "#/definitions/models.Story"
+                }
+            }
+        },
+        "controllers.TrendingResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                },
+                "trending": {
+                    "$ref": "#/definitions/models.Trending"
                 }
             }
         },
@@ -336,6 +857,12 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "category_confidence": {
+                    "type": "number"
+                },
+                "category_source": {
+                    "type": "string"
+                },
                 "content": {
                     "type": "string"
                 },
@@ -354,48 +881,177 @@ const docTemplate = `{
                 "description": {
                     "type": "string"
                 },
+                "entities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Entity"
+                    }
+                },
+                "grade_level": {
+                    "type": "number"
+                },
+                "image": {
+                    "$ref": "#/definitions/models.ArticleImage"
+                },
                 "image_url": {
                     "type": "string"
                 },
+                "ingested_at": {
+                    "type": "string"
+                },
+                "keyword_weights": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.KeywordWeight"
+                    }
+                },
                 "keywords": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
+                "language": {
+                    "type": "string"
+                },
                 "link": {
                     "type": "string"
                 },
                 "pubDate": {
                     "type": "string"
                 },
+                "published_at": {
+                    "type": "string"
+                },
+                "reading_ease": {
+                    "type": "number"
+                },
+                "reading_time": {
+                    "type": "integer"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "sentiment": {
+                    "type": "number"
+                },
                 "source_id": {
                     "type": "string"
                 },
                 "source_priority": {
                     "type": "integer"
                 },
+                "story_id": {
+                    "type": "string"
+                },
+                "summary": {
+                    "type": "string"
+                },
+                "thumbnail_url": {
+                    "type": "string"
+                },
                 "title": {
                     "type": "string"
+                },
+                "tone": {
+                    "type": "string"
+                },
+                "word_count": {
+                    "type": "integer"
                 }
             }
         },
-        "models.MongoArticle": {
+        "models.ArticleImage": {
             "type": "object",
-            "required": [
-                "article",
-                "created_at",
-                "id"
-            ],
             "properties": {
-                "article": {
-                    "$ref": "#/definitions/models.Article"
+                "content_type": {
+                    "type": "string"
                 },
-                "created_at": {
+                "hash": {
+                    "type": "string"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "reused": {
+                    "type": "boolean"
+                },
+                "status": {
                     "type": "string"
                 },
+                "thumbnails": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Thumbnail"
+                    }
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CategorySentiment": {
+            "type": "object",
+            "properties": {
+                "articles": {
+                    "type": "integer"
+                },
+                "average": {
+                    "type": "number"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "tones": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "models.Entity": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "mentions": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EntitySummary": {
+            "type": "object",
+            "properties": {
+                "articles": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.KeywordWeight": {
+            "type": "object",
+            "properties": {
+                "term": {
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "number"
                 }
             }
         },
@@ -408,6 +1064,12 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "liked": {
                     "type": "array",
                     "items": {
@@ -416,6 +1078,154 @@ const docTemplate = `{
                 }
             }
         },
+        "models.Story": {
+            "type": "object",
+            "properties": {
+                "article_count": {
+                    "type": "integer"
+                },
+                "article_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "first_published_at": {
+                    "type": "string"
+                },
+                "headline": {
+                    "type": "string"
+                },
+                "headline_article_id": {
+                    "type": "string"
+                },
+                "keyword_weights": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.KeywordWeight"
+                    }
+                },
+                "last_published_at": {
+                    "type": "string"
+                },
+                "sentiment": {
+                    "type": "number"
+                },
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "sources_count": {
+                    "type": "integer"
+                },
+                "story_id": {
+                    "type": "string"
+                },
+                "tone": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.StoryTrend": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "headline": {
+                    "type": "string"
+                },
+                "last_published_at": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "sources_count": {
+                    "type": "integer"
+                },
+                "story_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Thumbnail": {
+            "type": "object",
+            "properties": {
+                "height": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Trend": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "term": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Trending": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "entities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Trend"
+                    }
+                },
+                "keywords": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Trend"
+                    }
+                },
+                "stories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StoryTrend"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "window": {
+                    "type": "string"
+                }
+            }
+        },
         "models.UpdateUser": {
             "type": "object",
             "properties": {
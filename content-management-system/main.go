@@ -33,9 +33,12 @@ var (
 	redisclient       *redis.Client
 	profileCollection *mongo.Collection
 	newsCollection    *mongo.Collection
+	storyCollection   *mongo.Collection
 
-	profileService services.ProfileServices
-	newsService    services.ArticleServices
+	profileService  services.ProfileServices
+	newsService     services.ArticleServices
+	storyService    services.StoryServices
+	trendingService services.TrendingServices
 
 	newsController    controllers.NewsController
 	profileController controllers.ProfileController
@@ -153,11 +156,14 @@ func init() {
 
 	newsCollection = mongoclient.Database("golang_mongodb").Collection("articles")
 	profileCollection = mongoclient.Database("golang_mongodb").Collection("profiles")
+	storyCollection = mongoclient.Database("golang_mongodb").Collection("stories")
 
 	newsService = services.NewArticleService(ctx, newsCollection)
+	storyService = services.NewStoryService(ctx, storyCollection, newsCollection)
+	trendingService = services.NewTrendingService(ctx, redisclient, storyCollection)
 	profileService = services.NewProfileService(ctx, profileCollection)
 
-	newsController = controllers.NewNewsController(newsService)
+	newsController = controllers.NewNewsController(newsService, storyService, trendingService)
 	profileController = controllers.NewProfileController(profileService)
 
 	newsRouter = routes.NewNewsControllerRoute(newsController)
@@ -45,6 +45,7 @@ func DeserializeUser(profileService services.ProfileServices) gin.HandlerFunc {
 
 		ctx.Set("currentUserId", user.ID.Hex())
 		ctx.Set("currentUserPrefrence", user.Prefrences.Categories)
+		ctx.Set("currentUserLanguages", user.Prefrences.Languages)
 		ctx.Next()
 	}
 }
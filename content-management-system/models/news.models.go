@@ -20,10 +20,182 @@ type Article struct {
 	Country     []string           `json:"country" bson:"country"`
 	Category    []string           `json:"category" bson:"category"`
 	Date        string             `json:"pubDate" bson:"pubDate"`
+	Language    string             `json:"language" bson:"language"`
+	PublishedAt time.Time          `json:"published_at" bson:"published_at"`
+	IngestedAt  time.Time          `json:"ingested_at" bson:"ingested_at"`
+
+	KeywordWeights []KeywordWeight    `json:"keyword_weights" bson:"keyword_weights"`
+	StoryId        primitive.ObjectID `json:"story_id" bson:"story_id,omitempty"`
+	Entities       []Entity           `json:"entities" bson:"entities"`
+	ImageInfo      *ArticleImage      `json:"image,omitempty" bson:"image,omitempty"`
+	Thumbnail      string             `json:"thumbnail_url,omitempty" bson:"-"`
+	Summary        string             `json:"summary" bson:"summary"`
+	WordCount      int                `json:"word_count" bson:"word_count"`
+	ReadingTime    int                `json:"reading_time" bson:"reading_time"`
+	ReadingEase    *float64           `json:"reading_ease,omitempty" bson:"reading_ease,omitempty"`
+	GradeLevel     *float64           `json:"grade_level,omitempty" bson:"grade_level,omitempty"`
+	SummaryShort   string             `json:"-" bson:"summary_short"`
+	Sentiment      float64            `json:"sentiment" bson:"sentiment"`
+	Tone           string             `json:"tone" bson:"tone"`
+
+	CategorySource     string  `json:"category_source" bson:"category_source"`
+	CategoryConfidence float64 `json:"category_confidence" bson:"category_confidence"`
+	Score              float64 `json:"score,omitempty" bson:"score,omitempty"`
+}
+
+// KeywordWeight is the relevance of a keyword to its article, between 0 and 1
+type KeywordWeight struct {
+	Term   string  `json:"term" bson:"term"`
+	Weight float64 `json:"weight" bson:"weight"`
+}
+
+// Entity is a person, organisation or location an article mentions, ids look
+// like person:bola-tinubu
+type Entity struct {
+	Id       string `json:"id" bson:"id"`
+	Type     string `json:"type" bson:"type"`
+	Name     string `json:"name" bson:"name"`
+	Mentions int    `json:"mentions" bson:"mentions"`
+}
+
+// EntitySummary is an entity and the number of articles mentioning it
+type EntitySummary struct {
+	Id       string `json:"id" bson:"_id"`
+	Type     string `json:"type" bson:"type"`
+	Name     string `json:"name" bson:"name"`
+	Articles int    `json:"articles" bson:"articles"`
+}
+
+// ArticleImage is the lead image once news-ags fetched it, thumbnails are only
+// set when the status is ok and reused images are likely stock photos
+type ArticleImage struct {
+	Status      string      `json:"status" bson:"status"`
+	Hash        string      `json:"hash,omitempty" bson:"hash,omitempty"`
+	Width       int         `json:"width,omitempty" bson:"width,omitempty"`
+	Height      int         `json:"height,omitempty" bson:"height,omitempty"`
+	ContentType string      `json:"content_type,omitempty" bson:"content_type,omitempty"`
+	Thumbnails  []Thumbnail `json:"thumbnails" bson:"thumbnails"`
+	Reused      bool        `json:"reused" bson:"reused"`
+}
+
+// Thumbnail is a resized jpeg copy of an article image
+type Thumbnail struct {
+	Width  int    `json:"width" bson:"width"`
+	Height int    `json:"height" bson:"height"`
+	URL    string `json:"url" bson:"url"`
+}
+
+const ImageOK = "ok"
+
+// UseImage picks the thumbnail for clients showing images the given width
+// wide, the narrowest at least as wide or else the widest. Zero picks the
+// widest. Upstream urls that failed to load are dropped
+func (a *Article) UseImage(width int) {
+	if a.ImageInfo == nil {
+		return
+	}
+	if a.ImageInfo.Status != ImageOK {
+		a.Image = ""
+		return
+	}
+
+	var picked *Thumbnail
+	for i := range a.ImageInfo.Thumbnails {
+		thumbnail := &a.ImageInfo.Thumbnails[i]
+		switch {
+		case picked == nil:
+			picked = thumbnail
+		case width > 0 && thumbnail.Width >= width && (picked.Width < width || thumbnail.Width < picked.Width):
+			picked = thumbnail
+		case (width <= 0 || picked.Width < width) && thumbnail.Width > picked.Width:
+			picked = thumbnail
+		}
+	}
+	if picked != nil {
+		a.Thumbnail = picked.URL
+	}
+}
+
+// Tones label the sentiment of articles and stories, from -1 to 1
+var Tones = []string{"very_negative", "negative", "neutral", "positive", "very_positive"}
+
+// CategorySentiment aggregates the sentiment of the articles in a category,
+// tones counts the articles per tone
+type CategorySentiment struct {
+	Category string         `json:"category"`
+	Articles int            `json:"articles"`
+	Average  float64        `json:"average"`
+	Tones    map[string]int `json:"tones"`
+}
+
+var EntityTypes = []string{"person", "organisation", "location"}
+
+const (
+	SortPublished   = "published_at"
+	SortIngested    = "ingested_at"
+	SortReadingTime = "reading_time"
+	SortWordCount   = "word_count"
+	SortReadingEase = "reading_ease"
+)
+
+// SortOrders is the direction of every feed ordering, dates newest first,
+// lengths shortest first and readability easiest first
+var SortOrders = map[string]int{
+	SortPublished:   -1,
+	SortIngested:    -1,
+	SortReadingTime: 1,
+	SortWordCount:   1,
+	SortReadingEase: -1,
+}
+
+const (
+	SummaryLong  = "long"
+	SummaryShort = "short"
+)
+
+// UseSummary fills the summary with the requested length, articles stored
+// before summaries existed fall back to their description
+func (a *Article) UseSummary(length string) {
+	if length == SummaryShort && a.SummaryShort != "" {
+		a.Summary = a.SummaryShort
+	}
+	if a.Summary == "" {
+		a.Summary = a.Description
+	}
+}
+
+// FeedFilter narrows and orders the news feed
+type FeedFilter struct {
+	Categories []string
+	Entities   []string
+	Tones      []string
+	Languages  []string
+	From       time.Time
+	To         time.Time
+	SortBy     string
+	Limit      int
+	Page       int
+
+	// MinSentiment hides articles below it when set
+	MinSentiment *float64
+
+	// reading time bounds in minutes, zero leaves a side open
+	MinReadingTime int
+	MaxReadingTime int
+	// MaxGradeLevel hides articles harder than it when set
+	MaxGradeLevel *float64
+}
+
+// SearchFilter narrows a search, the query is optional when entities are given
+type SearchFilter struct {
+	Query     string
+	Entities  []string
+	Languages []string
 }
 
-type MongoArticle struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id" binding:"required"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at" binding:"required"`
-	Article   Article            `json:"article" bson:"article" binding:"required"`
+// TextLanguages are the languages the search index stems, by ISO 639-1 code
+var TextLanguages = map[string]string{
+	"da": "danish", "de": "german", "en": "english", "es": "spanish", "fi": "finnish",
+	"fr": "french", "hu": "hungarian", "it": "italian", "nb": "norwegian", "nl": "dutch",
+	"pt": "portuguese", "ro": "romanian", "ru": "russian", "sv": "swedish", "tr": "turkish",
 }
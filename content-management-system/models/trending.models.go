@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Trend is a term whose frequency spikes above its baseline, count is the
+// number of articles mentioning it within the window
+type Trend struct {
+	Term  string  `json:"term"`
+	Name  string  `json:"name,omitempty"`
+	Score float64 `json:"score"`
+	Count int     `json:"count"`
+}
+
+type StoryTrend struct {
+	StoryId      string    `json:"story_id"`
+	Headline     string    `json:"headline"`
+	SourcesCount int       `json:"sources_count"`
+	LastUpdate   time.Time `json:"last_published_at"`
+	Score        float64   `json:"score"`
+	Count        int       `json:"count"`
+}
+
+type Trending struct {
+	Window    string       `json:"window"`
+	Category  string       `json:"category"`
+	Keywords  []Trend      `json:"keywords"`
+	Entities  []Trend      `json:"entities"`
+	Stories   []StoryTrend `json:"stories"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
@@ -30,6 +30,7 @@ type UserProfile struct {
 
 type Prefrence struct {
 	Categories []string             `json:"categories" bson:"categories"`
+	Languages  []string             `json:"languages" bson:"languages"`
 	Liked      []primitive.ObjectID `json:"liked" bson:"liked"`
 }
 
@@ -19,4 +19,10 @@ func (r *NewsRouteController) NewsRoute(rg *gin.RouterGroup, service services.Ar
 
 	router.GET("/feed", r.newsController.Feed)
 	router.GET("/search", r.newsController.Search)
+	router.GET("/related/:id", r.newsController.Related)
+	router.GET("/entities", r.newsController.Entities)
+	router.GET("/sentiment", r.newsController.Sentiment)
+	router.GET("/stories", r.newsController.Stories)
+	router.GET("/stories/:id", r.newsController.Story)
+	router.GET("/trending", r.newsController.Trending)
 }
@@ -2,18 +2,37 @@ package services
 
 import (
 	"context"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/joey1123455/news-aggregator-service/content-management-system/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ArticleServices interface {
-	Search(key string) ([]models.MongoArticle, error)
-	NewsFeed(categories []string, limit, page int) ([]models.MongoArticle, error)
+	Search(search models.SearchFilter) ([]models.Article, error)
+	NewsFeed(filter models.FeedFilter) ([]models.Article, error)
+	Related(id string, limit int) ([]models.Article, error)
+	Entities(query, kind string, limit int) ([]models.EntitySummary, error)
+	SentimentByCategory(from, to time.Time) ([]models.CategorySentiment, error)
 }
 
+const (
+	// relatedCandidates bounds how many articles sharing a keyword are scored
+	relatedCandidates = 200
+
+	// entitySearchLimit bounds a search by entity alone, newest first
+	entitySearchLimit = 100
+)
+
 type ArticleServiceImp struct {
 	ctx        context.Context
 	collection *mongo.Collection
@@ -26,19 +45,20 @@ func NewArticleService(ctx context.Context, collection *mongo.Collection) Articl
 	}
 }
 
-func (as *ArticleServiceImp) NewsFeed(categories []string, limit, page int) ([]models.MongoArticle, error) {
-	var filter bson.M
+func (as *ArticleServiceImp) NewsFeed(feed models.FeedFilter) ([]models.Article, error) {
+	filter := feedFilter(feed)
 
-	// If categories is not nil, include the category filter
-	if categories != nil {
-		filter = bson.M{"category": bson.M{"$in": categories}}
+	sortBy := feed.SortBy
+	order, ok := models.SortOrders[sortBy]
+	if !ok {
+		sortBy, order = models.SortPublished, -1
 	}
 
-	// Define the options to sort by createdAt in descending order, skip, and limit
+	// Define the options to sort, skip, and limit
 	options := options.Find().
-		SetSort(bson.D{{Key: "created_at", Value: -1}}).
-		SetSkip(int64((page - 1) * limit)).
-		SetLimit(int64(limit))
+		SetSort(bson.D{{Key: sortBy, Value: order}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((feed.Page - 1) * feed.Limit)).
+		SetLimit(int64(feed.Limit))
 
 	// Find articles that match the filter, sort them, skip, and limit
 	cursor, err := as.collection.Find(as.ctx, filter, options)
@@ -48,7 +68,7 @@ func (as *ArticleServiceImp) NewsFeed(categories []string, limit, page int) ([]m
 	defer cursor.Close(as.ctx)
 
 	// Decode the results into a slice of articles
-	var articles []models.MongoArticle
+	var articles []models.Article
 	err = cursor.All(as.ctx, &articles)
 	if err != nil {
 		return nil, err
@@ -57,23 +77,347 @@ func (as *ArticleServiceImp) NewsFeed(categories []string, limit, page int) ([]m
 	return articles, nil
 }
 
-func (as ArticleServiceImp) Search(key string) ([]models.MongoArticle, error) {
-	// Define the filter to search for articles with title or content containing the query
-	filter := bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: key}}}}
+// feedFilter builds the mongo filter of a feed request
+func feedFilter(feed models.FeedFilter) bson.M {
+	filter := bson.M{}
+
+	// If categories is not nil, include the category filter
+	if feed.Categories != nil {
+		filter["category"] = bson.M{"$in": feed.Categories}
+	}
+
+	// Only keep articles mentioning every requested entity
+	if len(feed.Entities) > 0 {
+		filter["entities.id"] = bson.M{"$all": feed.Entities}
+	}
+
+	// Prefer the requested languages, articles stored before detection existed have none
+	if len(feed.Languages) > 0 {
+		filter["language"] = languageFilter(feed.Languages)
+	}
+
+	// Restrict the tone, articles scored before sentiment existed have none
+	if len(feed.Tones) > 0 {
+		filter["tone"] = bson.M{"$in": feed.Tones}
+	}
+	if feed.MinSentiment != nil {
+		filter["sentiment"] = bson.M{"$gte": *feed.MinSentiment}
+	}
+
+	// Restrict the publish window when bounds are given
+	published := bson.M{}
+	if !feed.From.IsZero() {
+		published["$gte"] = feed.From
+	}
+	if !feed.To.IsZero() {
+		published["$lte"] = feed.To
+	}
+	if len(published) > 0 {
+		filter["published_at"] = published
+	}
+
+	// Restrict the reading time and difficulty, articles saved before they
+	// were measured have no reading time and are not quick reads
+	readingTime := bson.M{}
+	if feed.MinReadingTime > 0 {
+		readingTime["$gte"] = feed.MinReadingTime
+	} else if feed.MaxReadingTime > 0 {
+		readingTime["$gte"] = 1
+	}
+	if feed.MaxReadingTime > 0 {
+		readingTime["$lte"] = feed.MaxReadingTime
+	}
+	if len(readingTime) > 0 {
+		filter["reading_time"] = readingTime
+	}
+	if feed.MaxGradeLevel != nil {
+		filter["grade_level"] = bson.M{"$lte": *feed.MaxGradeLevel}
+	}
+
+	return filter
+}
+
+// Search ranks the text matches by their text score, boosted by the weight
+// of the article keywords the query mentions. Entities narrow the matches to
+// articles mentioning all of them, without a query the newest are returned.
+// A single language also stems the query the way its articles were indexed
+func (as ArticleServiceImp) Search(search models.SearchFilter) ([]models.Article, error) {
+	key := search.Query
+	filter := bson.D{}
+	options := options.Find()
+	if key != "" {
+		// Define the filter to search for articles with title or content containing the query
+		text := bson.D{{Key: "$search", Value: key}}
+		if len(search.Languages) == 1 {
+			if analyzer, ok := models.TextLanguages[search.Languages[0]]; ok {
+				text = append(text, bson.E{Key: "$language", Value: analyzer})
+			}
+		}
+		filter = append(filter, bson.E{Key: "$text", Value: text})
+		options.SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}})
+	} else {
+		options.SetSort(bson.D{{Key: models.SortPublished, Value: -1}}).SetLimit(entitySearchLimit)
+	}
+	if len(search.Entities) > 0 {
+		filter = append(filter, bson.E{Key: "entities.id", Value: bson.M{"$all": search.Entities}})
+	}
+	if len(search.Languages) > 0 {
+		filter = append(filter, bson.E{Key: "language", Value: languageFilter(search.Languages)})
+	}
 
 	// Find articles that match the filter
-	cursor, err := as.collection.Find(as.ctx, filter)
+	cursor, err := as.collection.Find(as.ctx, filter, options)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(as.ctx)
 
 	// Decode the results into a slice of articles
-	var articles []models.MongoArticle
+	var articles []models.Article
 	err = cursor.All(as.ctx, &articles)
 	if err != nil {
 		return nil, err
 	}
 
+	if key == "" {
+		return articles, nil
+	}
+
+	query := termWords(key)
+	for i := range articles {
+		for _, keyword := range articles[i].KeywordWeights {
+			if mentionsTerm(query, keyword.Term) {
+				articles[i].Score += keyword.Weight
+			}
+		}
+	}
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].Score > articles[j].Score
+	})
+
 	return articles, nil
 }
+
+// termWords splits text into lowercase words the way keyword terms are
+// split, a hyphen or apostrophe inside a word keeps it whole
+func termWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '-' && r != '\''
+	})
+
+	trimmed := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.Trim(word, "-'"); word != "" {
+			trimmed = append(trimmed, word)
+		}
+	}
+
+	return trimmed
+}
+
+// mentionsTerm tells whether the query words hold every word of the term in
+// a row, so a query of wartime does not mention war or art
+func mentionsTerm(query []string, term string) bool {
+	words := termWords(term)
+	if len(words) == 0 {
+		return false
+	}
+
+	for start := 0; start+len(words) <= len(query); start++ {
+		if slices.Equal(query[start:start+len(words)], words) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Related finds the articles sharing the most heavily weighted keywords with
+// the given article, newest candidates first
+func (as ArticleServiceImp) Related(id string, limit int) ([]models.Article, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, primitive.ErrInvalidHex
+	}
+
+	var article models.Article
+	if err := as.collection.FindOne(as.ctx, bson.M{"_id": oid}).Decode(&article); err != nil {
+		return nil, err
+	}
+
+	weights := make(map[string]float64, len(article.KeywordWeights))
+	terms := make([]string, 0, len(article.KeywordWeights))
+	for _, keyword := range article.KeywordWeights {
+		weights[keyword.Term] = keyword.Weight
+		terms = append(terms, keyword.Term)
+	}
+	if len(terms) == 0 {
+		return []models.Article{}, nil
+	}
+
+	filter := bson.M{"_id": bson.M{"$ne": oid}, "keyword_weights.term": bson.M{"$in": terms}}
+	options := options.Find().
+		SetSort(bson.D{{Key: models.SortPublished, Value: -1}}).
+		SetLimit(relatedCandidates)
+
+	cursor, err := as.collection.Find(as.ctx, filter, options)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(as.ctx)
+
+	var candidates []models.Article
+	if err := cursor.All(as.ctx, &candidates); err != nil {
+		return nil, err
+	}
+
+	for i := range candidates {
+		for _, keyword := range candidates[i].KeywordWeights {
+			candidates[i].Score += weights[keyword.Term] * keyword.Weight
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	return candidates, nil
+}
+
+// Entities lists the entities with a word of their name starting with query,
+// most mentioned first. The anchored match on the normalized name keys stays
+// on their index. An empty kind covers every entity type
+func (as ArticleServiceImp) Entities(query, kind string, limit int) ([]models.EntitySummary, error) {
+	key := entityNameKey(query)
+	if key == "" {
+		return []models.EntitySummary{}, nil
+	}
+
+	match := bson.M{"entities.name_keys": bson.M{"$regex": "^" + regexp.QuoteMeta(key)}}
+	if kind != "" {
+		match["entities.type"] = kind
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"entities": bson.M{"$elemMatch": elemMatch(match)}}}},
+		{{Key: "$unwind", Value: "$entities"}},
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$entities.id",
+			"type":     bson.M{"$first": "$entities.type"},
+			"name":     bson.M{"$first": "$entities.name"},
+			"articles": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "articles", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := as.collection.Aggregate(as.ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(as.ctx)
+
+	entities := make([]models.EntitySummary, 0)
+	if err := cursor.All(as.ctx, &entities); err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// entityNameKey normalizes a name the way the aggregator keys entity names,
+// lowercase words without punctuation
+func entityNameKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(words, " ")
+}
+
+// languageFilter matches the given languages and articles whose language was
+// never detected, hiding those would empty the feed of older articles
+func languageFilter(languages []string) bson.M {
+	values := bson.A{nil, ""}
+	for _, language := range languages {
+		values = append(values, language)
+	}
+
+	return bson.M{"$in": values}
+}
+
+// elemMatch rewrites an entities.<field> filter for use inside $elemMatch
+func elemMatch(filter bson.M) bson.M {
+	fields := make(bson.M, len(filter))
+	for key, value := range filter {
+		fields[strings.TrimPrefix(key, "entities.")] = value
+	}
+
+	return fields
+}
+
+// SentimentByCategory averages the sentiment of the articles published between
+// from and to per category, most covered category first
+func (as ArticleServiceImp) SentimentByCategory(from, to time.Time) ([]models.CategorySentiment, error) {
+	group := bson.M{
+		"_id":      "$category",
+		"articles": bson.M{"$sum": 1},
+		"average":  bson.M{"$avg": "$sentiment"},
+	}
+	for _, tone := range models.Tones {
+		group[tone] = bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$tone", tone}}, 1, 0}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"published_at": bson.M{"$gte": from, "$lte": to},
+			"tone":         bson.M{"$in": models.Tones},
+		}}},
+		{{Key: "$unwind", Value: "$category"}},
+		{{Key: "$group", Value: group}},
+		{{Key: "$sort", Value: bson.D{{Key: "articles", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := as.collection.Aggregate(as.ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(as.ctx)
+
+	var rows []bson.M
+	if err := cursor.All(as.ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	categories := make([]models.CategorySentiment, 0, len(rows))
+	for _, row := range rows {
+		category := models.CategorySentiment{Tones: make(map[string]int, len(models.Tones))}
+		category.Category, _ = row["_id"].(string)
+		category.Articles = int(toFloat(row["articles"]))
+		category.Average = math.Round(toFloat(row["average"])*1000) / 1000
+		for _, tone := range models.Tones {
+			category.Tones[tone] = int(toFloat(row[tone]))
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
+
+// toFloat reads an aggregation number, which mongo returns as int32, int64 or double
+func toFloat(value interface{}) float64 {
+	switch number := value.(type) {
+	case int32:
+		return float64(number)
+	case int64:
+		return float64(number)
+	case float64:
+		return number
+	default:
+		return 0
+	}
+}
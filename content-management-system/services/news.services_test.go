@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/joey1123455/news-aggregator-service/content-management-system/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMentionsTerm(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		term  string
+		want  bool
+	}{
+		{name: "whole word", query: "war in europe", term: "war", want: true},
+		{name: "inside a word", query: "wartime", term: "war", want: false},
+		{name: "end of a word", query: "wartime", term: "time", want: false},
+		{name: "case and punctuation", query: "Climate, CHANGE!", term: "climate change", want: true},
+		{name: "phrase out of order", query: "change climate", term: "climate change", want: false},
+		{name: "phrase split by a word", query: "climate policy change", term: "climate change", want: false},
+		{name: "hyphenated word", query: "post-war europe", term: "post-war", want: true},
+		{name: "part of a hyphenated word", query: "post-war europe", term: "war", want: false},
+		{name: "empty term", query: "war", term: " ", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mentionsTerm(termWords(tt.query), tt.term); got != tt.want {
+				t.Errorf("mentionsTerm(%q, %q) = %v, want %v", tt.query, tt.term, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntityNameKey(t *testing.T) {
+	tests := map[string]string{
+		"Bola":         "bola",
+		"  Bola  TIN":  "bola tin",
+		"U.S.":         "u s",
+		"central-bank": "central bank",
+		"...":          "",
+	}
+	for name, want := range tests {
+		if got := entityNameKey(name); got != want {
+			t.Errorf("entityNameKey(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestFeedFilterReadingTime(t *testing.T) {
+	tests := []struct {
+		name string
+		feed models.FeedFilter
+		want bson.M
+	}{
+		{name: "no bounds", feed: models.FeedFilter{}, want: nil},
+		{name: "minimum only", feed: models.FeedFilter{MinReadingTime: 5}, want: bson.M{"$gte": 5}},
+		{name: "maximum leaves out unmeasured", feed: models.FeedFilter{MaxReadingTime: 3}, want: bson.M{"$gte": 1, "$lte": 3}},
+		{name: "both bounds", feed: models.FeedFilter{MinReadingTime: 2, MaxReadingTime: 4}, want: bson.M{"$gte": 2, "$lte": 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := feedFilter(tt.feed)["reading_time"].(bson.M)
+			if tt.want == nil {
+				if ok {
+					t.Errorf("reading_time = %v, want no filter", got)
+				}
+				return
+			}
+			if len(got) != len(tt.want) || got["$gte"] != tt.want["$gte"] || got["$lte"] != tt.want["$lte"] {
+				t.Errorf("reading_time = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+
+	"github.com/joey1123455/news-aggregator-service/content-management-system/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StoryServices interface {
+	Stories(filter models.StoryFilter) ([]models.Story, error)
+	Story(id string) (*models.Story, []models.Article, error)
+}
+
+type StoryServicesImp struct {
+	ctx               context.Context
+	storyCollection   *mongo.Collection
+	articleCollection *mongo.Collection
+}
+
+func NewStoryService(ctx context.Context, stories, articles *mongo.Collection) StoryServices {
+	return &StoryServicesImp{
+		ctx:               ctx,
+		storyCollection:   stories,
+		articleCollection: articles,
+	}
+}
+
+// Stories lists the most recently updated stories
+func (ss *StoryServicesImp) Stories(storyFilter models.StoryFilter) ([]models.Story, error) {
+	filter := bson.M{}
+	if len(storyFilter.Categories) > 0 {
+		filter["categories"] = bson.M{"$in": storyFilter.Categories}
+	}
+	if storyFilter.MinSources > 1 {
+		filter["sources_count"] = bson.M{"$gte": storyFilter.MinSources}
+	}
+
+	options := options.Find().
+		SetSort(bson.D{{Key: "last_published_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((storyFilter.Page - 1) * storyFilter.Limit)).
+		SetLimit(int64(storyFilter.Limit)).
+		SetProjection(bson.M{"article_ids": 0})
+
+	cursor, err := ss.storyCollection.Find(ss.ctx, filter, options)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ss.ctx)
+
+	stories := make([]models.Story, 0)
+	if err := cursor.All(ss.ctx, &stories); err != nil {
+		return nil, err
+	}
+
+	return stories, nil
+}
+
+// Story returns a story with its articles, newest first
+func (ss *StoryServicesImp) Story(id string) (*models.Story, []models.Article, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil, primitive.ErrInvalidHex
+	}
+
+	var story models.Story
+	if err := ss.storyCollection.FindOne(ss.ctx, bson.M{"_id": oid}).Decode(&story); err != nil {
+		return nil, nil, err
+	}
+
+	options := options.Find().SetSort(bson.D{{Key: "published_at", Value: -1}})
+	cursor, err := ss.articleCollection.Find(ss.ctx, bson.M{"story_id": oid}, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ss.ctx)
+
+	articles := make([]models.Article, 0)
+	if err := cursor.All(ss.ctx, &articles); err != nil {
+		return nil, nil, err
+	}
+
+	return &story, articles, nil
+}
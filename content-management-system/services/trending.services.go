@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/joey1123455/news-aggregator-service/content-management-system/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The rankings are computed by news-ags, see its trending service for the key layout
+const (
+	trendingRankPrefix  = "trending:rank:"
+	trendingCountPrefix = "trending:count:"
+	trendingWindowsKey  = "trending:windows"
+	trendingEntitiesKey = "trending:entities"
+	trendingUpdatedKey  = "trending:updated_at"
+	trendingAll         = "all"
+)
+
+var ErrUnknownWindow = errors.New("window is not tracked")
+
+type TrendingServices interface {
+	Trending(window, category string, limit int) (*models.Trending, error)
+	Windows() ([]string, error)
+}
+
+type TrendingServicesImp struct {
+	ctx             context.Context
+	rClient         *redis.Client
+	storyCollection *mongo.Collection
+}
+
+func NewTrendingService(ctx context.Context, client *redis.Client, stories *mongo.Collection) TrendingServices {
+	return &TrendingServicesImp{
+		ctx:             ctx,
+		rClient:         client,
+		storyCollection: stories,
+	}
+}
+
+func (ts *TrendingServicesImp) Windows() ([]string, error) {
+	return ts.rClient.SMembers(trendingWindowsKey).Result()
+}
+
+// Trending returns the top keywords and stories of a window, an empty category
+// covers every article
+func (ts *TrendingServicesImp) Trending(window, category string, limit int) (*models.Trending, error) {
+	tracked, err := ts.rClient.SIsMember(trendingWindowsKey, window).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !tracked {
+		return nil, ErrUnknownWindow
+	}
+
+	if category == "" {
+		category = trendingAll
+	}
+
+	trending := &models.Trending{Window: window, Category: category}
+	if updated, err := ts.rClient.Get(trendingUpdatedKey).Result(); err == nil {
+		trending.UpdatedAt, _ = time.Parse(time.RFC3339, updated)
+	}
+
+	if trending.Keywords, err = ts.ranking("keyword", window, category, limit); err != nil {
+		return nil, err
+	}
+
+	if trending.Entities, err = ts.ranking("entity", window, category, limit); err != nil {
+		return nil, err
+	}
+	if err := ts.entityNames(trending.Entities); err != nil {
+		return nil, err
+	}
+
+	stories, err := ts.ranking("story", window, category, limit)
+	if err != nil {
+		return nil, err
+	}
+	if trending.Stories, err = ts.storyTrends(stories); err != nil {
+		return nil, err
+	}
+
+	return trending, nil
+}
+
+func (ts *TrendingServicesImp) ranking(kind, window, category string, limit int) ([]models.Trend, error) {
+	suffix := kind + ":" + window + ":" + category
+
+	ranked, err := ts.rClient.ZRevRangeWithScores(trendingRankPrefix+suffix, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	trends := make([]models.Trend, 0, len(ranked))
+	if len(ranked) == 0 {
+		return trends, nil
+	}
+
+	terms := make([]string, 0, len(ranked))
+	for _, member := range ranked {
+		terms = append(terms, member.Member.(string))
+	}
+	counts, err := ts.rClient.HMGet(trendingCountPrefix+suffix, terms...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, member := range ranked {
+		trend := models.Trend{Term: terms[i], Score: member.Score}
+		if count, ok := counts[i].(string); ok {
+			value, _ := strconv.ParseFloat(count, 64)
+			trend.Count = int(value)
+		}
+		trends = append(trends, trend)
+	}
+
+	return trends, nil
+}
+
+// entityNames resolves the display name of every trending entity id
+func (ts *TrendingServicesImp) entityNames(trends []models.Trend) error {
+	if len(trends) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(trends))
+	for _, trend := range trends {
+		ids = append(ids, trend.Term)
+	}
+	names, err := ts.rClient.HMGet(trendingEntitiesKey, ids...).Result()
+	if err != nil {
+		return err
+	}
+
+	for i := range trends {
+		if name, ok := names[i].(string); ok {
+			trends[i].Name = name
+		}
+	}
+
+	return nil
+}
+
+// storyTrends adds the headline of every trending story, stories removed since
+// the ranking was computed are skipped
+func (ts *TrendingServicesImp) storyTrends(trends []models.Trend) ([]models.StoryTrend, error) {
+	stories := make([]models.StoryTrend, 0, len(trends))
+	if len(trends) == 0 {
+		return stories, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(trends))
+	for _, trend := range trends {
+		if id, err := primitive.ObjectIDFromHex(trend.Term); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	projection := options.Find().SetProjection(bson.M{"headline": 1, "sources_count": 1, "last_published_at": 1})
+	cursor, err := ts.storyCollection.Find(ts.ctx, bson.M{"_id": bson.M{"$in": ids}}, projection)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ts.ctx)
+
+	var found []models.Story
+	if err := cursor.All(ts.ctx, &found); err != nil {
+		return nil, err
+	}
+
+	byId := make(map[string]models.Story, len(found))
+	for _, story := range found {
+		byId[story.Id.Hex()] = story
+	}
+
+	for _, trend := range trends {
+		story, ok := byId[trend.Term]
+		if !ok {
+			continue
+		}
+		stories = append(stories, models.StoryTrend{
+			StoryId:      trend.Term,
+			Headline:     story.Headline,
+			SourcesCount: story.SourcesCount,
+			LastUpdate:   story.LastPublishedAt,
+			Score:        trend.Score,
+			Count:        trend.Count,
+		})
+	}
+
+	return stories, nil
+}
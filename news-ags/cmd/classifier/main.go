@@ -0,0 +1,64 @@
+// Command classifier trains the category classifier from the articles already
+// stored in mongo and writes the model file the saver reads.
+//
+//	go run ./cmd/classifier -out classifier.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/config"
+	"github.com/joey1123455/news-aggregator-service/news-ags/services"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	configPath := flag.String("config", ".", "directory holding app.env")
+	out := flag.String("out", "", "model file to write, defaults to CLASSIFIER_MODEL")
+	minDocs := flag.Int("min-docs", 20, "categories with fewer training articles are left out")
+	maxDocs := flag.Int("max-docs", 5000, "most articles used per category, 0 for no limit")
+	minCount := flag.Int("min-count", 2, "words seen fewer times are left out of the vocabulary")
+	holdout := flag.Int("holdout", 10, "every n-th article is kept aside to measure accuracy, 0 to disable")
+	flag.Parse()
+
+	config, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal("Could not load config ", err)
+	}
+	if *out == "" {
+		*out = config.ClassifierModel
+	}
+
+	ctx := context.TODO()
+	mongoclient, err := mongo.Connect(ctx, options.Client().ApplyURI(config.DBUri))
+	if err != nil {
+		log.Fatal("Could not connect to mongo ", err)
+	}
+	defer mongoclient.Disconnect(ctx)
+
+	articleCollection := mongoclient.Database("golang_mongodb").Collection("articles")
+	model, report, err := services.TrainClassifier(ctx, articleCollection, services.TrainOptions{
+		MinDocuments:  *minDocs,
+		MaxDocuments:  *maxDocs,
+		MinTokenCount: *minCount,
+		Holdout:       *holdout,
+	})
+	if report != nil {
+		reportJSON, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(reportJSON))
+	}
+	if err != nil {
+		log.Fatal("Could not train classifier ", err)
+	}
+
+	if err := model.Save(*out); err != nil {
+		log.Fatal("Could not write model ", err)
+	}
+	fmt.Fprintf(os.Stderr, "model with %d categories and %d words written to %s\n", len(model.Classes), model.Vocabulary, *out)
+}
@@ -0,0 +1,39 @@
+// Command fakenewsdata serves recorded newsdata.io responses on a local port so
+// the scraper can run without spending credits. Point news-ags at it with
+// NEWSDATA_BASE_URL=http://localhost:8090/api/1/news
+//
+//	go run ./cmd/fakenewsdata -fixtures testdata/newsdata -addr :8090
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/newsdatafake"
+)
+
+func main() {
+	fixtures := flag.String("fixtures", "testdata/newsdata", "directory of recorded newsdata responses")
+	addr := flag.String("addr", ":8090", "address to listen on")
+	keys := flag.String("keys", "", "comma separated api keys to accept, any key when empty")
+	credits := flag.Int("credits", 0, "requests per key before ApiLimitExceeded, 0 for no limit")
+	pageSize := flag.Int("page-size", 10, "articles per page")
+	flag.Parse()
+
+	opts := newsdatafake.Options{Credits: *credits, PageSize: *pageSize}
+	for _, key := range strings.Split(*keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			opts.Keys = append(opts.Keys, key)
+		}
+	}
+
+	handler, err := newsdatafake.NewHandler(*fixtures, opts)
+	if err != nil {
+		log.Fatal("Could not load fixtures ", err)
+	}
+
+	log.Printf("serving newsdata fixtures from %s on %s%s", *fixtures, *addr, newsdatafake.NewsPath)
+	log.Fatal(http.ListenAndServe(*addr, handler))
+}
@@ -11,4 +11,85 @@ type Config struct {
 	SMTPPass  string `mapstructure:"SMTP_PASS"`
 	SMTPPort  int    `mapstructure:"SMTP_PORT"`
 	SMTPUser  string `mapstructure:"SMTP_USER"`
+
+	ScrapeSchedule   string `mapstructure:"SCRAPE_SCHEDULE"`
+	SaveSchedule     string `mapstructure:"SAVE_SCHEDULE"`
+	SchedulerLockTTL int    `mapstructure:"SCHEDULER_LOCK_TTL"`
+
+	SourcesRegistry string `mapstructure:"SOURCES_REGISTRY"`
+	SourcesFile     string `mapstructure:"SOURCES_FILE"`
+
+	NewsdataURL  string `mapstructure:"NEWSDATA_BASE_URL"`
+	PageBudget   int    `mapstructure:"NEWSDATA_PAGE_BUDGET"`
+	ApiKeys      string `mapstructure:"NEWS_DATA_API_KEYS"`
+	DailyCredits int    `mapstructure:"NEWSDATA_DAILY_CREDITS"`
+
+	ReplayDatabase string `mapstructure:"REPLAY_DATABASE"`
+	ReplayRedisDB  int    `mapstructure:"REPLAY_REDIS_DB"`
+
+	ScrapePlanFile   string `mapstructure:"SCRAPE_PLAN_FILE"`
+	PlanSchedule     string `mapstructure:"PLAN_SCHEDULE"`
+	QualityRulesFile string `mapstructure:"QUALITY_RULES_FILE"`
+	AdminToken       string `mapstructure:"ADMIN_TOKEN"`
+
+	UpstreamTimeout    int `mapstructure:"UPSTREAM_TIMEOUT"`
+	UpstreamMaxRetries int `mapstructure:"UPSTREAM_MAX_RETRIES"`
+	BreakerThreshold   int `mapstructure:"UPSTREAM_BREAKER_THRESHOLD"`
+	BreakerCooldown    int `mapstructure:"UPSTREAM_BREAKER_COOLDOWN"`
+
+	StreamBatchSize     int `mapstructure:"STREAM_BATCH_SIZE"`
+	StreamClaimIdle     int `mapstructure:"STREAM_CLAIM_IDLE"`
+	StreamMaxDeliveries int `mapstructure:"STREAM_MAX_DELIVERIES"`
+	DedupWindowHours    int `mapstructure:"DEDUP_WINDOW_HOURS"`
+
+	UserAgent        string `mapstructure:"USER_AGENT"`
+	FetchFullArticle bool   `mapstructure:"FETCH_FULL_ARTICLE"`
+	MinContentLength int    `mapstructure:"MIN_CONTENT_LENGTH"`
+
+	KeywordLimit      int `mapstructure:"KEYWORD_LIMIT"`
+	KeywordWindowDays int `mapstructure:"KEYWORD_WINDOW_DAYS"`
+
+	ClassifierModel            string  `mapstructure:"CLASSIFIER_MODEL"`
+	ClassifierAssignThreshold  float64 `mapstructure:"CLASSIFIER_ASSIGN_THRESHOLD"`
+	ClassifierCorrectThreshold float64 `mapstructure:"CLASSIFIER_CORRECT_THRESHOLD"`
+
+	EntityGazetteer string `mapstructure:"ENTITY_GAZETTEER"`
+	EntityLimit     int    `mapstructure:"ENTITY_LIMIT"`
+
+	SummarySentences      int `mapstructure:"SUMMARY_SENTENCES"`
+	SummaryShortSentences int `mapstructure:"SUMMARY_SHORT_SENTENCES"`
+
+	ReadingWordsPerMinute int `mapstructure:"READING_WORDS_PER_MINUTE"`
+
+	ImagePipeline        bool   `mapstructure:"IMAGE_PIPELINE"`
+	ImageStore           string `mapstructure:"IMAGE_STORE"`
+	ImageDir             string `mapstructure:"IMAGE_DIR"`
+	ImagePublicURL       string `mapstructure:"IMAGE_PUBLIC_URL"`
+	ImageMaxBytes        int64  `mapstructure:"IMAGE_MAX_BYTES"`
+	ImageMinWidth        int    `mapstructure:"IMAGE_MIN_WIDTH"`
+	ImageMinHeight       int    `mapstructure:"IMAGE_MIN_HEIGHT"`
+	ImageMaxPixels       int    `mapstructure:"IMAGE_MAX_PIXELS"`
+	ImageThumbnailWidths string `mapstructure:"IMAGE_THUMBNAIL_WIDTHS"`
+	ImageReuseThreshold  int    `mapstructure:"IMAGE_REUSE_THRESHOLD"`
+
+	S3Endpoint  string `mapstructure:"S3_ENDPOINT"`
+	S3Region    string `mapstructure:"S3_REGION"`
+	S3Bucket    string `mapstructure:"S3_BUCKET"`
+	S3AccessKey string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey string `mapstructure:"S3_SECRET_KEY"`
+	S3PathStyle bool   `mapstructure:"S3_PATH_STYLE"`
+	S3PublicURL string `mapstructure:"S3_PUBLIC_URL"`
+
+	StoryWindowHours int     `mapstructure:"STORY_WINDOW_HOURS"`
+	StorySimilarity  float64 `mapstructure:"STORY_SIMILARITY"`
+
+	TrendingSchedule      string `mapstructure:"TRENDING_SCHEDULE"`
+	TrendingWindows       string `mapstructure:"TRENDING_WINDOWS"`
+	TrendingBaselineHours int    `mapstructure:"TRENDING_BASELINE_HOURS"`
+	TrendingMinCount      int    `mapstructure:"TRENDING_MIN_COUNT"`
+	TrendingSize          int    `mapstructure:"TRENDING_SIZE"`
+	TrendingKeywords      int    `mapstructure:"TRENDING_KEYWORDS"`
+
+	RunRetentionDays        int `mapstructure:"RUN_RETENTION_DAYS"`
+	DeadLetterRetentionDays int `mapstructure:"DEAD_LETTER_RETENTION_DAYS"`
 }
@@ -11,6 +11,135 @@ func LoadConfig(path string) (config Config, err error) {
 	viper.SetConfigType("env")
 	viper.SetConfigName("app")
 
+	// scheduler defaults, an empty schedule disables the job. The lock ttl is in
+	// seconds and at least 3
+	viper.SetDefault("SCRAPE_SCHEDULE", "*/30 * * * *")
+	viper.SetDefault("SAVE_SCHEDULE", "5,35 * * * *")
+	viper.SetDefault("SCHEDULER_LOCK_TTL", 600)
+
+	// sources are read from a json file unless set to "mongo"
+	viper.SetDefault("SOURCES_REGISTRY", "file")
+	viper.SetDefault("SOURCES_FILE", "sources.json")
+
+	// newsdata.io news endpoint, point it at cmd/fakenewsdata to scrape
+	// without spending credits
+	viper.SetDefault("NEWSDATA_BASE_URL", "https://newsdata.io/api/1/news")
+
+	// newsdata.io pages (credits) spent per category per run
+	viper.SetDefault("NEWSDATA_PAGE_BUDGET", 5)
+
+	// newsdata.io api key pool, comma separated keys each optionally followed
+	// by its own daily credits as key:credits. Keys are used in order and the
+	// next one takes over once a key is spent
+	viper.SetDefault("NEWS_DATA_API_KEYS", "")
+	viper.SetDefault("NEWSDATA_DAILY_CREDITS", 200)
+
+	// --replay runs against its own mongo database and redis db, both are
+	// emptied before every replay so runs start from the same state
+	viper.SetDefault("REPLAY_DATABASE", "golang_mongodb_replay")
+	viper.SetDefault("REPLAY_REDIS_DB", 15)
+
+	// the scrape plan file seeds the plan kept in redis, the plan schedule is
+	// how often entries with their own schedule are checked
+	viper.SetDefault("SCRAPE_PLAN_FILE", "scrape-plan.json")
+	viper.SetDefault("PLAN_SCHEDULE", "* * * * *")
+
+	// the quality rules file seeds the rules kept in redis, articles failing
+	// them are not saved
+	viper.SetDefault("QUALITY_RULES_FILE", "quality-rules.json")
+
+	// admin endpoints are disabled until a token is set
+	viper.SetDefault("ADMIN_TOKEN", "")
+
+	// upstream http client, timeout and cooldown are in seconds
+	viper.SetDefault("UPSTREAM_TIMEOUT", 15)
+	viper.SetDefault("UPSTREAM_MAX_RETRIES", 3)
+	viper.SetDefault("UPSTREAM_BREAKER_THRESHOLD", 5)
+	viper.SetDefault("UPSTREAM_BREAKER_COOLDOWN", 120)
+
+	// scraper to saver stream, claim idle is in seconds. Entries read max
+	// deliveries times without being saved are dead lettered
+	viper.SetDefault("STREAM_BATCH_SIZE", 200)
+	viper.SetDefault("STREAM_CLAIM_IDLE", 300)
+	viper.SetDefault("STREAM_MAX_DELIVERIES", 5)
+
+	// near duplicates are only looked up among stored articles published
+	// within this many hours of the batch
+	viper.SetDefault("DEDUP_WINDOW_HOURS", 72)
+
+	// article page fetching, bodies shorter than the minimum are replaced
+	viper.SetDefault("USER_AGENT", "news-ags/1.0")
+	viper.SetDefault("FETCH_FULL_ARTICLE", false)
+	viper.SetDefault("MIN_CONTENT_LENGTH", 500)
+
+	// extracted keywords per article and the days of corpus statistics idf is computed over
+	viper.SetDefault("KEYWORD_LIMIT", 10)
+	viper.SetDefault("KEYWORD_WINDOW_DAYS", 30)
+
+	// category classifier, written by cmd/classifier. Uncategorized articles take
+	// the prediction above the assign threshold, labelled ones above the correct one
+	viper.SetDefault("CLASSIFIER_MODEL", "classifier.json")
+	viper.SetDefault("CLASSIFIER_ASSIGN_THRESHOLD", 0.6)
+	viper.SetDefault("CLASSIFIER_CORRECT_THRESHOLD", 0.95)
+
+	// entity extraction, names in the gazetteer are matched before the rules
+	// and articles keep their most mentioned entities
+	viper.SetDefault("ENTITY_GAZETTEER", "gazetteer.json")
+	viper.SetDefault("ENTITY_LIMIT", 20)
+
+	// extractive summaries, the long one for previews and the short teaser
+	viper.SetDefault("SUMMARY_SENTENCES", 3)
+	viper.SetDefault("SUMMARY_SHORT_SENTENCES", 1)
+
+	// reading time is estimated at an average adult silent reading speed
+	viper.SetDefault("READING_WORDS_PER_MINUTE", 238)
+
+	// lead image pipeline, thumbnails are written to the image dir and served
+	// at the public url unless the store is "s3". Images outside the byte,
+	// size and pixel bounds are rejected and images shared by the reuse
+	// threshold of articles are flagged as stock photos
+	viper.SetDefault("IMAGE_PIPELINE", false)
+	viper.SetDefault("IMAGE_STORE", "disk")
+	viper.SetDefault("IMAGE_DIR", "images")
+	viper.SetDefault("IMAGE_PUBLIC_URL", "http://localhost:8001/images")
+	viper.SetDefault("IMAGE_MAX_BYTES", 10<<20)
+	viper.SetDefault("IMAGE_MIN_WIDTH", 200)
+	viper.SetDefault("IMAGE_MIN_HEIGHT", 120)
+	viper.SetDefault("IMAGE_MAX_PIXELS", 16000000)
+	viper.SetDefault("IMAGE_THUMBNAIL_WIDTHS", "160,320,640")
+	viper.SetDefault("IMAGE_REUSE_THRESHOLD", 3)
+
+	// s3 compatible thumbnail store, path style suits minio. Objects are read
+	// from the public url when set
+	viper.SetDefault("S3_ENDPOINT", "")
+	viper.SetDefault("S3_REGION", "us-east-1")
+	viper.SetDefault("S3_BUCKET", "")
+	viper.SetDefault("S3_ACCESS_KEY", "")
+	viper.SetDefault("S3_SECRET_KEY", "")
+	viper.SetDefault("S3_PATH_STYLE", true)
+	viper.SetDefault("S3_PUBLIC_URL", "")
+
+	// story clustering, articles join a story active within the window when
+	// their similarity to it reaches the threshold
+	viper.SetDefault("STORY_WINDOW_HOURS", 48)
+	viper.SetDefault("STORY_SIMILARITY", 0.3)
+
+	// trending rankings, every window is compared against the baseline hours
+	// before it. Terms need the minimum count to trend and each article adds
+	// its top keywords
+	viper.SetDefault("TRENDING_SCHEDULE", "*/10 * * * *")
+	viper.SetDefault("TRENDING_WINDOWS", "1h,6h,24h")
+	viper.SetDefault("TRENDING_BASELINE_HOURS", 168)
+	viper.SetDefault("TRENDING_MIN_COUNT", 3)
+	viper.SetDefault("TRENDING_SIZE", 50)
+	viper.SetDefault("TRENDING_KEYWORDS", 5)
+
+	// ingestion run history, runs older than this are dropped
+	viper.SetDefault("RUN_RETENTION_DAYS", 30)
+
+	// dead letters, failed items not seen again for this long are dropped
+	viper.SetDefault("DEAD_LETTER_RETENTION_DAYS", 30)
+
 	viper.AutomaticEnv()
 
 	err = viper.ReadInConfig()
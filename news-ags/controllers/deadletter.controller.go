@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/services"
+)
+
+type DeadLetterController struct {
+	deadLetterService services.DeadLetterService
+	scraperService    services.ScrapeArticleService
+}
+
+func NewDeadLetterController(ds services.DeadLetterService, sc services.ScrapeArticleService) DeadLetterController {
+	return DeadLetterController{deadLetterService: ds, scraperService: sc}
+}
+
+// @Summary Dead Letters
+// @Description Lists upstream items and articles that failed ingestion, most recently seen first and without their payload. Stages are parse for upstream items that could not be read, cache for articles that could not be queued, decode for queued articles the saver could not read back, stream for queued articles the saver failed to write too often and quality for articles the quality rules rejected
+// @Produce json
+// @Security AdminToken
+// @Param stage query string false "parse, cache, decode, stream or quality"
+// @Param source query string false "only letters from this source"
+// @Param page query int false "page number, defaults to 1"
+// @Param limit query int false "letters per page, defaults to 20, at most 100"
+// @Success 200 {array} models.DeadLetter
+// @Failure 400 {object} string "invalid filter"
+// @Failure 401 {object} string "error message"
+// @Failure 500 {object} string "error message"
+// @Router /admin/deadletters [get]
+func (dC DeadLetterController) DeadLetters(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "page must be a positive number"})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "limit must be between 1 and 100"})
+		return
+	}
+
+	letters, total, err := dC.deadLetterService.DeadLetters(models.DeadLetterFilter{
+		Stage:  ctx.Query("stage"),
+		Source: ctx.Query("source"),
+		Limit:  int64(limit),
+		Skip:   int64((page - 1) * limit),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "page": page, "total": total, "dead_letters": letters})
+}
+
+// @Summary Dead Letter
+// @Description Returns a single dead letter with the raw payload, the stage that failed and the reason
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "dead letter id"
+// @Success 200 {object} models.DeadLetter
+// @Failure 401 {object} string "error message"
+// @Failure 404 {object} string "error message"
+// @Failure 500 {object} string "error message"
+// @Router /admin/deadletters/{id} [get]
+func (dC DeadLetterController) DeadLetter(ctx *gin.Context) {
+	letter, err := dC.deadLetterService.DeadLetter(ctx.Param("id"))
+	if err != nil {
+		dC.fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "dead_letter": letter})
+}
+
+// @Summary Retry Dead Letter
+// @Description Parses the payload again and puts its articles back on the stream for the next save, the letter is dropped once they are queued. A letter that fails again keeps the new reason
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "dead letter id"
+// @Success 200 {object} models.DeadLetter
+// @Failure 401 {object} string "error message"
+// @Failure 404 {object} string "error message"
+// @Failure 422 {object} models.DeadLetter "the letter failed again"
+// @Failure 500 {object} string "error message"
+// @Router /admin/deadletters/{id}/retry [post]
+func (dC DeadLetterController) Retry(ctx *gin.Context) {
+	letter, err := dC.deadLetterService.Retry(ctx.Param("id"), dC.scraperService.Requeue)
+	if errors.Is(err, services.ErrRetryFailed) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"status": "fail", "message": err.Error(), "dead_letter": letter})
+		return
+	}
+	if err != nil {
+		dC.fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Articles queued for the next save", "dead_letter": letter})
+}
+
+// @Summary Discard Dead Letter
+// @Description Drops a dead letter for good
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "dead letter id"
+// @Success 200 {object} string "Dead letter discarded"
+// @Failure 401 {object} string "error message"
+// @Failure 404 {object} string "error message"
+// @Failure 500 {object} string "error message"
+// @Router /admin/deadletters/{id} [delete]
+func (dC DeadLetterController) Discard(ctx *gin.Context) {
+	if err := dC.deadLetterService.Discard(ctx.Param("id")); err != nil {
+		dC.fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Dead letter discarded"})
+}
+
+func (dC DeadLetterController) fail(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrDeadLetterNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/services"
+)
+
+type ScrapePlanController struct {
+	planService services.ScrapePlanService
+}
+
+func NewScrapePlanController(ps services.ScrapePlanService) ScrapePlanController {
+	return ScrapePlanController{planService: ps}
+}
+
+// @Summary Scrape Plan
+// @Description Returns the newsdata scrape plan, every entry is expanded into categories x languages x countries x domains queries
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} models.ScrapePlan
+// @Failure 401 {object} string "error message"
+// @Failure 500 {object} string "error message"
+// @Router /admin/plan [get]
+func (pC ScrapePlanController) GetPlan(ctx *gin.Context) {
+	plan, err := pC.planService.Plan()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "plan": plan})
+}
+
+// @Summary Replace Scrape Plan
+// @Description Replaces the whole scrape plan, the change is picked up by the next run on every replica
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param plan body models.ScrapePlan true "scrape plan"
+// @Success 200 {object} models.ScrapePlan
+// @Failure 400 {object} string "error message"
+// @Failure 401 {object} string "error message"
+// @Failure 500 {object} string "error message"
+// @Router /admin/plan [put]
+func (pC ScrapePlanController) ReplacePlan(ctx *gin.Context) {
+	var plan models.ScrapePlan
+	if err := ctx.ShouldBindJSON(&plan); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	updated, err := pC.planService.Replace(plan)
+	if err != nil {
+		pC.fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "plan": updated})
+}
+
+// @Summary Put Scrape Plan Entry
+// @Description Adds or replaces a single scrape plan entry, use it to enable, disable or reschedule an entry
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param name path string true "entry name"
+// @Param entry body models.ScrapePlanEntry true "scrape plan entry"
+// @Success 200 {object} models.ScrapePlan
+// @Failure 400 {object} string "error message"
+// @Failure 401 {object} string "error message"
+// @Failure 500 {object} string "error message"
+// @Router /admin/plan/{name} [put]
+func (pC ScrapePlanController) PutEntry(ctx *gin.Context) {
+	var entry models.ScrapePlanEntry
+	entry.Name = ctx.Param("name")
+	if err := ctx.ShouldBindJSON(&entry); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	entry.Name = ctx.Param("name")
+
+	updated, err := pC.planService.PutEntry(entry)
+	if err != nil {
+		pC.fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "plan": updated})
+}
+
+// @Summary Delete Scrape Plan Entry
+// @Description Removes a scrape plan entry
+// @Produce json
+// @Security AdminToken
+// @Param name path string true "entry name"
+// @Success 200 {object} models.ScrapePlan
+// @Failure 401 {object} string "error message"
+// @Failure 404 {object} string "error message"
+// @Failure 500 {object} string "error message"
+// @Router /admin/plan/{name} [delete]
+func (pC ScrapePlanController) DeleteEntry(ctx *gin.Context) {
+	updated, err := pC.planService.DeleteEntry(ctx.Param("name"))
+	if err != nil {
+		pC.fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "plan": updated})
+}
+
+func (pC ScrapePlanController) fail(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPlan):
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+	case errors.Is(err, services.ErrEntryNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+	}
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/services"
+)
+
+type QualityController struct {
+	qualityService services.QualityService
+}
+
+func NewQualityController(qs services.QualityService) QualityController {
+	return QualityController{qualityService: qs}
+}
+
+// @Summary Quality Rules
+// @Description Returns the rules articles must pass before they are saved, rejections are counted per reason in the save run reports
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} models.QualityRules
+// @Failure 401 {object} string "error message"
+// @Failure 500 {object} string "error message"
+// @Router /admin/quality [get]
+func (qC QualityController) GetRules(ctx *gin.Context) {
+	rules, err := qC.qualityService.Rules()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "rules": rules})
+}
+
+// @Summary Replace Quality Rules
+// @Description Replaces the quality rules, the change is picked up by the next saved batch on every replica. Required fields are any of title, link, description, content, image_url and creator, zero values switch a rule off
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param rules body models.QualityRules true "quality rules"
+// @Success 200 {object} models.QualityRules
+// @Failure 400 {object} string "error message"
+// @Failure 401 {object} string "error message"
+// @Failure 500 {object} string "error message"
+// @Router /admin/quality [put]
+func (qC QualityController) ReplaceRules(ctx *gin.Context) {
+	var rules models.QualityRules
+	if err := ctx.ShouldBindJSON(&rules); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+
+	updated, err := qC.qualityService.Replace(rules)
+	if errors.Is(err, services.ErrInvalidRules) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "rules": updated})
+}
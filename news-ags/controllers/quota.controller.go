@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/services"
+)
+
+type QuotaController struct {
+	keys services.NewsdataKeyPool
+}
+
+func NewQuotaController(keys services.NewsdataKeyPool) QuotaController {
+	return QuotaController{keys: keys}
+}
+
+// @Summary Newsdata Quota
+// @Description Returns the newsdata.io credits used and left today on every api key of the pool, keys are masked. Credits reset at midnight UTC
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} models.NewsdataQuota
+// @Failure 401 {object} string "error message"
+// @Failure 500 {object} string "error message"
+// @Router /admin/quota [get]
+func (qC QuotaController) Quota(ctx *gin.Context) {
+	quota, err := qC.keys.Quota()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "quota": quota})
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/services"
+)
+
+type RunController struct {
+	runService services.RunService
+}
+
+func NewRunController(rs services.RunService) RunController {
+	return RunController{runService: rs}
+}
+
+// @Summary Ingestion Runs
+// @Description Lists scrape, plan and save runs newest first with their totals, timings and error samples. The per source and category breakdown is only returned by the single run endpoint
+// @Produce json
+// @Param kind query string false "scrape, plan or save"
+// @Param status query string false "running, success, partial or failed"
+// @Param source query string false "only runs that touched this source"
+// @Param since query string false "only runs started at or after this RFC 3339 time"
+// @Param page query int false "page number, defaults to 1"
+// @Param limit query int false "runs per page, defaults to 20, at most 100"
+// @Success 200 {array} models.IngestionRun
+// @Failure 400 {object} string "invalid filter"
+// @Failure 500 {object} string "error message"
+// @Router /runs [get]
+func (rC RunController) Runs(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "page must be a positive number"})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "limit must be between 1 and 100"})
+		return
+	}
+
+	filter := models.RunFilter{
+		Kind:   ctx.Query("kind"),
+		Status: ctx.Query("status"),
+		Source: ctx.Query("source"),
+		Limit:  int64(limit),
+		Skip:   int64((page - 1) * limit),
+	}
+	if since := ctx.Query("since"); since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "since must be an RFC 3339 time"})
+			return
+		}
+	}
+
+	runs, total, err := rC.runService.Runs(filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "page": page, "total": total, "runs": runs})
+}
+
+// @Summary Ingestion Run
+// @Description Returns a single run with fetched, parsed, deduplicated, inserted, updated and failed counts per source and category
+// @Produce json
+// @Param id path string true "run id"
+// @Success 200 {object} models.IngestionRun
+// @Failure 404 {object} string "error message"
+// @Failure 500 {object} string "error message"
+// @Router /runs/{id} [get]
+func (rC RunController) Run(ctx *gin.Context) {
+	run, err := rC.runService.Run(ctx.Param("id"))
+	if errors.Is(err, services.ErrRunNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "run": run})
+}
@@ -1,32 +1,46 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
 	"github.com/joey1123455/news-aggregator-service/news-ags/services"
 )
 
 type ArticleSaverController struct {
-	saverService services.ArticleSaverService
+	saverService     services.ArticleSaverService
+	schedulerService services.SchedulerService
 }
 
-func NewArticleSaverController(aSS services.ArticleSaverService) ArticleSaverController {
-	return ArticleSaverController{saverService: aSS}
+func NewArticleSaverController(aSS services.ArticleSaverService, ss services.SchedulerService) ArticleSaverController {
+	return ArticleSaverController{saverService: aSS, schedulerService: ss}
 }
 
 // @Summary Save News
 // @Description Saves news articles stored in a redis cache into a mongo collection
 // @Produce json
-// @Success 200 {object} string "News scrapped and added to cache"
+// @Success 200 {object} models.IngestionRun "News saved, the run report counts what was inserted, updated and deduplicated"
+// @Failure 409 {object} string "a save is already running"
 // @Failure 500 {object} string "error message"
 // @Router /save/news [get]
 func (aSC ArticleSaverController) SaveArticles(ctx *gin.Context) {
-	err := aSC.saverService.SaveArticles()
+	// the manual save takes the lock of the scheduled one so they never overlap
+	var run *models.IngestionRun
+	err := aSC.schedulerService.Run("save", func() error {
+		var err error
+		run, err = aSC.saverService.SaveArticles()
+		return err
+	})
+	if errors.Is(err, services.ErrJobRunning) {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "A save is already running, try again once it finished"})
+		return
+	}
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error(), "run": run})
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "News scrapped and added to cache"})
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "News saved from cache", "run": run})
 
 }
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/services"
+)
+
+type SchedulerController struct {
+	schedulerService services.SchedulerService
+}
+
+func NewSchedulerController(ss services.SchedulerService) SchedulerController {
+	return SchedulerController{schedulerService: ss}
+}
+
+// @Summary Scheduler Status
+// @Description Returns the last run, next run, duration and outcome of every scheduled ingestion job
+// @Produce json
+// @Success 200 {array} models.JobStatus
+// @Failure 500 {object} string "error message"
+// @Router /scheduler/status [get]
+func (sC SchedulerController) Status(ctx *gin.Context) {
+	jobs, err := sC.schedulerService.Status()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "jobs": jobs})
+}
@@ -1,32 +1,58 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
 	"github.com/joey1123455/news-aggregator-service/news-ags/services"
 )
 
 type ArticleScrapperController struct {
-	scraperService services.ScrapeArticleService
+	scraperService   services.ScrapeArticleService
+	schedulerService services.SchedulerService
 }
 
-func NewArticleScrapperController(sc services.ScrapeArticleService) ArticleScrapperController {
-	return ArticleScrapperController{scraperService: sc}
+func NewArticleScrapperController(sc services.ScrapeArticleService, ss services.SchedulerService) ArticleScrapperController {
+	return ArticleScrapperController{scraperService: sc, schedulerService: ss}
 }
 
 // @Summary Scrape News
 // @Description Makes a get request to newsapi.io and cahes news objects in database
 // @Produce json
-// @Success 200 {object} string "News scrapped and added to cache"
+// @Success 200 {object} models.ScrapeReport "News scrapped and added to cache, status is partial when some fetches failed"
+// @Failure 409 {object} string "a scrape is already running"
 // @Failure 500 {object} string "error message"
 // @Router /scrape/news [get]
 func (aSC ArticleScrapperController) ScrapeNews(ctx *gin.Context) {
-	err := aSC.scraperService.ParseArticle()
-	if err != nil {
+	// the manual scrape takes the lock of the scheduled one so they never overlap
+	var report *models.ScrapeReport
+	err := aSC.schedulerService.Run("scrape", func() error {
+		var err error
+		if report, err = aSC.scraperService.ParseArticle(); err != nil {
+			return err
+		}
+		return report.Err()
+	})
+	if errors.Is(err, services.ErrJobRunning) {
+		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "A scrape is already running, try again once it finished"})
+		return
+	}
+	if report == nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": err.Error()})
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "News scrapped and added to cache"})
+
+	if report.Failed > 0 && report.Succeeded == 0 {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"status": "fail", "message": report.Err().Error(), "report": report})
+		return
+	}
+
+	if report.Failed > 0 {
+		ctx.JSON(http.StatusOK, gin.H{"status": "partial", "message": report.Err().Error(), "report": report})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "News scrapped and added to cache", "report": report})
 
 }
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,6 +15,7 @@ import (
 	"github.com/joey1123455/news-aggregator-service/news-ags/config"
 	"github.com/joey1123455/news-aggregator-service/news-ags/controllers"
 	docs "github.com/joey1123455/news-aggregator-service/news-ags/docs"
+	"github.com/joey1123455/news-aggregator-service/news-ags/newsdatafake"
 	"github.com/joey1123455/news-aggregator-service/news-ags/routes"
 	"github.com/joey1123455/news-aggregator-service/news-ags/services"
 
@@ -25,20 +28,58 @@ import (
 )
 
 var (
-	server            *gin.Engine
-	ctx               context.Context
-	mongoclient       *mongo.Client
-	redisclient       *redis.Client
-	articleCollection *mongo.Collection
-
-	scraperService services.ScrapeArticleService
-	saverService   services.ArticleSaverService
-
-	scraperController controllers.ArticleScrapperController
-	saverController   controllers.ArticleSaverController
-
-	scraperRoutesController routes.ScrapeRouteController
-	saverRouteController    routes.SaveRouteController
+	server               *gin.Engine
+	ctx                  context.Context
+	mongoclient          *mongo.Client
+	redisclient          *redis.Client
+	articleCollection    *mongo.Collection
+	sourceCollection     *mongo.Collection
+	corpusTermCollection *mongo.Collection
+	corpusDayCollection  *mongo.Collection
+	storyCollection      *mongo.Collection
+	imageCollection      *mongo.Collection
+	runCollection        *mongo.Collection
+	deadLetterCollection *mongo.Collection
+
+	// replayDir holds recorded newsdata responses, when set the service
+	// ingests them once from a local stand-in server and exits
+	replayDir    string
+	replayServer *newsdatafake.Server
+
+	sourceRegistry services.SourceRegistry
+	newsdataKeys   services.NewsdataKeyPool
+	deadLetters    services.DeadLetterService
+
+	planService      services.ScrapePlanService
+	qualityService   services.QualityService
+	keywordService   services.KeywordService
+	classifier       services.ClassifierService
+	entityService    services.EntityService
+	imageService     services.ImageService
+	runService       services.RunService
+	storyService     services.StoryService
+	trendingService  services.TrendingService
+	scraperService   services.ScrapeArticleService
+	saverService     services.ArticleSaverService
+	schedulerService services.SchedulerService
+
+	scraperController    controllers.ArticleScrapperController
+	saverController      controllers.ArticleSaverController
+	schedulerController  controllers.SchedulerController
+	planController       controllers.ScrapePlanController
+	qualityController    controllers.QualityController
+	quotaController      controllers.QuotaController
+	runController        controllers.RunController
+	deadLetterController controllers.DeadLetterController
+
+	scraperRoutesController   routes.ScrapeRouteController
+	saverRouteController      routes.SaveRouteController
+	schedulerRouteController  routes.SchedulerRouteController
+	planRouteController       routes.ScrapePlanRouteController
+	qualityRouteController    routes.QualityRouteController
+	quotaRouteController      routes.QuotaRouteController
+	runRouteController        routes.RunRouteController
+	deadLetterRouteController routes.DeadLetterRouteController
 )
 
 //	@title			News Aggregator service
@@ -55,6 +96,10 @@ var (
 // @host 51.21.106.236:8001
 // @BasePath /api
 
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name X-Admin-Token
+
 func main() {
 	config, err := config.LoadConfig(".")
 
@@ -78,6 +123,44 @@ func main() {
 
 	server.Use(cors.New(corsConfig))
 
+	if replayDir != "" {
+		replay()
+		return
+	}
+
+	scrapeJob := func() error {
+		report, err := scraperService.ParseArticle()
+		if err != nil {
+			return err
+		}
+		return report.Err()
+	}
+	if err := schedulerService.Register("scrape", config.ScrapeSchedule, scrapeJob); err != nil {
+		log.Fatal("Could not schedule scraper", err)
+	}
+	planJob := func() error {
+		report, err := scraperService.ScrapeScheduled()
+		if err != nil {
+			return err
+		}
+		return report.Err()
+	}
+	if err := schedulerService.Register("plan", config.PlanSchedule, planJob); err != nil {
+		log.Fatal("Could not schedule scrape plan", err)
+	}
+	saveJob := func() error {
+		_, err := saverService.SaveArticles()
+		return err
+	}
+	if err := schedulerService.Register("save", config.SaveSchedule, saveJob); err != nil {
+		log.Fatal("Could not schedule saver", err)
+	}
+	if err := schedulerService.Register("trending", config.TrendingSchedule, trendingService.Rank); err != nil {
+		log.Fatal("Could not schedule trending", err)
+	}
+	schedulerService.Start()
+	defer schedulerService.Stop()
+
 	docs.SwaggerInfo.BasePath = "/api"
 	router := server.Group("/api")
 	router.GET("/healthchecker", func(ctx *gin.Context) {
@@ -85,17 +168,53 @@ func main() {
 	})
 	scraperRoutesController.ScrapeRoute(router, scraperService)
 	saverRouteController.SaveRoute(router, saverService)
+	schedulerRouteController.SchedulerRoute(router, schedulerService)
+	planRouteController.ScrapePlanRoute(router, planService, config.AdminToken)
+	qualityRouteController.QualityRoute(router, config.AdminToken)
+	quotaRouteController.QuotaRoute(router, config.AdminToken)
+	runRouteController.RunRoute(router)
+	deadLetterRouteController.DeadLetterRoute(router, config.AdminToken)
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
+	// thumbnails kept on disk are served next to the api
+	if config.ImagePipeline && config.ImageStore != "s3" {
+		server.Static("/images", config.ImageDir)
+	}
+
 	log.Fatal(server.Run(":" + config.Port))
 }
 
 func init() {
+	flag.StringVar(&replayDir, "replay", "", "ingest the newsdata responses recorded in this directory and exit")
+	flag.Parse()
+
 	Config, err := config.LoadConfig(".")
 	if err != nil {
 		log.Fatal("Could not load environment variables", err)
 	}
 
+	database, redisDB := "golang_mongodb", 0
+
+	// replays only reach the stand-in server, article pages and images would
+	// go to the network. They write to their own database and redis db so the
+	// service data is left alone and dedup starts from nothing every time
+	if replayDir != "" {
+		// both are emptied before the replay, never let that be the service data
+		if Config.ReplayDatabase == "" || Config.ReplayDatabase == database || Config.ReplayRedisDB == redisDB {
+			log.Fatal("Could not replay, REPLAY_DATABASE and REPLAY_REDIS_DB must not be the service's own")
+		}
+		database, redisDB = Config.ReplayDatabase, Config.ReplayRedisDB
+
+		replayServer, err = newsdatafake.NewServer(replayDir, newsdatafake.Options{PageSize: 50})
+		if err != nil {
+			log.Fatal("Could not start the replay server", err)
+		}
+		Config.NewsdataURL = replayServer.NewsURL()
+		Config.ApiKeys, Config.ApiKey = "replay:1000000", ""
+		Config.FetchFullArticle = false
+		Config.ImagePipeline = false
+	}
+
 	ctx = context.TODO()
 
 	// Connect to MongoDB
@@ -117,6 +236,7 @@ func init() {
 	// Connect to Redis
 	redisclient = redis.NewClient(&redis.Options{
 		Addr: Config.RedisUri,
+		DB:   redisDB,
 	})
 
 	if _, err := redisclient.Ping().Result(); err != nil {
@@ -132,20 +252,178 @@ func init() {
 
 	fmt.Println("Redis client connected successfully...")
 
+	if replayDir != "" {
+		if err := mongoclient.Database(database).Drop(ctx); err != nil {
+			log.Fatal("Could not empty the replay database ", err)
+		}
+		if err := redisclient.FlushDB().Err(); err != nil {
+			log.Fatal("Could not empty the replay redis db ", err)
+		}
+	}
+
 	// Collections
-	articleCollection = mongoclient.Database("golang_mongodb").Collection("articles")
+	articleCollection = mongoclient.Database(database).Collection("articles")
+	sourceCollection = mongoclient.Database(database).Collection("sources")
+	corpusTermCollection = mongoclient.Database(database).Collection("corpus_terms")
+	corpusDayCollection = mongoclient.Database(database).Collection("corpus_days")
+	storyCollection = mongoclient.Database(database).Collection("stories")
+	imageCollection = mongoclient.Database(database).Collection("images")
+	runCollection = mongoclient.Database(database).Collection("ingestion_runs")
+	deadLetterCollection = mongoclient.Database(database).Collection("dead_letters")
+
+	// Sources
+	if Config.SourcesRegistry == "mongo" {
+		sourceRegistry = services.NewMongoSourceRegistry(ctx, sourceCollection)
+	} else {
+		sourceRegistry = services.NewFileSourceRegistry(Config.SourcesFile)
+	}
+	if replayDir != "" {
+		sourceRegistry = services.NewReplaySourceRegistry(sourceRegistry)
+	}
+
+	upstreamClient := services.NewUpstreamClient(services.UpstreamOptions{
+		UserAgent:        Config.UserAgent,
+		Timeout:          time.Duration(Config.UpstreamTimeout) * time.Second,
+		MaxRetries:       Config.UpstreamMaxRetries,
+		BreakerThreshold: Config.BreakerThreshold,
+		BreakerCooldown:  time.Duration(Config.BreakerCooldown) * time.Second,
+	})
+	// the single key of older configs joins the pool last
+	keys, err := services.ParseNewsdataKeys(Config.ApiKeys+","+Config.ApiKey, Config.DailyCredits)
+	if err != nil {
+		log.Fatal("Could not read newsdata api keys", err)
+	}
+	newsdataKeys = services.NewNewsdataKeyPool(redisclient, keys)
+	deadLetters = services.NewDeadLetterService(ctx, deadLetterCollection, time.Duration(Config.DeadLetterRetentionDays)*24*time.Hour)
+	sourceFactory := services.SourceFactory{
+		Keys:        newsdataKeys,
+		NewsdataURL: Config.NewsdataURL,
+		PageBudget:  Config.PageBudget,
+		Upstream:    upstreamClient,
+		RClient:     redisclient,
+		DeadLetters: deadLetters,
+	}
+
+	streamOptions := services.StreamOptions{
+		BatchSize:     int64(Config.StreamBatchSize),
+		ClaimIdle:     time.Duration(Config.StreamClaimIdle) * time.Second,
+		MaxDeliveries: int64(Config.StreamMaxDeliveries),
+		DedupWindow:   time.Duration(Config.DedupWindowHours) * time.Hour,
+	}
+
+	var enricher *services.ArticleEnricher
+	if Config.FetchFullArticle {
+		enricher = services.NewArticleEnricher(upstreamClient, Config.UserAgent, Config.MinContentLength)
+	}
 
 	// Services
-	scraperService = services.NewScrapper(ctx, redisclient, Config.ApiKey)
-	saverService = services.NewArticleSaver(ctx, redisclient, articleCollection)
+	planService = services.NewScrapePlanService(redisclient, Config.ScrapePlanFile)
+	runService = services.NewRunService(ctx, runCollection, time.Duration(Config.RunRetentionDays)*24*time.Hour)
+	scraperService = services.NewScrapper(ctx, redisclient, sourceFactory, sourceRegistry, planService, streamOptions, enricher, runService, deadLetters)
+	keywordService = services.NewKeywordService(ctx, corpusTermCollection, corpusDayCollection, Config.KeywordLimit, Config.KeywordWindowDays)
+	classifier = services.NewClassifier(Config.ClassifierModel, Config.ClassifierAssignThreshold, Config.ClassifierCorrectThreshold)
+	entityService = services.NewEntityService(Config.EntityGazetteer, Config.EntityLimit)
+	summarizer := services.NewSummarizer(Config.SummarySentences, Config.SummaryShortSentences)
+	readingMeter := services.NewReadingMeter(Config.ReadingWordsPerMinute)
+	if Config.ImagePipeline {
+		imageStore, err := newImageStore(Config)
+		if err != nil {
+			log.Fatal("Could not set up the image store", err)
+		}
+		thumbnailWidths, err := services.ParseThumbnailWidths(Config.ImageThumbnailWidths)
+		if err != nil {
+			log.Fatal("Could not read thumbnail widths", err)
+		}
+		imageService = services.NewImageService(ctx, imageCollection, articleCollection, imageStore, services.ImageOptions{
+			UserAgent:      Config.UserAgent,
+			Timeout:        time.Duration(Config.UpstreamTimeout) * time.Second,
+			MaxBytes:       Config.ImageMaxBytes,
+			MinWidth:       Config.ImageMinWidth,
+			MinHeight:      Config.ImageMinHeight,
+			MaxPixels:      Config.ImageMaxPixels,
+			Widths:         thumbnailWidths,
+			ReuseThreshold: Config.ImageReuseThreshold,
+		})
+	}
+	qualityService = services.NewQualityService(ctx, redisclient, articleCollection, Config.QualityRulesFile)
+	if replayDir != "" {
+		qualityService = services.NewReplayQualityService(ctx, redisclient, articleCollection, Config.QualityRulesFile)
+	}
+	storyService = services.NewStoryService(ctx, storyCollection, articleCollection, time.Duration(Config.StoryWindowHours)*time.Hour, Config.StorySimilarity)
+	trendingWindows, err := services.ParseTrendingWindows(Config.TrendingWindows)
+	if err != nil {
+		log.Fatal("Could not read trending windows", err)
+	}
+	trendingService = services.NewTrendingService(redisclient, services.TrendingOptions{
+		Windows:  trendingWindows,
+		Baseline: time.Duration(Config.TrendingBaselineHours) * time.Hour,
+		MinCount: Config.TrendingMinCount,
+		Keywords: Config.TrendingKeywords,
+		Size:     Config.TrendingSize,
+	})
+	saverService = services.NewArticleSaver(ctx, redisclient, articleCollection, keywordService, classifier, entityService, summarizer, readingMeter, imageService, storyService, trendingService, qualityService, runService, deadLetters, streamOptions)
+	schedulerService, err = services.NewScheduler(ctx, redisclient, time.Duration(Config.SchedulerLockTTL)*time.Second)
+	if err != nil {
+		log.Fatal("Could not set up the scheduler ", err)
+	}
 
 	// Controllers
-	scraperController = controllers.NewArticleScrapperController(scraperService)
-	saverController = controllers.NewArticleSaverController(saverService)
+	scraperController = controllers.NewArticleScrapperController(scraperService, schedulerService)
+	saverController = controllers.NewArticleSaverController(saverService, schedulerService)
+	schedulerController = controllers.NewSchedulerController(schedulerService)
+	planController = controllers.NewScrapePlanController(planService)
+	qualityController = controllers.NewQualityController(qualityService)
+	quotaController = controllers.NewQuotaController(newsdataKeys)
+	runController = controllers.NewRunController(runService)
+	deadLetterController = controllers.NewDeadLetterController(deadLetters, scraperService)
 
 	// Routes
 	scraperRoutesController = routes.NewScrapeRouteController(scraperController)
 	saverRouteController = routes.NewSaverRouteController(saverController)
+	schedulerRouteController = routes.NewSchedulerRouteController(schedulerController)
+	planRouteController = routes.NewScrapePlanRouteController(planController)
+	qualityRouteController = routes.NewQualityRouteController(qualityController)
+	quotaRouteController = routes.NewQuotaRouteController(quotaController)
+	runRouteController = routes.NewRunRouteController(runController)
+	deadLetterRouteController = routes.NewDeadLetterRouteController(deadLetterController)
 
 	server = gin.Default()
 }
+
+// replay scrapes the recorded responses and saves them like a scheduled run
+// would, the scrape and save reports are printed for the caller to compare
+func replay() {
+	defer replayServer.Close()
+
+	report, err := scraperService.ParseArticle()
+	if err != nil {
+		log.Fatal("Could not replay the recorded responses ", err)
+	}
+	run, err := saverService.SaveArticles()
+	if err != nil {
+		log.Fatal("Could not save the replayed articles ", err)
+	}
+
+	reportJSON, _ := json.MarshalIndent(gin.H{"scrape": report, "save": run}, "", "  ")
+	fmt.Println(string(reportJSON))
+	if err := report.Err(); err != nil {
+		log.Fatal("Replay finished with failures ", err)
+	}
+}
+
+// newImageStore picks where thumbnails are kept from the config
+func newImageStore(config config.Config) (services.ImageStore, error) {
+	if config.ImageStore == "s3" {
+		return services.NewS3ImageStore(services.S3Options{
+			Endpoint:  config.S3Endpoint,
+			Region:    config.S3Region,
+			Bucket:    config.S3Bucket,
+			AccessKey: config.S3AccessKey,
+			SecretKey: config.S3SecretKey,
+			PathStyle: config.S3PathStyle,
+			PublicURL: config.S3PublicURL,
+		})
+	}
+
+	return services.NewDiskImageStore(config.ImageDir, config.ImagePublicURL), nil
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin guards admin endpoints with the shared admin token, sent as a
+// bearer token or in the X-Admin-Token header. An empty token disables them
+func RequireAdmin(adminToken string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if adminToken == "" {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "Admin endpoints are disabled"})
+			return
+		}
+
+		token := ctx.Request.Header.Get("X-Admin-Token")
+		fields := strings.Fields(ctx.Request.Header.Get("Authorization"))
+		if len(fields) == 2 && fields[0] == "Bearer" {
+			token = fields[1]
+		}
+
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": "Invalid admin token"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -20,13 +21,99 @@ type Article struct {
 	Country     []string           `json:"country" bson:"country"`
 	Category    []string           `json:"category" bson:"category"`
 	Date        string             `json:"pubDate" bson:"pubDate"`
+	Language    string             `json:"language" bson:"language"`
+	PublishedAt time.Time          `json:"published_at" bson:"published_at"`
+	IngestedAt  time.Time          `json:"ingested_at" bson:"ingested_at"`
+
+	KeywordWeights []KeywordWeight `json:"keyword_weights" bson:"keyword_weights"`
+
+	StoryId primitive.ObjectID `json:"story_id" bson:"story_id,omitempty"`
+
+	Entities []Entity `json:"entities" bson:"entities"`
+
+	ImageInfo *ArticleImage `json:"image,omitempty" bson:"image,omitempty"`
+
+	TextLanguage string `json:"-" bson:"text_language"`
+
+	Summary      string `json:"summary" bson:"summary"`
+	SummaryShort string `json:"summary_short" bson:"summary_short"`
+
+	WordCount   int      `json:"word_count" bson:"word_count"`
+	ReadingTime int      `json:"reading_time" bson:"reading_time"`
+	ReadingEase *float64 `json:"reading_ease,omitempty" bson:"reading_ease,omitempty"`
+	GradeLevel  *float64 `json:"grade_level,omitempty" bson:"grade_level,omitempty"`
+
+	Sentiment float64 `json:"sentiment" bson:"sentiment"`
+	Tone      string  `json:"tone" bson:"tone"`
+
+	CategorySource     string   `json:"category_source" bson:"category_source"`
+	PredictedCategory  string   `json:"predicted_category" bson:"predicted_category"`
+	CategoryConfidence float64  `json:"category_confidence" bson:"category_confidence"`
+	UpstreamCategory   []string `json:"upstream_category,omitempty" bson:"upstream_category,omitempty"`
+
+	Fingerprint      string   `json:"fingerprint" bson:"fingerprint"`
+	FingerprintBands []string `json:"fingerprint_bands" bson:"fingerprint_bands"`
+	// ContentLength lets deduplication compare bodies without loading them
+	ContentLength int `json:"-" bson:"content_length"`
+
+	CanonicalKey string    `json:"canonical_key" bson:"canonical_key"`
+	TitleKey     string    `json:"-" bson:"title_key,omitempty"`
+	FirstSeenAt  time.Time `json:"first_seen_at" bson:"first_seen_at"`
+	LastSeenAt   time.Time `json:"last_seen_at" bson:"last_seen_at"`
+	Revision     int       `json:"revision" bson:"revision"`
 }
 
+// KeywordWeight is the relevance of a keyword to its article, between 0 and 1
+type KeywordWeight struct {
+	Term   string  `json:"term" bson:"term"`
+	Weight float64 `json:"weight" bson:"weight"`
+}
+
+const (
+	CategorySourceUpstream   = "upstream"
+	CategorySourceClassifier = "classifier"
+)
+
+const (
+	ToneVeryNegative = "very_negative"
+	ToneNegative     = "negative"
+	ToneNeutral      = "neutral"
+	TonePositive     = "positive"
+	ToneVeryPositive = "very_positive"
+
+	// sentiment at or beyond the strong threshold is very negative or very positive
+	ToneStrongThreshold = 0.6
+	ToneThreshold       = 0.2
+)
+
+// Tone labels a sentiment score between -1 and 1
+func Tone(sentiment float64) string {
+	switch {
+	case sentiment <= -ToneStrongThreshold:
+		return ToneVeryNegative
+	case sentiment <= -ToneThreshold:
+		return ToneNegative
+	case sentiment >= ToneStrongThreshold:
+		return ToneVeryPositive
+	case sentiment >= ToneThreshold:
+		return TonePositive
+	default:
+		return ToneNeutral
+	}
+}
+
+// NewsResponse keeps the results raw so one malformed article does not fail the page
 type NewsResponse struct {
-	Status       string    `json:"status"`
-	TotalResults int       `json:"totalResults"`
-	Articles     []Article `json:"results"`
-	NextPage     string    `json:"nextPage"`
+	Status       string            `json:"status"`
+	TotalResults int               `json:"totalResults"`
+	Results      []json.RawMessage `json:"results"`
+	NextPage     string            `json:"nextPage"`
+}
+
+// NewsdataArticle decodes newsdata.io results, whose article ids are not mongo object ids
+type NewsdataArticle struct {
+	Article
+	ArticleId string `json:"article_id"`
 }
 
 type MongoArticle struct {
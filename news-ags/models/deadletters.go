@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Stages an article can be lost at on its way to mongo
+const (
+	// DeadLetterStageParse is an upstream item or feed document that could not be read
+	DeadLetterStageParse = "parse"
+	// DeadLetterStageCache is an article that could not be queued on the stream
+	DeadLetterStageCache = "cache"
+	// DeadLetterStageDecode is a stream entry the saver could not read back
+	DeadLetterStageDecode = "decode"
+	// DeadLetterStageStream is a queued article the saver failed to write too often
+	DeadLetterStageStream = "stream"
+	// DeadLetterStageQuality is an article the quality rules turned away
+	DeadLetterStageQuality = "quality"
+)
+
+// DeadLetter keeps the raw payload of something that failed ingestion so it
+// can be inspected and retried. The same payload failing again at the same
+// stage updates the letter instead of adding another
+type DeadLetter struct {
+	Id          primitive.ObjectID `json:"id" bson:"_id"`
+	Stage       string             `json:"stage" bson:"stage"`
+	Source      string             `json:"source,omitempty" bson:"source,omitempty"`
+	Category    string             `json:"category,omitempty" bson:"category,omitempty"`
+	Reason      string             `json:"reason" bson:"reason"`
+	Payload     string             `json:"payload,omitempty" bson:"payload"`
+	Truncated   bool               `json:"truncated,omitempty" bson:"truncated,omitempty"`
+	Fingerprint string             `json:"-" bson:"fingerprint"`
+	Occurrences int                `json:"occurrences" bson:"occurrences"`
+	Attempts    int                `json:"attempts" bson:"attempts"`
+	FirstSeenAt time.Time          `json:"first_seen_at" bson:"first_seen_at"`
+	LastSeenAt  time.Time          `json:"last_seen_at" bson:"last_seen_at"`
+	RetriedAt   *time.Time         `json:"retried_at,omitempty" bson:"retried_at,omitempty"`
+	ExpiresAt   time.Time          `json:"-" bson:"expires_at"`
+}
+
+// DeadLetterFilter narrows the dead letter listing, zero values match everything
+type DeadLetterFilter struct {
+	Stage  string
+	Source string
+	Limit  int64
+	Skip   int64
+}
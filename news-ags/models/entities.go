@@ -0,0 +1,37 @@
+package models
+
+const (
+	EntityPerson       = "person"
+	EntityOrganisation = "organisation"
+	EntityLocation     = "location"
+)
+
+const (
+	EntitySourceGazetteer = "gazetteer"
+	EntitySourceRule      = "rule"
+)
+
+// Entity is a person, organisation or location mentioned by an article. The id
+// is normalized as <type>:<slug> so every spelling of a name shares one id
+type Entity struct {
+	Id       string `json:"id" bson:"id"`
+	Type     string `json:"type" bson:"type"`
+	Name     string `json:"name" bson:"name"`
+	Mentions int    `json:"mentions" bson:"mentions"`
+	Source   string `json:"source" bson:"source"`
+	// NameKeys is the normalized name from each of its words on, the content
+	// service looks entities up by their prefix
+	NameKeys []string `json:"-" bson:"name_keys"`
+}
+
+// GazetteerEntry is a known entity and the names it appears under
+type GazetteerEntry struct {
+	Id      string   `json:"id"`
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+type Gazetteer struct {
+	Entries []GazetteerEntry `json:"entries"`
+}
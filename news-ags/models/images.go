@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+const (
+	ImageOK          = "ok"
+	ImageBroken      = "broken"
+	ImageInvalid     = "invalid"
+	ImageUnsupported = "unsupported"
+	ImageTooSmall    = "too_small"
+	ImageTooLarge    = "too_large"
+)
+
+// Thumbnail is a resized jpeg copy of an article image
+type Thumbnail struct {
+	Width  int    `json:"width" bson:"width"`
+	Height int    `json:"height" bson:"height"`
+	URL    string `json:"url" bson:"url"`
+}
+
+// ArticleImage describes the lead image of an article once it was fetched,
+// thumbnails are only set when the status is ok. Reused images are shared by
+// enough articles to be stock or file photos
+type ArticleImage struct {
+	Status      string      `json:"status" bson:"status"`
+	Hash        string      `json:"hash,omitempty" bson:"hash,omitempty"`
+	Width       int         `json:"width,omitempty" bson:"width,omitempty"`
+	Height      int         `json:"height,omitempty" bson:"height,omitempty"`
+	ContentType string      `json:"content_type,omitempty" bson:"content_type,omitempty"`
+	Thumbnails  []Thumbnail `json:"thumbnails" bson:"thumbnails"`
+	Reused      bool        `json:"reused" bson:"reused"`
+}
+
+// Image is a distinct picture keyed by its perceptual hash, every upstream url
+// it was fetched from points at the same thumbnails. Uses counts the articles
+// saved with it
+type Image struct {
+	Hash        string      `json:"hash" bson:"_id"`
+	HashBands   []string    `json:"hash_bands" bson:"hash_bands"`
+	URLs        []string    `json:"urls" bson:"urls"`
+	Width       int         `json:"width" bson:"width"`
+	Height      int         `json:"height" bson:"height"`
+	ContentType string      `json:"content_type" bson:"content_type"`
+	Bytes       int         `json:"bytes" bson:"bytes"`
+	Thumbnails  []Thumbnail `json:"thumbnails" bson:"thumbnails"`
+	Uses        int         `json:"uses" bson:"uses"`
+	FirstSeenAt time.Time   `json:"first_seen_at" bson:"first_seen_at"`
+	LastSeenAt  time.Time   `json:"last_seen_at" bson:"last_seen_at"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// ScrapePlanEntry describes a slice of a newsdata source to scrape, every
+// combination of its categories, languages, countries and domains is queried
+// on its own. An empty dimension leaves that filter off the query
+type ScrapePlanEntry struct {
+	Name       string            `json:"name" binding:"required"`
+	Source     string            `json:"source"`
+	Enabled    bool              `json:"enabled"`
+	Schedule   string            `json:"schedule,omitempty"`
+	Categories []string          `json:"categories"`
+	Languages  []string          `json:"languages"`
+	Countries  []string          `json:"countries"`
+	Domains    []string          `json:"domains"`
+	Params     map[string]string `json:"params,omitempty"`
+	PageBudget int               `json:"page_budget,omitempty"`
+}
+
+type ScrapePlan struct {
+	Entries   []ScrapePlanEntry `json:"entries"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// NewsdataQuery is a single cell of a scrape plan entry
+type NewsdataQuery struct {
+	Entry      string
+	Category   string
+	Language   string
+	Country    string
+	Domain     string
+	Params     map[string]string
+	PageBudget int
+}
+
+// Key identifies the query, it is stable across runs so cursors can be resumed
+func (q NewsdataQuery) Key() string {
+	return strings.Join([]string{q.Entry, q.Category, q.Language, q.Country, q.Domain}, ":")
+}
+
+// Queries expands the entry into one query per combination of its dimensions
+func (e ScrapePlanEntry) Queries() []NewsdataQuery {
+	queries := []NewsdataQuery{{Entry: e.Name, Params: e.Params, PageBudget: e.PageBudget}}
+
+	expand := func(values []string, set func(*NewsdataQuery, string)) {
+		if len(values) == 0 {
+			return
+		}
+
+		expanded := make([]NewsdataQuery, 0, len(queries)*len(values))
+		for _, query := range queries {
+			for _, value := range values {
+				q := query
+				set(&q, value)
+				expanded = append(expanded, q)
+			}
+		}
+		queries = expanded
+	}
+
+	expand(e.Categories, func(q *NewsdataQuery, v string) { q.Category = v })
+	expand(e.Languages, func(q *NewsdataQuery, v string) { q.Language = v })
+	expand(e.Countries, func(q *NewsdataQuery, v string) { q.Country = v })
+	expand(e.Domains, func(q *NewsdataQuery, v string) { q.Domain = v })
+
+	return queries
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Reasons the quality gate rejects an article for, a missing required field
+// is reported as missing_ followed by the field name
+const (
+	RejectMissingPrefix  = "missing_"
+	RejectContentShort   = "content_too_short"
+	RejectBoilerplate    = "boilerplate"
+	RejectBlockedDomain  = "blocked_domain"
+	RejectBlockedKeyword = "blocked_keyword"
+	RejectTooOld         = "too_old"
+	RejectDuplicateTitle = "duplicate_title"
+)
+
+// QualityFields are the article fields rules can require
+var QualityFields = []string{"title", "link", "description", "content", "image_url", "creator"}
+
+// QualityRules decide which articles are worth saving, zero values switch a
+// rule off. Text rules compare case insensitively and domains match their
+// subdomains too
+type QualityRules struct {
+	RequiredFields []string `json:"required_fields"`
+	// MinContentLength is the number of characters the content must reach
+	MinContentLength int `json:"min_content_length"`
+	// Boilerplate are phrases upstreams put in place of the article, like a
+	// paywall notice. Articles are rejected when their content is little more
+	// than these phrases
+	Boilerplate     []string `json:"boilerplate"`
+	BlockedDomains  []string `json:"blocked_domains"`
+	BlockedKeywords []string `json:"blocked_keywords"`
+	// MaxAgeHours rejects articles published longer ago
+	MaxAgeHours int `json:"max_age_hours"`
+	// DuplicateTitleHours rejects an article whose title an article at
+	// another url published within the hours already carries, unless its
+	// source weighs more
+	DuplicateTitleHours int       `json:"duplicate_title_hours"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// QualityRejection is an article the gate turned away and why
+type QualityRejection struct {
+	Article Article
+	Reason  string
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// NewsdataKeyQuota is the credit usage of one newsdata.io api key for the
+// current day, keys are only shown masked
+type NewsdataKeyQuota struct {
+	Id           string     `json:"id"`
+	Key          string     `json:"key"`
+	Limit        int        `json:"limit"`
+	Used         int        `json:"used"`
+	Remaining    int        `json:"remaining"`
+	Exhausted    bool       `json:"exhausted"`
+	CoolingUntil *time.Time `json:"cooling_until,omitempty"`
+}
+
+// NewsdataQuota sums the credit usage of the whole key pool, credits reset at
+// midnight UTC
+type NewsdataQuota struct {
+	Day       string             `json:"day"`
+	ResetsAt  time.Time          `json:"resets_at"`
+	Limit     int                `json:"limit"`
+	Used      int                `json:"used"`
+	Remaining int                `json:"remaining"`
+	Keys      []NewsdataKeyQuota `json:"keys"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type FetchReport struct {
+	Source   string `json:"source"`
+	Entry    string `json:"entry,omitempty"`
+	Category string `json:"category,omitempty"`
+	Language string `json:"language,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Articles int    `json:"articles"`
+	Queued   int    `json:"queued"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ScrapeReport struct {
+	RunId      string        `json:"run_id,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Succeeded  int           `json:"succeeded"`
+	Failed     int           `json:"failed"`
+	Fetches    []FetchReport `json:"fetches"`
+}
+
+func (sr *ScrapeReport) Add(reports ...FetchReport) {
+	for _, report := range reports {
+		if report.Error != "" {
+			sr.Failed++
+		} else {
+			sr.Succeeded++
+		}
+		sr.Fetches = append(sr.Fetches, report)
+	}
+}
+
+// Err summarises the failed fetches, nil when every fetch succeeded
+func (sr *ScrapeReport) Err() error {
+	if sr.Failed == 0 {
+		return nil
+	}
+
+	for _, report := range sr.Fetches {
+		if report.Error != "" {
+			return fmt.Errorf("%d of %d fetches failed, %s %s: %s",
+				sr.Failed, sr.Failed+sr.Succeeded, report.Source, report.Category, report.Error)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	RunKindScrape = "scrape"
+	RunKindPlan   = "plan"
+	RunKindSave   = "save"
+
+	RunStatusRunning = "running"
+	RunStatusSuccess = "success"
+	RunStatusPartial = "partial"
+	RunStatusFailed  = "failed"
+
+	// RunSourceUnknown counts stream entries too broken to tell their source
+	RunSourceUnknown = "unknown"
+
+	// runErrorSamples caps the errors kept per run and per breakdown row
+	runErrorSamples = 10
+)
+
+// RunCounts follows articles through a run. A scrape fetches articles from
+// upstream and parses them onto the stream, a save parses stream entries,
+// rejects the ones failing the quality rules, drops near duplicates and
+// inserts or updates the rest. Failed counts the fetches and articles that
+// were lost along the way
+type RunCounts struct {
+	Fetched      int `json:"fetched" bson:"fetched"`
+	Parsed       int `json:"parsed" bson:"parsed"`
+	Rejected     int `json:"rejected" bson:"rejected"`
+	Deduplicated int `json:"deduplicated" bson:"deduplicated"`
+	Inserted     int `json:"inserted" bson:"inserted"`
+	Updated      int `json:"updated" bson:"updated"`
+	Failed       int `json:"failed" bson:"failed"`
+	// Rejections counts the rejected articles by reason
+	Rejections map[string]int `json:"rejections,omitempty" bson:"rejections,omitempty"`
+}
+
+func (rc *RunCounts) Add(other RunCounts) {
+	rc.Fetched += other.Fetched
+	rc.Parsed += other.Parsed
+	rc.Deduplicated += other.Deduplicated
+	rc.Inserted += other.Inserted
+	rc.Updated += other.Updated
+	rc.Failed += other.Failed
+	for reason, count := range other.Rejections {
+		rc.Reject(reason, count)
+	}
+}
+
+// Reject counts articles turned away for the reason
+func (rc *RunCounts) Reject(reason string, count int) {
+	if rc.Rejections == nil {
+		rc.Rejections = make(map[string]int)
+	}
+	rc.Rejected += count
+	rc.Rejections[reason] += count
+}
+
+// RunBreakdown is the share of a run that came from one source and category
+type RunBreakdown struct {
+	Source    string `json:"source" bson:"source"`
+	Category  string `json:"category,omitempty" bson:"category,omitempty"`
+	RunCounts `bson:",inline"`
+	Errors    []string `json:"errors,omitempty" bson:"errors,omitempty"`
+}
+
+// IngestionRun records one scrape or save run, it is stored as running when
+// the run starts and replaced with the final counts when it ends
+type IngestionRun struct {
+	Id         primitive.ObjectID `json:"id" bson:"_id"`
+	Kind       string             `json:"kind" bson:"kind"`
+	Status     string             `json:"status" bson:"status"`
+	Host       string             `json:"host" bson:"host"`
+	StartedAt  time.Time          `json:"started_at" bson:"started_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	Duration   int64              `json:"duration_ms" bson:"duration_ms"`
+	Totals     RunCounts          `json:"totals" bson:"totals"`
+	Breakdown  []RunBreakdown     `json:"breakdown,omitempty" bson:"breakdown"`
+	Errors     []string           `json:"errors,omitempty" bson:"errors,omitempty"`
+	ExpiresAt  time.Time          `json:"-" bson:"expires_at"`
+}
+
+// Row returns the breakdown row of a source and category, adding it on first
+// use. The row is only valid until the next call adds another
+func (ir *IngestionRun) Row(source, category string) *RunBreakdown {
+	for i := range ir.Breakdown {
+		if ir.Breakdown[i].Source == source && ir.Breakdown[i].Category == category {
+			return &ir.Breakdown[i]
+		}
+	}
+
+	ir.Breakdown = append(ir.Breakdown, RunBreakdown{Source: source, Category: category})
+	return &ir.Breakdown[len(ir.Breakdown)-1]
+}
+
+// Sample keeps the error on the row and the run, up to a few of each
+func (ir *IngestionRun) Sample(row *RunBreakdown, err string) {
+	if row != nil && len(row.Errors) < runErrorSamples {
+		row.Errors = append(row.Errors, err)
+	}
+	if len(ir.Errors) < runErrorSamples {
+		ir.Errors = append(ir.Errors, err)
+	}
+}
+
+// Finish totals the breakdown and settles the status, err is what stopped
+// the run early if anything did
+func (ir *IngestionRun) Finish(err error) {
+	now := time.Now()
+	ir.FinishedAt = &now
+	ir.Duration = now.Sub(ir.StartedAt).Milliseconds()
+
+	ir.Totals = RunCounts{}
+	for _, row := range ir.Breakdown {
+		ir.Totals.Add(row.RunCounts)
+	}
+
+	switch {
+	case err != nil:
+		ir.Sample(nil, err.Error())
+		ir.Status = RunStatusFailed
+		if ir.Totals.Parsed > 0 {
+			ir.Status = RunStatusPartial
+		}
+	case ir.Totals.Failed > 0 && ir.Totals.Parsed == 0:
+		ir.Status = RunStatusFailed
+	case ir.Totals.Failed > 0:
+		ir.Status = RunStatusPartial
+	default:
+		ir.Status = RunStatusSuccess
+	}
+}
+
+// RunFilter narrows the run history, zero values match everything
+type RunFilter struct {
+	Kind   string
+	Status string
+	Source string
+	Since  time.Time
+	Limit  int64
+	Skip   int64
+}
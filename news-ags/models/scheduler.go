@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	JobOutcomeSuccess = "success"
+	JobOutcomeFailed  = "failed"
+	JobOutcomeRunning = "running"
+)
+
+type JobStatus struct {
+	Name      string    `json:"name"`
+	Schedule  string    `json:"schedule"`
+	Running   bool      `json:"running"`
+	LastRun   time.Time `json:"last_run"`
+	NextRun   time.Time `json:"next_run"`
+	Duration  int64     `json:"duration_ms"`
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+	RunBy     string    `json:"run_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+const (
+	SourceTypeNewsdata = "newsdata"
+	SourceTypeRSS      = "rss"
+	SourceTypeAtom     = "atom"
+	SourceTypeJSONFeed = "jsonfeed"
+)
+
+// SourceConfig registers a source. Weight ranks its articles from 1 to 1000,
+// the higher weight wins near duplicates and story headlines. Newsdata
+// articles carry their own rank mapped onto the same scale
+type SourceConfig struct {
+	Name       string            `json:"name" bson:"name" binding:"required"`
+	Type       string            `json:"type" bson:"type" binding:"required"`
+	URL        string            `json:"url" bson:"url"`
+	Enabled    bool              `json:"enabled" bson:"enabled"`
+	Weight     int               `json:"weight" bson:"weight"`
+	Categories []string          `json:"categories" bson:"categories"`
+	Country    []string          `json:"country" bson:"country"`
+	Params     map[string]string `json:"params" bson:"params"`
+	PageBudget int               `json:"page_budget" bson:"page_budget"`
+}
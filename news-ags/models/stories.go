@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Story groups the articles reporting on the same event
+type Story struct {
+	Id                primitive.ObjectID   `json:"story_id" bson:"_id"`
+	Headline          string               `json:"headline" bson:"headline"`
+	HeadlineArticleId primitive.ObjectID   `json:"headline_article_id" bson:"headline_article_id"`
+	HeadlineWeight    int                  `json:"-" bson:"headline_weight"`
+	ArticleIds        []primitive.ObjectID `json:"article_ids" bson:"article_ids"`
+	ArticleCount      int                  `json:"article_count" bson:"article_count"`
+	Sources           []string             `json:"sources" bson:"sources"`
+	SourcesCount      int                  `json:"sources_count" bson:"sources_count"`
+	Categories        []string             `json:"categories" bson:"categories"`
+	KeywordWeights    []KeywordWeight      `json:"keyword_weights" bson:"keyword_weights"`
+	SentimentSum      float64              `json:"-" bson:"sentiment_sum"`
+	Sentiment         float64              `json:"sentiment" bson:"sentiment"`
+	Tone              string               `json:"tone" bson:"tone"`
+	FirstPublishedAt  time.Time            `json:"first_published_at" bson:"first_published_at"`
+	LastPublishedAt   time.Time            `json:"last_published_at" bson:"last_published_at"`
+	CreatedAt         time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt         time.Time            `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+const (
+	TrendKeyword = "keyword"
+	TrendStory   = "story"
+	TrendEntity  = "entity"
+)
+
+// TrendKinds are the kinds of terms trending is computed for
+var TrendKinds = []string{TrendKeyword, TrendStory, TrendEntity}
+
+// TrendAll is the category every article is counted under
+const TrendAll = "all"
@@ -0,0 +1,306 @@
+// Package newsdatafake is a local stand-in for the newsdata.io news endpoint.
+// It serves recorded api responses so the scraper can be exercised without
+// spending credits, paginates them through nextPage like the real api and can
+// be told to answer with the errors newsdata returns.
+//
+// Fixtures are newsdata responses saved as json files in a directory, the
+// articles of every file are served in file name order.
+package newsdatafake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NewsPath is where the fake serves the news endpoint, as newsdata does
+const NewsPath = "/api/1/news"
+
+// creditsSpent stands for a key out of daily credits among the failures
+const creditsSpent = -1
+
+// languages and countries map the codes queries use to the names newsdata
+// puts on articles
+var (
+	languages = map[string]string{
+		"en": "english", "fr": "french", "es": "spanish", "pt": "portuguese", "de": "german",
+		"it": "italian", "nl": "dutch", "sw": "swahili", "ha": "hausa", "yo": "yoruba", "ar": "arabic",
+	}
+	countries = map[string]string{
+		"ng": "nigeria", "gh": "ghana", "sn": "senegal", "ke": "kenya", "za": "south africa",
+		"gb": "united kingdom", "us": "united states of america", "fr": "france", "ca": "canada",
+	}
+)
+
+type Options struct {
+	// Keys are the accepted api keys, any key is accepted when empty
+	Keys []string
+	// Credits is the number of requests a key may make before it is answered
+	// with ApiLimitExceeded, zero for no limit
+	Credits int
+	// PageSize is the number of articles per page, 10 like the free plan when zero
+	PageSize int
+}
+
+// Handler answers newsdata news requests from fixtures
+type Handler struct {
+	opts     Options
+	articles []json.RawMessage
+	filters  []articleFilter
+
+	mu       sync.Mutex
+	requests int
+	used     map[string]int
+	failures []int
+}
+
+// articleFilter holds the fields of a fixture article queries filter on
+type articleFilter struct {
+	Category []string `json:"category"`
+	Country  []string `json:"country"`
+	Language string   `json:"language"`
+	SourceId string   `json:"source_id"`
+}
+
+// NewHandler loads the fixtures in the directory
+func NewHandler(dir string, opts Options) (*Handler, error) {
+	if opts.PageSize <= 0 {
+		opts.PageSize = 10
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no newsdata fixtures in %s", dir)
+	}
+	sort.Strings(files)
+
+	h := &Handler{opts: opts, used: make(map[string]int)}
+	seen := make(map[string]bool)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Results []json.RawMessage `json:"results"`
+		}
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, fmt.Errorf("error parsing fixture %s: %w", file, err)
+		}
+
+		for _, raw := range response.Results {
+			var article struct {
+				articleFilter
+				ArticleId string `json:"article_id"`
+			}
+			if err := json.Unmarshal(raw, &article); err != nil {
+				return nil, fmt.Errorf("error parsing fixture %s: %w", file, err)
+			}
+			// the same article recorded twice is served once, like the api
+			if article.ArticleId != "" && seen[article.ArticleId] {
+				continue
+			}
+			seen[article.ArticleId] = true
+
+			h.articles = append(h.articles, raw)
+			h.filters = append(h.filters, article.articleFilter)
+		}
+	}
+
+	return h, nil
+}
+
+// Fail makes the next n requests answer with the status, 401, 429 and 500
+// carry the bodies newsdata sends. A 429 is the short term rate limit, a
+// key running out of credits is simulated through Options.Credits
+func (h *Handler) Fail(status, n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		h.failures = append(h.failures, status)
+	}
+}
+
+// Requests is the number of requests served, failed ones included
+func (h *Handler) Requests() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.requests
+}
+
+// Used is the number of credits the key spent
+func (h *Handler) Used(key string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.used[key]
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != NewsPath {
+		writeError(w, http.StatusNotFound, "NotFound", "endpoint not found")
+		return
+	}
+
+	query := r.URL.Query()
+	key := query.Get("apiKey")
+
+	h.mu.Lock()
+	h.requests++
+	status := 0
+	if len(h.failures) > 0 {
+		status, h.failures = h.failures[0], h.failures[1:]
+	}
+	switch {
+	case status != 0:
+	case key == "" || len(h.opts.Keys) > 0 && !slices.Contains(h.opts.Keys, key):
+		status = http.StatusUnauthorized
+	case h.opts.Credits > 0 && h.used[key] >= h.opts.Credits:
+		status = creditsSpent
+	default:
+		h.used[key]++
+	}
+	h.mu.Unlock()
+
+	switch status {
+	case 0:
+	case http.StatusUnauthorized:
+		writeError(w, status, "Unauthorized", "The provided API key is not valid.")
+		return
+	case creditsSpent:
+		// newsdata answers 429 for both the rate limit and spent credits
+		writeError(w, http.StatusTooManyRequests, "ApiLimitExceeded", "API daily credit limit exceeded.")
+		return
+	case http.StatusTooManyRequests:
+		w.Header().Set("Retry-After", "1")
+		writeError(w, status, "RateLimitExceeded", "Too many requests in a short period.")
+		return
+	case http.StatusInternalServerError:
+		writeError(w, status, "InternalServerError", "Something went wrong, please try again later.")
+		return
+	default:
+		writeError(w, status, "Error", http.StatusText(status))
+		return
+	}
+
+	matched := make([]json.RawMessage, 0)
+	for i, filter := range h.filters {
+		if filter.matches(query) {
+			matched = append(matched, h.articles[i])
+		}
+	}
+
+	offset := 0
+	if page := query.Get("page"); page != "" {
+		parsed, err := strconv.Atoi(strings.TrimPrefix(page, "fake"))
+		if err != nil || parsed < 0 || parsed > len(matched) {
+			writeError(w, http.StatusUnprocessableEntity, "UnsupportedParameter", "The page parameter is not valid.")
+			return
+		}
+		offset = parsed
+	}
+
+	end := min(offset+h.opts.PageSize, len(matched))
+	var nextPage *string
+	if end < len(matched) {
+		next := "fake" + strconv.Itoa(end)
+		nextPage = &next
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":       "success",
+		"totalResults": len(matched),
+		"results":      matched[offset:end],
+		"nextPage":     nextPage,
+	})
+}
+
+// matches applies the category, language, country and domain filters of a
+// query, each takes a comma separated list like the api
+func (f articleFilter) matches(query map[string][]string) bool {
+	get := func(key string) []string {
+		values := query[key]
+		if len(values) == 0 || values[0] == "" {
+			return nil
+		}
+		return strings.Split(strings.ToLower(values[0]), ",")
+	}
+
+	if categories := get("category"); categories != nil && !overlaps(categories, f.Category, nil) {
+		return false
+	}
+	if codes := get("language"); codes != nil && !overlaps(codes, []string{f.Language}, languages) {
+		return false
+	}
+	if codes := get("country"); codes != nil && !overlaps(codes, f.Country, countries) {
+		return false
+	}
+	if domains := get("domain"); domains != nil && !slices.Contains(domains, strings.ToLower(f.SourceId)) {
+		return false
+	}
+
+	return true
+}
+
+// overlaps tells whether any wanted value, or the name it stands for, is in have
+func overlaps(wanted, have []string, names map[string]string) bool {
+	for _, value := range wanted {
+		for _, h := range have {
+			h = strings.ToLower(h)
+			if h == value || names[value] == h {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"status":  "error",
+		"results": map[string]string{"message": message, "code": code},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// Server runs the handler on a local port
+type Server struct {
+	*httptest.Server
+	*Handler
+}
+
+// NewServer starts a fake newsdata server over the fixtures in the directory,
+// close it when done
+func NewServer(dir string, opts Options) (*Server, error) {
+	handler, err := NewHandler(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{Server: httptest.NewServer(handler), Handler: handler}, nil
+}
+
+// NewsURL is the base url the newsdata source should be pointed at
+func (s *Server) NewsURL() string {
+	return s.URL + NewsPath
+}
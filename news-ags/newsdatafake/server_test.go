@@ -0,0 +1,110 @@
+package newsdatafake
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+type response struct {
+	Status       string          `json:"status"`
+	TotalResults int             `json:"totalResults"`
+	Results      json.RawMessage `json:"results"`
+	NextPage     *string         `json:"nextPage"`
+}
+
+func get(t *testing.T, s *Server, query string) (int, response) {
+	t.Helper()
+
+	resp, err := http.Get(s.NewsURL() + "?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body response
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, body
+}
+
+func TestServerPaginates(t *testing.T) {
+	s, err := NewServer("../testdata/newsdata", Options{PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	query := "apiKey=key"
+	total := 0
+	for pages := 1; ; pages++ {
+		status, body := get(t, s, query)
+		if status != http.StatusOK {
+			t.Fatalf("page %d status = %d", pages, status)
+		}
+
+		var results []json.RawMessage
+		_ = json.Unmarshal(body.Results, &results)
+		total += len(results)
+
+		if body.NextPage == nil {
+			if pages != 3 || total != body.TotalResults {
+				t.Errorf("served %d articles over %d pages, want %d over 3", total, pages, body.TotalResults)
+			}
+			break
+		}
+		query = "apiKey=key&page=" + *body.NextPage
+	}
+}
+
+func TestServerFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		fail   int
+		key    string
+		status int
+		code   string
+	}{
+		{name: "unknown key", opts: Options{Keys: []string{"good"}}, key: "bad", status: 401, code: "Unauthorized"},
+		{name: "forced 401", fail: 401, key: "good", status: 401, code: "Unauthorized"},
+		{name: "rate limit", fail: 429, key: "good", status: 429, code: "RateLimitExceeded"},
+		{name: "server error", fail: 500, key: "good", status: 500, code: "InternalServerError"},
+		{name: "credits spent", opts: Options{Credits: 1}, key: "good", status: 429, code: "ApiLimitExceeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewServer("../testdata/newsdata", tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			if tt.opts.Credits > 0 {
+				get(t, s, "apiKey="+tt.key)
+			}
+			if tt.fail != 0 {
+				s.Fail(tt.fail, 1)
+			}
+
+			status, body := get(t, s, "apiKey="+tt.key)
+			var result struct {
+				Code string `json:"code"`
+			}
+			_ = json.Unmarshal(body.Results, &result)
+			if status != tt.status || body.Status != "error" || result.Code != tt.code {
+				t.Errorf("got %d %s %s, want %d error %s", status, body.Status, result.Code, tt.status, tt.code)
+			}
+
+			// forced failures only last for the requests they were set for
+			if tt.fail != 0 {
+				if status, _ := get(t, s, "apiKey="+tt.key); status != http.StatusOK {
+					t.Errorf("status after the failure = %d, want 200", status)
+				}
+			}
+		})
+	}
+}
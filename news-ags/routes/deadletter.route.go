@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/controllers"
+	"github.com/joey1123455/news-aggregator-service/news-ags/middleware"
+)
+
+type DeadLetterRouteController struct {
+	deadLetterController controllers.DeadLetterController
+}
+
+func NewDeadLetterRouteController(dc controllers.DeadLetterController) DeadLetterRouteController {
+	return DeadLetterRouteController{
+		deadLetterController: dc,
+	}
+}
+
+func (rc DeadLetterRouteController) DeadLetterRoute(rg *gin.RouterGroup, adminToken string) {
+	router := rg.Group("/admin/deadletters")
+	router.Use(middleware.RequireAdmin(adminToken))
+
+	router.GET("", rc.deadLetterController.DeadLetters)
+	router.GET("/:id", rc.deadLetterController.DeadLetter)
+	router.POST("/:id/retry", rc.deadLetterController.Retry)
+	router.DELETE("/:id", rc.deadLetterController.Discard)
+}
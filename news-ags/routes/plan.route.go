@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/controllers"
+	"github.com/joey1123455/news-aggregator-service/news-ags/middleware"
+	"github.com/joey1123455/news-aggregator-service/news-ags/services"
+)
+
+type ScrapePlanRouteController struct {
+	planController controllers.ScrapePlanController
+}
+
+func NewScrapePlanRouteController(pc controllers.ScrapePlanController) ScrapePlanRouteController {
+	return ScrapePlanRouteController{
+		planController: pc,
+	}
+}
+
+func (rc ScrapePlanRouteController) ScrapePlanRoute(rg *gin.RouterGroup, service services.ScrapePlanService, adminToken string) {
+	router := rg.Group("/admin/plan")
+	router.Use(middleware.RequireAdmin(adminToken))
+
+	router.GET("", rc.planController.GetPlan)
+	router.PUT("", rc.planController.ReplacePlan)
+	router.PUT("/:name", rc.planController.PutEntry)
+	router.DELETE("/:name", rc.planController.DeleteEntry)
+}
@@ -0,0 +1,135 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"github.com/joey1123455/news-aggregator-service/news-ags/controllers"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/services"
+)
+
+const testAdminToken = "admin-secret"
+
+func newPlanServer(t *testing.T, adminToken string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	planService := services.NewScrapePlanService(client, "../scrape-plan.json")
+	engine := gin.New()
+	NewScrapePlanRouteController(controllers.NewScrapePlanController(planService)).
+		ScrapePlanRoute(engine.Group("/api"), planService, adminToken)
+
+	return engine
+}
+
+type planResponse struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Plan    models.ScrapePlan `json:"plan"`
+}
+
+func planRequest(t *testing.T, engine *gin.Engine, method, path, body string) (int, planResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+
+	var resp planResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s answered %q: %v", method, path, recorder.Body.String(), err)
+	}
+
+	return recorder.Code, resp
+}
+
+func TestPlanRoutesRequireTheAdminToken(t *testing.T) {
+	engine := newPlanServer(t, testAdminToken)
+
+	for _, header := range []string{"", "Bearer wrong-token"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/plan", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q answered %d, want 401", header, recorder.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/plan", nil)
+	req.Header.Set("X-Admin-Token", testAdminToken)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("X-Admin-Token answered %d, want 200", recorder.Code)
+	}
+
+	disabled := newPlanServer(t, "")
+	recorder = httptest.NewRecorder()
+	disabled.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/admin/plan", nil))
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("disabled admin answered %d, want 403", recorder.Code)
+	}
+}
+
+func TestPlanRoutes(t *testing.T) {
+	engine := newPlanServer(t, testAdminToken)
+
+	code, resp := planRequest(t, engine, http.MethodGet, "/api/admin/plan", "")
+	if code != http.StatusOK || len(resp.Plan.Entries) != 2 {
+		t.Fatalf("GET answered %d with %+v, want the seeded plan", code, resp.Plan)
+	}
+
+	// the path names the entry, a name in the body is ignored
+	code, resp = planRequest(t, engine, http.MethodPut, "/api/admin/plan/east-africa", `{"name": "other", "enabled": true, "schedule": "0 */2 * * *", "countries": ["ke"]}`)
+	if code != http.StatusOK || len(resp.Plan.Entries) != 3 || resp.Plan.Entries[2].Name != "east-africa" || resp.Plan.Entries[2].Schedule != "0 */2 * * *" {
+		t.Errorf("PUT entry answered %d with %+v, want east-africa added", code, resp.Plan.Entries)
+	}
+
+	code, resp = planRequest(t, engine, http.MethodPut, "/api/admin/plan/east-africa", `{"schedule": "sometimes"}`)
+	if code != http.StatusBadRequest || resp.Status != "fail" {
+		t.Errorf("PUT with a bad schedule answered %d %+v, want 400", code, resp)
+	}
+	code, _ = planRequest(t, engine, http.MethodPut, "/api/admin/plan/east-africa", `{"enabled": `)
+	if code != http.StatusBadRequest {
+		t.Errorf("PUT with broken json answered %d, want 400", code)
+	}
+
+	code, resp = planRequest(t, engine, http.MethodDelete, "/api/admin/plan/global-en", "")
+	if code != http.StatusOK || len(resp.Plan.Entries) != 2 || resp.Plan.Entries[0].Name != "west-africa" {
+		t.Errorf("DELETE answered %d with %+v, want global-en removed", code, resp.Plan.Entries)
+	}
+	code, _ = planRequest(t, engine, http.MethodDelete, "/api/admin/plan/global-en", "")
+	if code != http.StatusNotFound {
+		t.Errorf("DELETE of a missing entry answered %d, want 404", code)
+	}
+
+	code, _ = planRequest(t, engine, http.MethodPut, "/api/admin/plan", `{"entries": [{"name": "a"}, {"name": "a"}]}`)
+	if code != http.StatusBadRequest {
+		t.Errorf("PUT of a plan with duplicate entries answered %d, want 400", code)
+	}
+	code, resp = planRequest(t, engine, http.MethodPut, "/api/admin/plan", `{"entries": [{"name": "only", "enabled": true}]}`)
+	if code != http.StatusOK || len(resp.Plan.Entries) != 1 {
+		t.Errorf("PUT plan answered %d with %+v, want the plan replaced", code, resp.Plan.Entries)
+	}
+
+	_, resp = planRequest(t, engine, http.MethodGet, "/api/admin/plan", "")
+	if len(resp.Plan.Entries) != 1 || resp.Plan.Entries[0].Name != "only" {
+		t.Errorf("GET after the edits returned %+v, want the replaced plan", resp.Plan.Entries)
+	}
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/controllers"
+	"github.com/joey1123455/news-aggregator-service/news-ags/middleware"
+)
+
+type QualityRouteController struct {
+	qualityController controllers.QualityController
+}
+
+func NewQualityRouteController(qc controllers.QualityController) QualityRouteController {
+	return QualityRouteController{
+		qualityController: qc,
+	}
+}
+
+func (rc QualityRouteController) QualityRoute(rg *gin.RouterGroup, adminToken string) {
+	router := rg.Group("/admin/quality")
+	router.Use(middleware.RequireAdmin(adminToken))
+
+	router.GET("", rc.qualityController.GetRules)
+	router.PUT("", rc.qualityController.ReplaceRules)
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/controllers"
+	"github.com/joey1123455/news-aggregator-service/news-ags/middleware"
+)
+
+type QuotaRouteController struct {
+	quotaController controllers.QuotaController
+}
+
+func NewQuotaRouteController(qc controllers.QuotaController) QuotaRouteController {
+	return QuotaRouteController{
+		quotaController: qc,
+	}
+}
+
+func (rc QuotaRouteController) QuotaRoute(rg *gin.RouterGroup, adminToken string) {
+	router := rg.Group("/admin/quota")
+	router.Use(middleware.RequireAdmin(adminToken))
+
+	router.GET("", rc.quotaController.Quota)
+}
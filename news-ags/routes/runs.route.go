@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/controllers"
+)
+
+type RunRouteController struct {
+	runController controllers.RunController
+}
+
+func NewRunRouteController(rc controllers.RunController) RunRouteController {
+	return RunRouteController{
+		runController: rc,
+	}
+}
+
+func (rc RunRouteController) RunRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/runs")
+
+	router.GET("", rc.runController.Runs)
+	router.GET("/:id", rc.runController.Run)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/joey1123455/news-aggregator-service/news-ags/controllers"
+	"github.com/joey1123455/news-aggregator-service/news-ags/services"
+)
+
+type SchedulerRouteController struct {
+	schedulerController controllers.SchedulerController
+}
+
+func NewSchedulerRouteController(sc controllers.SchedulerController) SchedulerRouteController {
+	return SchedulerRouteController{
+		schedulerController: sc,
+	}
+}
+
+func (rc SchedulerRouteController) SchedulerRoute(rg *gin.RouterGroup, service services.SchedulerService) {
+	router := rg.Group("/scheduler")
+
+	router.GET("/status", rc.schedulerController.Status)
+}
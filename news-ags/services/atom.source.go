@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+type AtomSource struct {
+	cfg         models.SourceConfig
+	upstream    *UpstreamClient
+	deadLetters DeadLetterService
+}
+
+func NewAtomSource(cfg models.SourceConfig, upstream *UpstreamClient, deadLetters DeadLetterService) Source {
+	return &AtomSource{
+		cfg:         cfg,
+		upstream:    upstream,
+		deadLetters: deadLetters,
+	}
+}
+
+func (as AtomSource) Name() string {
+	return as.cfg.Name
+}
+
+// Fetch reads an Atom 1.0 feed
+func (as AtomSource) Fetch(ctx context.Context, emit func([]models.Article) (int, error)) []models.FetchReport {
+	return fetchFeed(ctx, as.upstream, as.cfg, as.parse, as.deadLetters, emit)
+}
+
+// Reparse reads a feed document captured as a dead letter again
+func (as AtomSource) Reparse(letter models.DeadLetter) ([]models.Article, error) {
+	return as.parse([]byte(letter.Payload))
+}
+
+func (as AtomSource) parse(body []byte) ([]models.Article, error) {
+	var feed models.AtomFeed
+	if err := decodeFeed(body, &feed); err != nil {
+		return nil, err
+	}
+
+	articles := make([]models.Article, 0, len(feed.Entries))
+	for _, entry := range feed.Entries {
+		content := entry.Content.Body
+		if content == "" {
+			content = entry.Summary.Body
+		}
+
+		authors := make([]string, 0, len(entry.Authors))
+		for _, author := range entry.Authors {
+			authors = append(authors, author.Name)
+		}
+
+		keywords := make([]string, 0, len(entry.Categories))
+		for _, category := range entry.Categories {
+			keywords = append(keywords, category.Term)
+		}
+
+		date := entry.Published
+		if date == "" {
+			date = entry.Updated
+		}
+
+		article := models.Article{
+			Title:       utils.CleanText(entry.Title),
+			Description: utils.CleanText(entry.Summary.Body),
+			URL:         atomLink(entry.Links),
+			Author:      authors,
+			Image:       atomImage(entry.Links, content),
+			Content:     utils.CleanText(content),
+			Keywords:    keywords,
+			Date:        date,
+		}
+		applySourceDefaults(&article, as.cfg)
+		articles = append(articles, article)
+	}
+
+	return articles, nil
+}
+
+// atomLink prefers the alternate link as described by RFC 4287
+func atomLink(links []models.AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+
+	return ""
+}
+
+func atomImage(links []models.AtomLink, content string) string {
+	for _, link := range links {
+		if link.Rel == "enclosure" && strings.HasPrefix(link.Type, "image/") {
+			return link.Href
+		}
+	}
+
+	return utils.FirstImage(content)
+}
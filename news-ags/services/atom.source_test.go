@@ -0,0 +1,58 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+)
+
+func TestAtomParse(t *testing.T) {
+	source := AtomSource{cfg: models.SourceConfig{Name: "abuja-wire", Weight: 200}}
+
+	articles, err := source.parse(readFeedFixture(t, "atom.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(articles) != 2 {
+		t.Fatalf("parsed %d articles, want 2", len(articles))
+	}
+
+	wage := articles[0]
+	if wage.Title != "Senate passes “minimum wage” bill" {
+		t.Errorf("title = %q, want the windows-1252 quotes as UTF-8", wage.Title)
+	}
+	if wage.URL != "https://abujawire.example.com/politics/wage" {
+		t.Errorf("url = %q, want the alternate link", wage.URL)
+	}
+	if wage.Image != "https://abujawire.example.com/img/wage.png" {
+		t.Errorf("image = %q, want the enclosure link", wage.Image)
+	}
+	if wage.Content != "The bill now goes to the president." || wage.Description != wage.Content {
+		t.Errorf("content = %q, description = %q, want the summary", wage.Content, wage.Description)
+	}
+	if !slices.Equal(wage.Author, []string{"Musa Ibrahim", "Ngozi Eze"}) || !slices.Equal(wage.Keywords, []string{"politics"}) {
+		t.Errorf("authors = %q, keywords = %q", wage.Author, wage.Keywords)
+	}
+	if wage.Date != "2024-09-03T08:00:00+01:00" {
+		t.Errorf("date = %q, want published", wage.Date)
+	}
+	if wage.Source != "abuja-wire" || wage.Weight != 200 {
+		t.Errorf("source defaults not applied: %+v", wage)
+	}
+
+	rain := articles[1]
+	if rain.URL != "https://abujawire.example.com/weather/rain" {
+		t.Errorf("url = %q, want the link without rel", rain.URL)
+	}
+	if !strings.Contains(rain.Content, "Heavy rain is expected.") {
+		t.Errorf("content = %q, want the xhtml body", rain.Content)
+	}
+	if rain.Image != "https://abujawire.example.com/img/rain.jpg" {
+		t.Errorf("image = %q, want the embedded image", rain.Image)
+	}
+	if rain.Date != "2024-09-03T09:30:00+01:00" {
+		t.Errorf("date = %q, want updated", rain.Date)
+	}
+}
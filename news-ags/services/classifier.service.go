@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// classifierTokenLimit caps the body tokens a document contributes, the lead
+// of an article says most about its topic
+const classifierTokenLimit = 1000
+
+// nonTopicCategories are upstream labels that say nothing about the subject
+var nonTopicCategories = map[string]bool{"top": true, "other": true}
+
+// ClassifierService assigns a category to uncategorized articles and corrects
+// categories the model is confident are wrong
+type ClassifierService interface {
+	Classify(articles []models.Article)
+}
+
+type ClassifierServiceImp struct {
+	path    string
+	assign  float64
+	correct float64
+	mu      sync.Mutex
+	model   *utils.NaiveBayes
+	loaded  time.Time
+}
+
+// NewClassifier reads the model from path, a model written later by the
+// training cli is picked up without a restart
+func NewClassifier(path string, assign, correct float64) ClassifierService {
+	return &ClassifierServiceImp{
+		path:    path,
+		assign:  assign,
+		correct: correct,
+	}
+}
+
+// Classify predicts the category of every article. Articles without a topic
+// category get the prediction when it reaches the assign threshold, labelled
+// articles are only relabelled past the correct threshold. The upstream
+// categories are kept on the article when they are replaced
+func (cs *ClassifierServiceImp) Classify(articles []models.Article) {
+	model := cs.current()
+
+	for i := range articles {
+		article := &articles[i]
+		if article.CategorySource == "" {
+			article.CategorySource = models.CategorySourceUpstream
+		}
+		if model == nil {
+			continue
+		}
+
+		prediction := model.Predict(classifierTokens(*article))
+		predicted, confidence := prediction.Best()
+		if predicted == "" {
+			continue
+		}
+		article.PredictedCategory = predicted
+		article.CategoryConfidence = confidence
+
+		topics := make([]string, 0, len(article.Category))
+		kept := make([]string, 0, len(article.Category))
+		for _, category := range article.Category {
+			if _, ok := model.Classes[category]; ok {
+				topics = append(topics, category)
+			} else {
+				kept = append(kept, category)
+			}
+		}
+
+		switch {
+		case len(topics) == 0 && confidence >= cs.assign:
+		case len(topics) > 0 && confidence >= cs.correct && !contains(topics, predicted):
+		default:
+			continue
+		}
+
+		article.UpstreamCategory = article.Category
+		article.Category = append(kept, predicted)
+		article.CategorySource = models.CategorySourceClassifier
+	}
+}
+
+// current returns the loaded model, reloading it when the file changed
+func (cs *ClassifierServiceImp) current() *utils.NaiveBayes {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	info, err := os.Stat(cs.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			utils.LogErrorToFile("stat classifier model", err.Error())
+		}
+		return cs.model
+	}
+
+	if cs.model == nil || info.ModTime().After(cs.loaded) {
+		model, err := utils.LoadNaiveBayes(cs.path)
+		if err != nil {
+			utils.LogErrorToFile("load classifier model", err.Error())
+			return cs.model
+		}
+		cs.model = model
+		cs.loaded = info.ModTime()
+	}
+
+	return cs.model
+}
+
+func classifierTokens(article models.Article) []string {
+	tokens := utils.Tokenize(article.Title + " " + article.Title + " " + article.Description)
+
+	body := utils.Tokenize(article.Content)
+	if len(body) > classifierTokenLimit {
+		body = body[:classifierTokenLimit]
+	}
+
+	return append(tokens, body...)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+type TrainOptions struct {
+	MinDocuments  int
+	MaxDocuments  int
+	MinTokenCount int
+	Holdout       int
+}
+
+// TrainReport summarises a training run, accuracy is measured on the held
+// out articles and counts a prediction matching any of their categories
+type TrainReport struct {
+	Documents map[string]int `json:"documents"`
+	Dropped   []string       `json:"dropped"`
+	Tested    int            `json:"tested"`
+	Accuracy  float64        `json:"accuracy"`
+}
+
+// TrainClassifier learns a model from the articles whose categories came from
+// upstream, newest first, every Holdout-th article is kept aside for testing
+func TrainClassifier(ctx context.Context, collection *mongo.Collection, opts TrainOptions) (*utils.NaiveBayes, *TrainReport, error) {
+	filter := bson.M{
+		"category.0":      bson.M{"$exists": true},
+		"category_source": bson.M{"$ne": models.CategorySourceClassifier},
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetProjection(bson.M{"title": 1, "description": 1, "content": 1, "category": 1})
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	trainer := newClassifierTrainer(opts)
+	for cursor.Next(ctx) {
+		var article models.Article
+		if err := cursor.Decode(&article); err != nil {
+			return nil, nil, err
+		}
+		trainer.add(article)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return trainer.finish()
+}
+
+// classifierTrainer learns from articles in the order they are added
+type classifierTrainer struct {
+	opts   TrainOptions
+	model  *utils.NaiveBayes
+	report *TrainReport
+	held   []models.Article
+	seen   int
+}
+
+func newClassifierTrainer(opts TrainOptions) *classifierTrainer {
+	return &classifierTrainer{
+		opts:   opts,
+		model:  utils.NewNaiveBayes(),
+		report: &TrainReport{Documents: make(map[string]int)},
+		held:   make([]models.Article, 0),
+	}
+}
+
+func (ct *classifierTrainer) add(article models.Article) {
+	ct.seen++
+	if ct.opts.Holdout > 0 && ct.seen%ct.opts.Holdout == 0 {
+		ct.held = append(ct.held, article)
+		return
+	}
+
+	tokens := classifierTokens(article)
+	for _, category := range article.Category {
+		if nonTopicCategories[category] || category == "" {
+			continue
+		}
+		if ct.opts.MaxDocuments > 0 && ct.report.Documents[category] >= ct.opts.MaxDocuments {
+			continue
+		}
+
+		ct.model.Train(category, tokens)
+		ct.report.Documents[category]++
+	}
+}
+
+// finish drops the categories with too few articles, finalizes the model and
+// measures it on the held out articles
+func (ct *classifierTrainer) finish() (*utils.NaiveBayes, *TrainReport, error) {
+	model, report, opts := ct.model, ct.report, ct.opts
+
+	for category, count := range report.Documents {
+		if count < opts.MinDocuments {
+			model.Remove(category)
+			report.Dropped = append(report.Dropped, category)
+		}
+	}
+	sort.Strings(report.Dropped)
+	for _, category := range report.Dropped {
+		delete(report.Documents, category)
+	}
+
+	if len(model.Classes) < 2 {
+		return nil, report, fmt.Errorf("need at least two categories with %d articles to train, got %d", opts.MinDocuments, len(model.Classes))
+	}
+	model.Finalize(opts.MinTokenCount)
+
+	correct := 0
+	for _, article := range ct.held {
+		labelled := false
+		for _, category := range article.Category {
+			if _, ok := model.Classes[category]; ok {
+				labelled = true
+			}
+		}
+		if !labelled {
+			continue
+		}
+
+		report.Tested++
+		predicted, _ := model.Predict(classifierTokens(article)).Best()
+		if contains(article.Category, predicted) {
+			correct++
+		}
+	}
+	if report.Tested > 0 {
+		report.Accuracy = float64(correct) / float64(report.Tested)
+	}
+
+	return model, report, nil
+}
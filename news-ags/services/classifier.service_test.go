@@ -0,0 +1,175 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+)
+
+var classifierTopics = map[string][]string{
+	"sports":   {"Striker scores late goal in derby match", "Coach names squad for league fixture", "Midfielder signs with champions after transfer"},
+	"politics": {"Senate passes budget bill after vote", "Minister resigns over election dispute", "Governor signs law ahead of parliament recess"},
+	"business": {"Central bank holds interest rates"},
+}
+
+// classifierArticles returns n labelled articles per topic, in a fixed order
+func classifierArticles(n int) []models.Article {
+	articles := make([]models.Article, 0)
+	for i := 0; i < n; i++ {
+		for _, topic := range []string{"sports", "politics", "business"} {
+			titles := classifierTopics[topic]
+			if topic == "business" && i > 0 {
+				continue
+			}
+			articles = append(articles, models.Article{
+				Title:    titles[i%len(titles)],
+				Content:  fmt.Sprintf("%s report number %d", titles[(i+1)%len(titles)], i),
+				Category: []string{topic, "top"},
+			})
+		}
+	}
+
+	return articles
+}
+
+func TestClassifierTrainer(t *testing.T) {
+	trainer := newClassifierTrainer(TrainOptions{MinDocuments: 3, MaxDocuments: 8, MinTokenCount: 1, Holdout: 5})
+	for _, article := range classifierArticles(12) {
+		trainer.add(article)
+	}
+
+	model, report, err := trainer.finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(report.Dropped, []string{"business"}) {
+		t.Errorf("dropped = %q, want business with a single article", report.Dropped)
+	}
+	if report.Documents["sports"] != 8 || report.Documents["politics"] != 8 || len(report.Documents) != 2 {
+		t.Errorf("documents = %v, want 8 per topic once capped", report.Documents)
+	}
+	if _, ok := model.Classes["top"]; ok {
+		t.Error("trained the non-topic category top")
+	}
+	// every fifth of the 25 articles is held out, none of them business
+	if report.Tested != 5 || report.Accuracy != 1 {
+		t.Errorf("tested %d at %.2f accuracy, want 5 at 1", report.Tested, report.Accuracy)
+	}
+}
+
+func TestClassifierTrainerNeedsTwoCategories(t *testing.T) {
+	trainer := newClassifierTrainer(TrainOptions{MinDocuments: 5})
+	for _, article := range classifierArticles(3) {
+		trainer.add(article)
+	}
+
+	if model, report, err := trainer.finish(); err == nil || model != nil || len(report.Dropped) != 3 {
+		t.Errorf("finish() = %v, %+v, %v, want every category dropped and an error", model, report, err)
+	}
+}
+
+func trainedClassifier(t *testing.T, assign, correct float64) ClassifierService {
+	t.Helper()
+
+	trainer := newClassifierTrainer(TrainOptions{MinDocuments: 3, MinTokenCount: 1})
+	for _, article := range classifierArticles(12) {
+		trainer.add(article)
+	}
+	model, _, err := trainer.finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "classifier.json")
+	if err := model.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewClassifier(path, assign, correct)
+}
+
+func TestClassifyThresholds(t *testing.T) {
+	sporty := "Striker scores late goal as coach names squad"
+
+	tests := []struct {
+		name     string
+		assign   float64
+		correct  float64
+		article  models.Article
+		category []string
+		upstream []string
+		source   string
+	}{
+		{
+			name:     "assigns to an article without a topic",
+			assign:   0.6,
+			correct:  0.9,
+			article:  models.Article{Title: sporty, Category: []string{"top"}},
+			category: []string{"top", "sports"},
+			upstream: []string{"top"},
+			source:   models.CategorySourceClassifier,
+		},
+		{
+			name:     "leaves an article without a topic below the assign threshold",
+			assign:   0.6,
+			correct:  0.9,
+			article:  models.Article{Title: "Weather stays mild"},
+			category: nil,
+			source:   models.CategorySourceUpstream,
+		},
+		{
+			name:     "corrects a confident mislabel",
+			assign:   0.6,
+			correct:  0.9,
+			article:  models.Article{Title: sporty, Category: []string{"politics", "world"}},
+			category: []string{"world", "sports"},
+			upstream: []string{"politics", "world"},
+			source:   models.CategorySourceClassifier,
+		},
+		{
+			name:     "keeps a label below the correct threshold",
+			assign:   0.6,
+			correct:  1.1,
+			article:  models.Article{Title: sporty, Category: []string{"politics"}},
+			category: []string{"politics"},
+			source:   models.CategorySourceUpstream,
+		},
+		{
+			name:     "keeps a label the model agrees with",
+			assign:   0.6,
+			correct:  0.5,
+			article:  models.Article{Title: sporty, Category: []string{"sports", "politics"}},
+			category: []string{"sports", "politics"},
+			source:   models.CategorySourceUpstream,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			articles := []models.Article{tt.article}
+			trainedClassifier(t, tt.assign, tt.correct).Classify(articles)
+
+			got := articles[0]
+			if !slices.Equal(got.Category, tt.category) || !slices.Equal(got.UpstreamCategory, tt.upstream) || got.CategorySource != tt.source {
+				t.Errorf("category = %q, upstream = %q, source = %q, want %q, %q, %q",
+					got.Category, got.UpstreamCategory, got.CategorySource, tt.category, tt.upstream, tt.source)
+			}
+			if got.PredictedCategory == "" || got.CategoryConfidence == 0 {
+				t.Errorf("prediction not recorded: %q at %f", got.PredictedCategory, got.CategoryConfidence)
+			}
+		})
+	}
+}
+
+func TestClassifyWithoutModel(t *testing.T) {
+	articles := []models.Article{{Title: "Striker scores", Category: []string{"top"}}}
+	NewClassifier(filepath.Join(t.TempDir(), "missing.json"), 0.6, 0.9).Classify(articles)
+
+	if articles[0].CategorySource != models.CategorySourceUpstream || articles[0].PredictedCategory != "" {
+		t.Errorf("classified %+v without a model, want only the source set", articles[0])
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// deadLetterMaxPayload keeps letters well under the mongo document limit, a
+// truncated payload can be inspected but not retried
+const deadLetterMaxPayload = 1 << 20
+
+var (
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	ErrRetryFailed        = errors.New("dead letter retry failed")
+)
+
+// DeadLetterService keeps what failed ingestion instead of dropping it.
+// Capturing is best effort, a letter that can not be stored is only logged
+type DeadLetterService interface {
+	Capture(stage, source, category string, payload []byte, reason error)
+	DeadLetters(filter models.DeadLetterFilter) ([]models.DeadLetter, int64, error)
+	DeadLetter(id string) (*models.DeadLetter, error)
+	Retry(id string, requeue func(models.DeadLetter) error) (*models.DeadLetter, error)
+	Discard(id string) error
+}
+
+type DeadLetterServiceImp struct {
+	ctx                  context.Context
+	deadLetterCollection *mongo.Collection
+	retention            time.Duration
+	indexes              *sync.Once
+}
+
+// NewDeadLetterService builds the dead letter store, letters not seen again
+// within the retention are dropped
+func NewDeadLetterService(ctx context.Context, deadLetters *mongo.Collection, retention time.Duration) DeadLetterService {
+	return &DeadLetterServiceImp{
+		ctx:                  ctx,
+		deadLetterCollection: deadLetters,
+		retention:            retention,
+		indexes:              &sync.Once{},
+	}
+}
+
+// Capture stores the payload with the stage that failed and why
+func (ds DeadLetterServiceImp) Capture(stage, source, category string, payload []byte, reason error) {
+	ds.indexes.Do(func() {
+		if err := ds.createIndexes(); err != nil {
+			utils.LogErrorToFile("create dead letter indexes", err.Error())
+		}
+	})
+
+	sum := sha256.Sum256(append([]byte(stage+"\x00"+source+"\x00"), payload...))
+	truncated := len(payload) > deadLetterMaxPayload
+	if truncated {
+		payload = payload[:deadLetterMaxPayload]
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"_id":           primitive.NewObjectID(),
+			"stage":         stage,
+			"source":        source,
+			"category":      category,
+			"payload":       string(payload),
+			"truncated":     truncated,
+			"attempts":      0,
+			"first_seen_at": now,
+		},
+		"$set": bson.M{"reason": reason.Error(), "last_seen_at": now, "expires_at": now.Add(ds.retention)},
+		"$inc": bson.M{"occurrences": 1},
+	}
+	_, err := ds.deadLetterCollection.UpdateOne(ds.ctx, bson.M{"fingerprint": hex.EncodeToString(sum[:])}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		utils.LogErrorToFile("capture dead letter "+stage+" "+source, err.Error())
+	}
+}
+
+// DeadLetters lists letters most recently seen first without their payload,
+// along with the total number of letters the filter matches
+func (ds DeadLetterServiceImp) DeadLetters(filter models.DeadLetterFilter) ([]models.DeadLetter, int64, error) {
+	query := bson.M{}
+	if filter.Stage != "" {
+		query["stage"] = filter.Stage
+	}
+	if filter.Source != "" {
+		query["source"] = filter.Source
+	}
+
+	total, err := ds.deadLetterCollection.CountDocuments(ds.ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "last_seen_at", Value: -1}}).
+		SetProjection(bson.M{"payload": 0}).
+		SetSkip(filter.Skip).
+		SetLimit(filter.Limit)
+	cursor, err := ds.deadLetterCollection.Find(ds.ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ds.ctx)
+
+	letters := make([]models.DeadLetter, 0)
+	if err := cursor.All(ds.ctx, &letters); err != nil {
+		return nil, 0, err
+	}
+
+	return letters, total, nil
+}
+
+// DeadLetter returns a single letter with its raw payload
+func (ds DeadLetterServiceImp) DeadLetter(id string) (*models.DeadLetter, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	var letter models.DeadLetter
+	err = ds.deadLetterCollection.FindOne(ds.ctx, bson.M{"_id": objectId}).Decode(&letter)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &letter, nil
+}
+
+// Retry hands the letter to requeue and drops it once its articles are back
+// on the stream. A letter that fails again keeps the new reason and is
+// returned along with ErrRetryFailed
+func (ds DeadLetterServiceImp) Retry(id string, requeue func(models.DeadLetter) error) (*models.DeadLetter, error) {
+	letter, err := ds.DeadLetter(id)
+	if err != nil {
+		return nil, err
+	}
+
+	retryErr := errors.New("payload was truncated when it was captured")
+	if !letter.Truncated {
+		retryErr = requeue(*letter)
+	}
+	if retryErr == nil {
+		_, err := ds.deadLetterCollection.DeleteOne(ds.ctx, bson.M{"_id": letter.Id})
+		return letter, err
+	}
+
+	now := time.Now()
+	letter.Attempts++
+	letter.Reason = retryErr.Error()
+	letter.RetriedAt = &now
+	_, err = ds.deadLetterCollection.UpdateOne(ds.ctx, bson.M{"_id": letter.Id}, bson.M{
+		"$set": bson.M{"reason": letter.Reason, "retried_at": now},
+		"$inc": bson.M{"attempts": 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return letter, fmt.Errorf("%w: %v", ErrRetryFailed, retryErr)
+}
+
+// Discard drops a letter for good
+func (ds DeadLetterServiceImp) Discard(id string) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrDeadLetterNotFound
+	}
+
+	result, err := ds.deadLetterCollection.DeleteOne(ds.ctx, bson.M{"_id": objectId})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrDeadLetterNotFound
+	}
+
+	return nil
+}
+
+func (ds DeadLetterServiceImp) createIndexes() error {
+	// Index model for folding repeated failures into one letter
+	fingerprintIndex := mongo.IndexModel{
+		Keys:    bson.M{"fingerprint": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	// Index model for the listing
+	recentIndex := mongo.IndexModel{Keys: bson.D{{Key: "last_seen_at", Value: -1}}}
+	stageIndex := mongo.IndexModel{Keys: bson.D{{Key: "stage", Value: 1}, {Key: "last_seen_at", Value: -1}}}
+	sourceIndex := mongo.IndexModel{Keys: bson.D{{Key: "source", Value: 1}, {Key: "last_seen_at", Value: -1}}}
+
+	// Index model expiring letters past the retention
+	expiryIndex := mongo.IndexModel{
+		Keys:    bson.M{"expires_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err := ds.deadLetterCollection.Indexes().CreateMany(ds.ctx, []mongo.IndexModel{fingerprintIndex, recentIndex, stageIndex, sourceIndex, expiryIndex})
+	return err
+}
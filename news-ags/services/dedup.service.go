@@ -0,0 +1,159 @@
+package services
+
+import (
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+// fingerprintArticle stores the simhash of the title and body on the article.
+// A text without tokens gets no fingerprint, all such texts would hash to
+// zero and be taken for copies of each other
+func fingerprintArticle(article *models.Article) {
+	body := article.Content
+	if body == "" {
+		body = article.Description
+	}
+
+	text := article.Title + " " + body
+	if len(utils.Tokenize(text)) == 0 {
+		article.Fingerprint = ""
+		article.FingerprintBands = nil
+		return
+	}
+
+	fingerprint := utils.SimHash(text)
+	article.Fingerprint = utils.FormatFingerprint(fingerprint)
+	article.FingerprintBands = utils.FingerprintBands(fingerprint)
+}
+
+// canonicalizeArticle stores the key upserts are matched on
+func canonicalizeArticle(article *models.Article) {
+	if article.CanonicalKey == "" {
+		article.CanonicalKey = utils.CanonicalKey(article.URL, article.Source, article.Title)
+	}
+}
+
+// stampArticle records when the article was ingested and how long its content
+// is, and normalizes its publish date and title, articles with an unreadable date are treated as
+// published on ingest
+func stampArticle(article *models.Article, now time.Time) {
+	article.TitleKey = utils.TitleKey(article.Title)
+
+	article.ContentLength = len(article.Content)
+
+	if article.IngestedAt.IsZero() {
+		article.IngestedAt = now.UTC()
+	}
+
+	if article.PublishedAt.IsZero() {
+		published, err := utils.ParsePublishDate(article.Date)
+		if err != nil {
+			published = article.IngestedAt
+		}
+		article.PublishedAt = published
+	}
+}
+
+// articleFingerprint returns the parsed fingerprint, computing it when the
+// article was cached before fingerprints existed or was given the zero
+// fingerprint of an empty text. It reports false for an article without
+// text, which is never a near duplicate
+func articleFingerprint(article *models.Article) (uint64, bool) {
+	fingerprint, err := utils.ParseFingerprint(article.Fingerprint)
+	if err != nil || fingerprint == 0 {
+		fingerprintArticle(article)
+		fingerprint, _ = utils.ParseFingerprint(article.Fingerprint)
+	}
+
+	return fingerprint, fingerprint != 0
+}
+
+// preferArticle reports whether a should be kept over its near duplicate b,
+// the higher source weight wins and the longer body breaks ties
+func preferArticle(a, b models.Article) bool {
+	if a.Weight != b.Weight {
+		return a.Weight > b.Weight
+	}
+
+	return articleLength(a) > articleLength(b)
+}
+
+// articleLength is the length of the body, stored articles are loaded
+// without it and only carry the length recorded on save
+func articleLength(article models.Article) int {
+	if article.Content != "" {
+		return len(article.Content)
+	}
+
+	return article.ContentLength
+}
+
+// clusterDuplicates groups near duplicate articles of a batch using the LSH
+// bands, together with copies sharing a canonical key, and returns the best
+// copy of every cluster
+func clusterDuplicates(articles []models.Article) []models.Article {
+	parent := make([]int, len(articles))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	fingerprints := make([]uint64, len(articles))
+	buckets := make(map[string][]int)
+	keys := make(map[string]int)
+	for i := range articles {
+		fingerprints[i], _ = articleFingerprint(&articles[i])
+		for _, band := range articles[i].FingerprintBands {
+			buckets[band] = append(buckets[band], i)
+		}
+
+		canonicalizeArticle(&articles[i])
+		if j, ok := keys[articles[i].CanonicalKey]; ok {
+			parent[find(i)] = find(j)
+		} else {
+			keys[articles[i].CanonicalKey] = i
+		}
+	}
+
+	for _, members := range buckets {
+		for x := 0; x < len(members); x++ {
+			for y := x + 1; y < len(members); y++ {
+				i, j := members[x], members[y]
+				if utils.HammingDistance(fingerprints[i], fingerprints[j]) <= utils.NearDuplicateDistance {
+					parent[find(i)] = find(j)
+				}
+			}
+		}
+	}
+
+	best := make(map[int]int)
+	order := make([]int, 0)
+	for i := range articles {
+		root := find(i)
+		current, ok := best[root]
+		if !ok {
+			best[root] = i
+			order = append(order, root)
+			continue
+		}
+		if preferArticle(articles[i], articles[current]) {
+			best[root] = i
+		}
+	}
+
+	kept := make([]models.Article, 0, len(order))
+	for _, root := range order {
+		kept = append(kept, articles[best[root]])
+	}
+
+	return kept
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const dedupBody = "Lagos state government announced a new bus rapid transit corridor linking Ikorodu to the island, with construction expected to begin next month and finish within two years."
+
+func TestFingerprintArticleSkipsTextWithoutTokens(t *testing.T) {
+	article := models.Article{Title: "—", Content: "!! ?"}
+	fingerprintArticle(&article)
+	if article.Fingerprint != "" || article.FingerprintBands != nil {
+		t.Errorf("fingerprint = %q, bands = %q, want none for a text without tokens", article.Fingerprint, article.FingerprintBands)
+	}
+	if _, ok := articleFingerprint(&article); ok {
+		t.Error("articleFingerprint reported a fingerprint for a text without tokens")
+	}
+
+	// articles stored before empty texts were skipped carry the zero fingerprint
+	legacy := models.Article{Fingerprint: "0000000000000000", FingerprintBands: []string{"0:0000", "1:0000", "2:0000", "3:0000"}}
+	if _, ok := articleFingerprint(&legacy); ok || legacy.FingerprintBands != nil {
+		t.Errorf("zero fingerprint kept its bands %q, want it dropped", legacy.FingerprintBands)
+	}
+
+	article = models.Article{Title: "Transit corridor", Content: dedupBody}
+	if fingerprint, ok := articleFingerprint(&article); !ok || fingerprint == 0 || len(article.FingerprintBands) != 4 {
+		t.Errorf("fingerprint = %x, bands = %q, want a fingerprint", fingerprint, article.FingerprintBands)
+	}
+}
+
+func TestClusterDuplicates(t *testing.T) {
+	articles := []models.Article{
+		{URL: "https://a.example.com/1", Title: "", Content: ""},
+		{URL: "https://b.example.com/2", Title: "", Content: "..."},
+		{URL: "https://c.example.com/3", Title: "Transit corridor", Content: dedupBody, Weight: 100},
+		{URL: "https://d.example.com/4", Title: "Transit corridor", Content: dedupBody, Weight: 500},
+		{URL: "https://a.example.com/1", Title: "", Content: ""},
+	}
+
+	kept := clusterDuplicates(articles)
+	urls := make([]string, 0, len(kept))
+	for _, article := range kept {
+		urls = append(urls, article.URL)
+	}
+
+	// texts without tokens are only merged on their url
+	want := []string{"https://a.example.com/1", "https://b.example.com/2", "https://d.example.com/4"}
+	if len(urls) != len(want) {
+		t.Fatalf("kept %q, want %q", urls, want)
+	}
+	for i := range want {
+		if urls[i] != want[i] {
+			t.Errorf("kept %q, want %q", urls, want)
+			break
+		}
+	}
+}
+
+func TestPreferArticleUsesStoredLength(t *testing.T) {
+	incoming := models.Article{Weight: 100, Content: "short body"}
+	stored := models.Article{Weight: 100, ContentLength: 500}
+	if preferArticle(incoming, stored) {
+		t.Error("a shorter body beat the stored length")
+	}
+	if !preferArticle(models.Article{Weight: 200}, stored) {
+		t.Error("a heavier source lost to a longer body")
+	}
+
+	article := models.Article{Content: "four"}
+	stampArticle(&article, time.Now())
+	if article.ContentLength != 4 {
+		t.Errorf("content length = %d, want 4", article.ContentLength)
+	}
+}
+
+func TestDuplicateFilter(t *testing.T) {
+	monday := time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC)
+	batch := []models.Article{
+		{FingerprintBands: []string{"0:aaaa", "1:bbbb"}, PublishedAt: monday.Add(6 * time.Hour)},
+		{},
+		{FingerprintBands: []string{"0:cccc"}, PublishedAt: monday},
+	}
+
+	filter, ok := duplicateFilter(batch, 24*time.Hour)
+	if !ok {
+		t.Fatal("duplicateFilter found no bands")
+	}
+	bands := filter["fingerprint_bands"].(bson.M)["$in"].([]string)
+	if len(bands) != 3 {
+		t.Errorf("bands = %q, want all three", bands)
+	}
+	window := filter["published_at"].(bson.M)
+	if window["$gte"] != monday.Add(-24*time.Hour) || window["$lte"] != monday.Add(30*time.Hour) {
+		t.Errorf("published_at = %v, want a day either side of the batch", window)
+	}
+
+	if filter, _ := duplicateFilter(batch, 0); filter["published_at"] != nil {
+		t.Errorf("filter = %v, want no window when it is unset", filter)
+	}
+	if _, ok := duplicateFilter([]models.Article{{}, {}}, time.Hour); ok {
+		t.Error("duplicateFilter built a lookup for a batch without fingerprints")
+	}
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+const (
+	robotsCacheTTL = 6 * time.Hour
+	enrichWorkers  = 4
+	disallowAll    = "User-agent: *\nDisallow: /"
+)
+
+// truncatedPattern matches the markers upstream apis leave on cut off bodies
+var truncatedPattern = regexp.MustCompile(`(?i)(\.\.\.|…|\[\+\d+ chars\]|only available in paid plans)\s*$`)
+
+type robotsEntry struct {
+	rules   *utils.RobotsRules
+	fetched time.Time
+}
+
+// ArticleEnricher fetches article pages to fill in what the feed left out
+type ArticleEnricher struct {
+	upstream   *UpstreamClient
+	userAgent  string
+	minContent int
+	mu         sync.Mutex
+	robots     map[string]robotsEntry
+}
+
+func NewArticleEnricher(upstream *UpstreamClient, userAgent string, minContent int) *ArticleEnricher {
+	return &ArticleEnricher{
+		upstream:   upstream,
+		userAgent:  userAgent,
+		minContent: minContent,
+		robots:     make(map[string]robotsEntry),
+	}
+}
+
+// EnrichAll enriches a batch with a few pages in flight at once, failures
+// leave the article as the feed delivered it
+func (ae *ArticleEnricher) EnrichAll(ctx context.Context, articles []models.Article) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, enrichWorkers)
+
+	for i := range articles {
+		if !ae.needsEnrichment(articles[i]) {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(article *models.Article) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := ae.Enrich(ctx, article); err != nil {
+				utils.LogErrorToFile("enrich "+article.URL, err.Error())
+			}
+		}(&articles[i])
+	}
+	wg.Wait()
+}
+
+func (ae *ArticleEnricher) needsEnrichment(article models.Article) bool {
+	if article.URL == "" {
+		return false
+	}
+
+	content := strings.TrimSpace(article.Content)
+	return len(content) < ae.minContent || truncatedPattern.MatchString(content) ||
+		article.Image == "" || len(article.Author) == 0
+}
+
+// Enrich downloads the article page, when robots.txt allows it, and fills the
+// content, image, author and canonical key the feed did not provide
+func (ae *ArticleEnricher) Enrich(ctx context.Context, article *models.Article) error {
+	page, err := url.Parse(article.URL)
+	if err != nil || page.Host == "" {
+		return err
+	}
+
+	if !ae.allowed(ctx, page) {
+		return nil
+	}
+
+	resp, err := ae.upstream.Get(ctx, "page:"+page.Host, article.URL, nil)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() || !strings.Contains(resp.Header().Get("Content-Type"), "html") {
+		return nil
+	}
+
+	extracted, err := utils.ExtractArticle(resp.Body(), article.URL)
+	if err != nil {
+		return err
+	}
+
+	content := strings.TrimSpace(article.Content)
+	if (len(content) < ae.minContent || truncatedPattern.MatchString(content)) && len(extracted.Content) > len(content) {
+		article.Content = extracted.Content
+	}
+	if article.Image == "" {
+		article.Image = extracted.Image
+	}
+	if len(article.Author) == 0 && extracted.Byline != "" {
+		article.Author = []string{extracted.Byline}
+	}
+	if article.CanonicalKey == "" && extracted.Canonical != "" {
+		article.CanonicalKey = utils.CanonicalKey(extracted.Canonical, article.Source, article.Title)
+	}
+
+	return nil
+}
+
+// allowed checks the host robots.txt as described by RFC 9309, a missing
+// robots.txt allows everything and a failing one disallows everything
+func (ae *ArticleEnricher) allowed(ctx context.Context, page *url.URL) bool {
+	host := page.Scheme + "://" + page.Host
+
+	ae.mu.Lock()
+	entry, ok := ae.robots[host]
+	ae.mu.Unlock()
+
+	if !ok || time.Since(entry.fetched) > robotsCacheTTL {
+		rules := utils.ParseRobots([]byte(disallowAll), ae.userAgent)
+
+		resp, err := ae.upstream.Get(ctx, "page:"+page.Host, host+"/robots.txt", nil)
+		switch {
+		case err != nil || resp.StatusCode() >= 500:
+			// unreachable, keep everything disallowed until the next refresh
+		case resp.StatusCode() >= 400:
+			rules = utils.ParseRobots(nil, ae.userAgent)
+		default:
+			rules = utils.ParseRobots(resp.Body(), ae.userAgent)
+		}
+
+		entry = robotsEntry{rules: rules, fetched: time.Now()}
+		ae.mu.Lock()
+		ae.robots[host] = entry
+		ae.mu.Unlock()
+	}
+
+	path := page.EscapedPath()
+	if page.RawQuery != "" {
+		path += "?" + page.RawQuery
+	}
+
+	return entry.rules.Allowed(path)
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+// EntityService tags the people, organisations and locations an article
+// mentions, names in the gazetteer get their curated id and the rest an id
+// derived from the name
+type EntityService interface {
+	Extract(articles []models.Article)
+}
+
+type EntityServiceImp struct {
+	path    string
+	limit   int
+	mu      sync.Mutex
+	matcher *utils.EntityMatcher
+	loaded  time.Time
+}
+
+// NewEntityService reads the gazetteer from path, edits to the file are picked
+// up without a restart. Without a gazetteer only the rules apply
+func NewEntityService(path string, limit int) EntityService {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	return &EntityServiceImp{
+		path:    path,
+		limit:   limit,
+		matcher: utils.NewEntityMatcher(),
+	}
+}
+
+// Extract sets the entities of every article, keeping the most mentioned
+func (es *EntityServiceImp) Extract(articles []models.Article) {
+	matcher := es.current()
+
+	for i := range articles {
+		article := &articles[i]
+		mentions := matcher.Extract(article.Title + "\n" + article.Description + "\n" + article.Content)
+		if len(mentions) > es.limit {
+			mentions = mentions[:es.limit]
+		}
+
+		article.Entities = make([]models.Entity, 0, len(mentions))
+		for _, mention := range mentions {
+			article.Entities = append(article.Entities, models.Entity{
+				Id:       mention.Id,
+				Type:     mention.Type,
+				Name:     mention.Name,
+				Mentions: mention.Mentions,
+				Source:   mention.Source,
+				NameKeys: utils.EntityNameKeys(mention.Name),
+			})
+		}
+	}
+}
+
+// current returns the matcher, rebuilding it when the gazetteer changed
+func (es *EntityServiceImp) current() *utils.EntityMatcher {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	info, err := os.Stat(es.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			utils.LogErrorToFile("stat gazetteer", err.Error())
+		}
+		return es.matcher
+	}
+
+	if info.ModTime().After(es.loaded) {
+		matcher, err := loadGazetteer(es.path)
+		if err != nil {
+			utils.LogErrorToFile("load gazetteer", err.Error())
+			return es.matcher
+		}
+		es.matcher = matcher
+		es.loaded = info.ModTime()
+	}
+
+	return es.matcher
+}
+
+func loadGazetteer(path string) (*utils.EntityMatcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var gazetteer models.Gazetteer
+	if err := json.Unmarshal(data, &gazetteer); err != nil {
+		return nil, err
+	}
+
+	matcher := utils.NewEntityMatcher()
+	for _, entry := range gazetteer.Entries {
+		if entry.Type != models.EntityPerson && entry.Type != models.EntityOrganisation && entry.Type != models.EntityLocation {
+			utils.LogErrorToFile("load gazetteer", "unknown entity type "+entry.Type+" for "+entry.Name)
+			continue
+		}
+
+		id := entry.Id
+		if id == "" {
+			id = utils.EntityId(entry.Type, entry.Name)
+		}
+		matcher.Add(id, entry.Type, entry.Name, entry.Aliases)
+	}
+
+	return matcher, nil
+}
@@ -0,0 +1,522 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	imageWorkers = 4
+	// images whose hashes are this close are the same picture recompressed,
+	// resized or lightly edited
+	imageNearDuplicateDistance = 6
+	// imageMaxURLs bounds the upstream urls remembered per image
+	imageMaxURLs     = 100
+	thumbnailQuality = 82
+)
+
+// decodableImages are the content types thumbnails can be made from
+var decodableImages = map[string]bool{"image/jpeg": true, "image/png": true, "image/gif": true}
+
+// errImageTransient marks fetch failures worth retrying the next time the
+// article is saved, the article keeps whatever image it had
+var errImageTransient = errors.New("transient image failure")
+
+type ImageOptions struct {
+	UserAgent string
+	Timeout   time.Duration
+	MaxBytes  int64
+	MinWidth  int
+	MinHeight int
+	MaxPixels int
+	// Widths are the thumbnail widths, images are never scaled up
+	Widths []int
+	// ReuseThreshold is the number of articles sharing an image that makes it a stock photo
+	ReuseThreshold int
+}
+
+// ImageService fetches and validates article lead images, recognises pictures
+// already seen by their perceptual hash and stores thumbnails for new ones
+type ImageService interface {
+	Process(articles []models.Article)
+	Record(articles []models.Article) error
+	createIndexes() error
+}
+
+type ImageServiceImp struct {
+	ctx               context.Context
+	imageCollection   *mongo.Collection
+	articleCollection *mongo.Collection
+	store             ImageStore
+	client            *http.Client
+	opts              ImageOptions
+}
+
+func NewImageService(ctx context.Context, images, articles *mongo.Collection, store ImageStore, opts ImageOptions) ImageService {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 15 * time.Second
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 10 << 20
+	}
+	if opts.MaxPixels <= 0 {
+		opts.MaxPixels = 16000000
+	}
+	if len(opts.Widths) == 0 {
+		opts.Widths = []int{160, 320, 640}
+	}
+	if opts.ReuseThreshold <= 1 {
+		opts.ReuseThreshold = 3
+	}
+
+	return &ImageServiceImp{
+		ctx:               ctx,
+		imageCollection:   images,
+		articleCollection: articles,
+		store:             store,
+		client:            &http.Client{Timeout: opts.Timeout},
+		opts:              opts,
+	}
+}
+
+// fetchedImage is an image downloaded and decoded in this batch
+type fetchedImage struct {
+	status string
+	image  models.Image
+	pixels *image.RGBA
+	hash   uint64
+}
+
+// Process sets the image of every article with a lead image url. Urls seen
+// before are answered from mongo, new ones are downloaded, and pictures that
+// turn out to be a copy of a known image share its thumbnails. Failures are
+// logged and leave the article image as it was
+func (is ImageServiceImp) Process(articles []models.Article) {
+	urls := make([]string, 0, len(articles))
+	seen := make(map[string]bool)
+	for _, article := range articles {
+		if imageURL(article.Image) && !seen[article.Image] {
+			seen[article.Image] = true
+			urls = append(urls, article.Image)
+		}
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	known, err := is.findByURL(urls)
+	if err != nil {
+		utils.LogErrorToFile("find article images", err.Error())
+		return
+	}
+
+	missing := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if _, ok := known[u]; !ok {
+			missing = append(missing, u)
+		}
+	}
+
+	statuses := make(map[string]string)
+	is.fetchAll(missing, func(u string, fetched fetchedImage) {
+		if fetched.status != models.ImageOK {
+			statuses[u] = fetched.status
+			return
+		}
+
+		stored, err := is.save(u, fetched)
+		if err != nil {
+			utils.LogErrorToFile("store image "+u, err.Error())
+			return
+		}
+		known[u] = stored
+	})
+
+	// articles in the batch count towards reuse before they are recorded
+	batchUses := make(map[string]int)
+	for _, article := range articles {
+		if stored, ok := known[article.Image]; ok {
+			batchUses[stored.Hash]++
+		}
+	}
+
+	for i := range articles {
+		article := &articles[i]
+		if stored, ok := known[article.Image]; ok {
+			article.ImageInfo = &models.ArticleImage{
+				Status:      models.ImageOK,
+				Hash:        stored.Hash,
+				Width:       stored.Width,
+				Height:      stored.Height,
+				ContentType: stored.ContentType,
+				Thumbnails:  stored.Thumbnails,
+				Reused:      stored.Uses+batchUses[stored.Hash] >= is.opts.ReuseThreshold,
+			}
+		} else if status, ok := statuses[article.Image]; ok {
+			article.ImageInfo = &models.ArticleImage{Status: status, Thumbnails: []models.Thumbnail{}}
+		}
+	}
+}
+
+func imageURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+func (is ImageServiceImp) findByURL(urls []string) (map[string]models.Image, error) {
+	cursor, err := is.imageCollection.Find(is.ctx, bson.M{"urls": bson.M{"$in": urls}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(is.ctx)
+
+	var images []models.Image
+	if err := cursor.All(is.ctx, &images); err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		wanted[u] = true
+	}
+
+	known := make(map[string]models.Image)
+	for _, img := range images {
+		for _, u := range img.URLs {
+			if wanted[u] {
+				known[u] = img
+			}
+		}
+	}
+
+	return known, nil
+}
+
+// fetchAll downloads the urls with a few requests in flight at once and hands
+// every image to the handler as soon as it is decoded, so only a few are held
+// in memory. Handlers run one at a time, transient failures are only logged
+func (is ImageServiceImp) fetchAll(urls []string, handle func(string, fetchedImage)) {
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, imageWorkers)
+	)
+
+	for _, u := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(u string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			fetched, err := is.fetch(u)
+			if err != nil {
+				utils.LogErrorToFile("fetch image "+u, err.Error())
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			handle(u, fetched)
+		}(u)
+	}
+	wg.Wait()
+}
+
+// fetch downloads and validates one image. Permanent problems are reported
+// through the status, only transient ones are errors
+func (is ImageServiceImp) fetch(u string) (fetchedImage, error) {
+	req, err := http.NewRequestWithContext(is.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fetchedImage{status: models.ImageBroken}, nil
+	}
+	if is.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", is.opts.UserAgent)
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := is.client.Do(req)
+	if err != nil {
+		return fetchedImage{}, fmt.Errorf("%w: %v", errImageTransient, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fetchedImage{}, fmt.Errorf("%w: %s", errImageTransient, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return fetchedImage{status: models.ImageBroken}, nil
+	case resp.ContentLength > is.opts.MaxBytes:
+		return fetchedImage{status: models.ImageTooLarge}, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, is.opts.MaxBytes+1))
+	if err != nil {
+		return fetchedImage{}, fmt.Errorf("%w: %v", errImageTransient, err)
+	}
+	if int64(len(data)) > is.opts.MaxBytes {
+		return fetchedImage{status: models.ImageTooLarge}, nil
+	}
+
+	// the body is trusted over the header, servers label error pages and
+	// webp alike as whatever the url ends in
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return fetchedImage{status: models.ImageInvalid}, nil
+	}
+	if !decodableImages[contentType] {
+		return fetchedImage{status: models.ImageUnsupported}, nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fetchedImage{status: models.ImageBroken}, nil
+	}
+	if config.Width*config.Height > is.opts.MaxPixels {
+		return fetchedImage{status: models.ImageTooLarge}, nil
+	}
+	if config.Width < is.opts.MinWidth || config.Height < is.opts.MinHeight {
+		return fetchedImage{status: models.ImageTooSmall}, nil
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fetchedImage{status: models.ImageBroken}, nil
+	}
+
+	pixels := utils.FlattenImage(decoded)
+	hash := utils.PerceptualHash(pixels)
+	return fetchedImage{
+		status: models.ImageOK,
+		pixels: pixels,
+		hash:   hash,
+		image: models.Image{
+			Hash:        utils.FormatFingerprint(hash),
+			HashBands:   utils.FingerprintBands(hash),
+			Width:       config.Width,
+			Height:      config.Height,
+			ContentType: contentType,
+			Bytes:       len(data),
+		},
+	}, nil
+}
+
+// save stores a fetched image under its url. A near duplicate of a known image
+// only adds the url to it, anything else gets its thumbnails generated
+func (is ImageServiceImp) save(u string, fetched fetchedImage) (models.Image, error) {
+	now := time.Now()
+
+	duplicate, err := is.findNearDuplicate(fetched)
+	if err != nil {
+		return models.Image{}, err
+	}
+	if duplicate != nil {
+		_, err := is.imageCollection.UpdateOne(is.ctx, bson.M{"_id": duplicate.Hash}, bson.M{
+			"$push": bson.M{"urls": bson.M{"$each": []string{u}, "$slice": -imageMaxURLs}},
+			"$set":  bson.M{"last_seen_at": now},
+		})
+		return *duplicate, err
+	}
+
+	img := fetched.image
+	img.Thumbnails, err = is.thumbnails(fetched)
+	if err != nil {
+		return models.Image{}, err
+	}
+	img.URLs = []string{u}
+	img.FirstSeenAt = now
+	img.LastSeenAt = now
+
+	// two urls of the same picture in one batch race for the hash, the loser
+	// adds its url to the winner
+	_, err = is.imageCollection.UpdateOne(is.ctx, bson.M{"_id": img.Hash}, bson.M{
+		"$setOnInsert": bson.M{
+			"hash_bands":    img.HashBands,
+			"width":         img.Width,
+			"height":        img.Height,
+			"content_type":  img.ContentType,
+			"bytes":         img.Bytes,
+			"thumbnails":    img.Thumbnails,
+			"uses":          0,
+			"first_seen_at": now,
+		},
+		"$push": bson.M{"urls": bson.M{"$each": []string{u}, "$slice": -imageMaxURLs}},
+		"$set":  bson.M{"last_seen_at": now},
+	}, options.Update().SetUpsert(true))
+
+	return img, err
+}
+
+func (is ImageServiceImp) findNearDuplicate(fetched fetchedImage) (*models.Image, error) {
+	cursor, err := is.imageCollection.Find(is.ctx, bson.M{"hash_bands": bson.M{"$in": fetched.image.HashBands}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(is.ctx)
+
+	var candidates []models.Image
+	if err := cursor.All(is.ctx, &candidates); err != nil {
+		return nil, err
+	}
+
+	var (
+		best     *models.Image
+		distance = imageNearDuplicateDistance + 1
+	)
+	for i, candidate := range candidates {
+		hash, err := utils.ParseFingerprint(candidate.Hash)
+		if err != nil {
+			continue
+		}
+		if d := utils.HammingDistance(fetched.hash, hash); d < distance {
+			best, distance = &candidates[i], d
+		}
+	}
+
+	return best, nil
+}
+
+// thumbnails stores a jpeg per configured width narrower than the image, an
+// image narrower than every width is stored once at its own size
+func (is ImageServiceImp) thumbnails(fetched fetchedImage) ([]models.Thumbnail, error) {
+	widths := make([]int, 0, len(is.opts.Widths))
+	for _, width := range is.opts.Widths {
+		if width < fetched.image.Width {
+			widths = append(widths, width)
+		}
+	}
+	if len(widths) == 0 {
+		widths = append(widths, fetched.image.Width)
+	}
+
+	thumbnails := make([]models.Thumbnail, 0, len(widths))
+	for _, width := range widths {
+		width, height := utils.FitWidth(fetched.image.Width, fetched.image.Height, width)
+		resized := fetched.pixels
+		if width != fetched.image.Width {
+			resized = utils.ResizeImage(fetched.pixels, width, height)
+		}
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return nil, err
+		}
+
+		key := fmt.Sprintf("%s/%d.jpg", fetched.image.Hash, width)
+		thumbnailURL, err := is.store.Put(is.ctx, key, "image/jpeg", buf.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		thumbnails = append(thumbnails, models.Thumbnail{Width: width, Height: height, URL: thumbnailURL})
+	}
+
+	return thumbnails, nil
+}
+
+// Record counts the articles saved for the first time towards the uses of
+// their image. Images crossing the reuse threshold get every article showing
+// them flagged, earlier articles included
+func (is ImageServiceImp) Record(articles []models.Article) error {
+	uses := make(map[string]int)
+	for _, article := range articles {
+		if article.ImageInfo != nil && article.ImageInfo.Status == models.ImageOK {
+			uses[article.ImageInfo.Hash]++
+		}
+	}
+	if len(uses) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(uses))
+	hashes := make([]string, 0, len(uses))
+	for hash, count := range uses {
+		hashes = append(hashes, hash)
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": hash}).
+			SetUpdate(bson.M{"$inc": bson.M{"uses": count}}))
+	}
+	if _, err := is.imageCollection.BulkWrite(is.ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return err
+	}
+
+	cursor, err := is.imageCollection.Find(is.ctx, bson.M{
+		"_id":  bson.M{"$in": hashes},
+		"uses": bson.M{"$gte": is.opts.ReuseThreshold},
+	}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(is.ctx)
+
+	var reused []models.Image
+	if err := cursor.All(is.ctx, &reused); err != nil {
+		return err
+	}
+	if len(reused) == 0 {
+		return nil
+	}
+
+	reusedHashes := make([]string, 0, len(reused))
+	for _, img := range reused {
+		reusedHashes = append(reusedHashes, img.Hash)
+	}
+	_, err = is.articleCollection.UpdateMany(is.ctx,
+		bson.M{"image.hash": bson.M{"$in": reusedHashes}, "image.reused": false},
+		bson.M{"$set": bson.M{"image.reused": true}})
+
+	return err
+}
+
+func (is ImageServiceImp) createIndexes() error {
+	// Index model for url lookups
+	urlsIndex := mongo.IndexModel{Keys: bson.M{"urls": 1}}
+
+	// Index model for near duplicate lookups
+	bandsIndex := mongo.IndexModel{Keys: bson.M{"hash_bands": 1}}
+
+	if _, err := is.imageCollection.Indexes().CreateMany(is.ctx, []mongo.IndexModel{urlsIndex, bandsIndex}); err != nil {
+		return err
+	}
+
+	// Index model for flagging reused images
+	_, err := is.articleCollection.Indexes().CreateOne(is.ctx, mongo.IndexModel{Keys: bson.M{"image.hash": 1}})
+	return err
+}
+
+// ParseThumbnailWidths reads a comma separated list of thumbnail widths
+func ParseThumbnailWidths(value string) ([]int, error) {
+	widths := make([]int, 0)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		width, err := strconv.Atoi(field)
+		if err != nil || width <= 0 {
+			return nil, fmt.Errorf("invalid thumbnail width %q", field)
+		}
+		widths = append(widths, width)
+	}
+
+	return widths, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+func testImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(255 * x / width), G: uint8(255 * y / height), B: 128, A: 255})
+		}
+	}
+
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImageThumbnails(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		widths        []int
+		want          []models.Thumbnail
+	}{
+		{
+			name:   "every width narrower than the image",
+			width:  500,
+			height: 250,
+			widths: []int{160, 320, 640},
+			want:   []models.Thumbnail{{Width: 160, Height: 80}, {Width: 320, Height: 160}},
+		},
+		{
+			name:   "image narrower than every width",
+			width:  120,
+			height: 90,
+			widths: []int{160, 320},
+			want:   []models.Thumbnail{{Width: 120, Height: 90}},
+		},
+		{
+			name:   "a width equal to the image is not a thumbnail",
+			width:  320,
+			height: 240,
+			widths: []int{160, 320},
+			want:   []models.Thumbnail{{Width: 160, Height: 120}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t)
+			service := ImageServiceImp{
+				ctx:   context.Background(),
+				store: newTestS3Store(t, fake, S3Options{PathStyle: true}),
+				opts:  ImageOptions{Widths: tt.widths},
+			}
+
+			pixels := testImage(tt.width, tt.height)
+			hash := utils.FormatFingerprint(utils.PerceptualHash(pixels))
+			thumbnails, err := service.thumbnails(fetchedImage{
+				pixels: pixels,
+				image:  models.Image{Hash: hash, Width: tt.width, Height: tt.height},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(thumbnails) != len(tt.want) {
+				t.Fatalf("got %d thumbnails, want %d", len(thumbnails), len(tt.want))
+			}
+
+			for i, thumbnail := range thumbnails {
+				want := tt.want[i]
+				if thumbnail.Width != want.Width || thumbnail.Height != want.Height {
+					t.Errorf("thumbnail %d is %dx%d, want %dx%d", i, thumbnail.Width, thumbnail.Height, want.Width, want.Height)
+				}
+
+				if wantURL := fmt.Sprintf("%s/thumbs/%s/%d.jpg", fake.URL, hash, want.Width); thumbnail.URL != wantURL {
+					t.Errorf("thumbnail url = %q, want %q", thumbnail.URL, wantURL)
+				}
+				object, ok := fake.object(strings.TrimPrefix(thumbnail.URL, fake.URL+"/"))
+				if !ok {
+					t.Fatalf("thumbnail %s was not uploaded", thumbnail.URL)
+				}
+				decoded, err := jpeg.Decode(bytes.NewReader(object.data))
+				if err != nil {
+					t.Fatalf("thumbnail %s is not a jpeg: %v", thumbnail.URL, err)
+				}
+				if size := decoded.Bounds().Size(); size.X != want.Width || size.Y != want.Height {
+					t.Errorf("uploaded thumbnail is %v, want %dx%d", size, want.Width, want.Height)
+				}
+			}
+		})
+	}
+}
+
+func TestImageThumbnailsStoreFailure(t *testing.T) {
+	fake := newFakeS3(t)
+	fake.failWith = http.StatusInternalServerError
+	service := ImageServiceImp{
+		ctx:   context.Background(),
+		store: newTestS3Store(t, fake, S3Options{PathStyle: true}),
+		opts:  ImageOptions{Widths: []int{160}},
+	}
+
+	_, err := service.thumbnails(fetchedImage{pixels: testImage(320, 200), image: models.Image{Hash: "ab12", Width: 320, Height: 200}})
+	if err == nil {
+		t.Fatal("a failed upload was not reported")
+	}
+}
+
+func TestImageFetch(t *testing.T) {
+	photo := encodePNG(t, testImage(400, 300))
+	icon := encodePNG(t, testImage(40, 30))
+
+	mux := http.NewServeMux()
+	serve := func(path, contentType string, body []byte) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.Write(body)
+		})
+	}
+	serve("/photo.png", "image/png", photo)
+	serve("/mislabelled.jpg", "image/jpeg", photo)
+	serve("/icon.png", "image/png", icon)
+	serve("/page.jpg", "image/jpeg", []byte("<!DOCTYPE html><html><body>not found</body></html>"))
+	serve("/photo.webp", "image/webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "))
+	serve("/truncated.png", "image/png", photo[:64])
+	serve("/huge.png", "image/png", bytes.Repeat(photo, 4))
+	mux.HandleFunc("/busy.png", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	})
+	mux.HandleFunc("/down.png", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	service := NewImageService(context.Background(), nil, nil, nil, ImageOptions{
+		MinWidth:  100,
+		MinHeight: 100,
+		MaxBytes:  int64(len(photo)) * 2,
+	}).(*ImageServiceImp)
+
+	tests := []struct {
+		path      string
+		status    string
+		transient bool
+	}{
+		{path: "/photo.png", status: models.ImageOK},
+		{path: "/mislabelled.jpg", status: models.ImageOK},
+		{path: "/icon.png", status: models.ImageTooSmall},
+		{path: "/page.jpg", status: models.ImageInvalid},
+		{path: "/photo.webp", status: models.ImageUnsupported},
+		{path: "/truncated.png", status: models.ImageBroken},
+		{path: "/huge.png", status: models.ImageTooLarge},
+		{path: "/missing.png", status: models.ImageBroken},
+		{path: "/busy.png", transient: true},
+		{path: "/down.png", transient: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			fetched, err := service.fetch(server.URL + tt.path)
+			if tt.transient {
+				if !errors.Is(err, errImageTransient) {
+					t.Fatalf("fetch() error = %v, want a transient failure", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fetched.status != tt.status {
+				t.Fatalf("status = %q, want %q", fetched.status, tt.status)
+			}
+			if tt.status != models.ImageOK {
+				return
+			}
+
+			if fetched.image.Width != 400 || fetched.image.Height != 300 || fetched.image.ContentType != "image/png" {
+				t.Errorf("image = %+v, want a 400x300 png", fetched.image)
+			}
+			if fetched.image.Hash != utils.FormatFingerprint(fetched.hash) || len(fetched.image.HashBands) == 0 {
+				t.Errorf("hash %q and bands %v do not match %x", fetched.image.Hash, fetched.image.HashBands, fetched.hash)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+// ImageStore keeps generated thumbnails and returns the url they are served from
+type ImageStore interface {
+	Put(ctx context.Context, key, contentType string, data []byte) (string, error)
+}
+
+// DiskImageStore writes thumbnails under a directory served at the public url
+type DiskImageStore struct {
+	dir       string
+	publicURL string
+}
+
+func NewDiskImageStore(dir, publicURL string) *DiskImageStore {
+	return &DiskImageStore{dir: dir, publicURL: strings.TrimRight(publicURL, "/")}
+}
+
+// Put writes through a temporary file so readers never see half a thumbnail
+func (ds *DiskImageStore) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	path := filepath.Join(ds.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".thumbnail-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+
+	return ds.publicURL + "/" + key, nil
+}
+
+type S3Options struct {
+	// Endpoint is the store url, https://s3.eu-west-1.amazonaws.com or a minio host
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle puts the bucket in the path instead of the host, minio needs it
+	PathStyle bool
+	// PublicURL is where clients read objects from, the object url when empty
+	PublicURL string
+	Timeout   time.Duration
+}
+
+// S3ImageStore uploads thumbnails to an s3 compatible bucket with signed PUT requests
+type S3ImageStore struct {
+	client *http.Client
+	opts   S3Options
+}
+
+func NewS3ImageStore(opts S3Options) (*S3ImageStore, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("s3 image store needs a bucket")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if _, err := url.Parse(opts.Endpoint); err != nil || opts.Endpoint == "" {
+		return nil, fmt.Errorf("s3 image store endpoint %q is not a url", opts.Endpoint)
+	}
+	opts.Endpoint = strings.TrimRight(opts.Endpoint, "/")
+	opts.PublicURL = strings.TrimRight(opts.PublicURL, "/")
+
+	return &S3ImageStore{client: &http.Client{Timeout: opts.Timeout}, opts: opts}, nil
+}
+
+func (ss *S3ImageStore) objectURL(key string) string {
+	if ss.opts.PathStyle {
+		return ss.opts.Endpoint + "/" + ss.opts.Bucket + "/" + key
+	}
+
+	endpoint, _ := url.Parse(ss.opts.Endpoint)
+	endpoint.Host = ss.opts.Bucket + "." + endpoint.Host
+	return endpoint.String() + "/" + key
+}
+
+// Put uploads the object, thumbnails are named after the image hash so they
+// never change and can be cached forever
+func (ss *S3ImageStore) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	object := ss.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, object, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Cache-Control", "public, max-age=31536000, immutable")
+
+	utils.SignV4(req, data, utils.SigV4Credentials{
+		AccessKey: ss.opts.AccessKey,
+		SecretKey: ss.opts.SecretKey,
+		Region:    ss.opts.Region,
+		Service:   "s3",
+	}, time.Now())
+
+	resp, err := ss.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("put %s: %s %s", key, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if ss.opts.PublicURL != "" {
+		return ss.opts.PublicURL + "/" + key, nil
+	}
+	return object, nil
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	fakeS3AccessKey = "AKIDNEWSAGS"
+	fakeS3SecretKey = "news-ags-secret"
+	fakeS3Region    = "eu-west-1"
+)
+
+type fakeS3Object struct {
+	contentType  string
+	cacheControl string
+	data         []byte
+}
+
+// fakeS3 is an s3 compatible store that keeps objects in memory and checks
+// every signature the way s3 does, from the headers the request signed
+type fakeS3 struct {
+	*httptest.Server
+	mu      sync.Mutex
+	objects map[string]fakeS3Object
+	// failWith answers every upload with the status when set
+	failWith int
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	t.Helper()
+
+	fake := &fakeS3{objects: make(map[string]fakeS3Object)}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(fake.Close)
+
+	return fake
+}
+
+func (fs *fakeS3) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if problem := fakeS3Verify(r, body); problem != "" {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>"+problem+"</Message></Error>", http.StatusForbidden)
+		return
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.failWith != 0 {
+		http.Error(w, "<Error><Code>SlowDown</Code></Error>", fs.failWith)
+		return
+	}
+
+	// virtual hosted requests name the bucket in the host, path style ones in the path
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if host, _, _ := net.SplitHostPort(r.Host); strings.HasPrefix(host, "thumbs.") {
+		key = "thumbs/" + key
+	}
+	fs.objects[key] = fakeS3Object{
+		contentType:  r.Header.Get("Content-Type"),
+		cacheControl: r.Header.Get("Cache-Control"),
+		data:         body,
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (fs *fakeS3) object(key string) (fakeS3Object, bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	object, ok := fs.objects[key]
+	return object, ok
+}
+
+// fakeS3Verify rebuilds the signature of a request and returns what is wrong with it
+func fakeS3Verify(r *http.Request, body []byte) string {
+	auth := r.Header.Get("Authorization")
+	fields := map[string]string{}
+	for _, field := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ", ") {
+		if name, value, ok := strings.Cut(field, "="); ok {
+			fields[name] = value
+		}
+	}
+	scope := strings.SplitN(fields["Credential"], "/", 2)
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") || len(scope) != 2 || fields["SignedHeaders"] == "" {
+		return "malformed authorization " + auth
+	}
+	if scope[0] != fakeS3AccessKey {
+		return "unknown access key " + scope[0]
+	}
+
+	bodyHash := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(bodyHash[:]) {
+		return "payload hash does not match the body"
+	}
+
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signed) {
+		return "signed headers are not sorted"
+	}
+	var headers strings.Builder
+	for _, name := range signed {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	for _, required := range []string{"host", "x-amz-content-sha256", "x-amz-date"} {
+		if !strings.Contains(";"+fields["SignedHeaders"]+";", ";"+required+";") {
+			return required + " is not signed"
+		}
+	}
+
+	canonical := strings.Join([]string{r.Method, r.URL.EscapedPath(), r.URL.RawQuery, headers.String(), fields["SignedHeaders"], hex.EncodeToString(bodyHash[:])}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", r.Header.Get("X-Amz-Date"), scope[1], hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	parts := strings.Split(scope[1], "/")
+	if len(parts) != 4 || parts[1] != fakeS3Region || parts[2] != "s3" || parts[3] != "aws4_request" {
+		return "bad credential scope " + scope[1]
+	}
+	key := []byte("AWS4" + fakeS3SecretKey)
+	for _, part := range append(parts, stringToSign) {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	if hex.EncodeToString(key) != fields["Signature"] {
+		return "signature does not match"
+	}
+
+	return ""
+}
+
+func newTestS3Store(t *testing.T, fake *fakeS3, opts S3Options) *S3ImageStore {
+	t.Helper()
+
+	if opts.Endpoint == "" {
+		opts.Endpoint = fake.URL
+	}
+	opts.Bucket = "thumbs"
+	opts.Region = fakeS3Region
+	opts.AccessKey = fakeS3AccessKey
+	if opts.SecretKey == "" {
+		opts.SecretKey = fakeS3SecretKey
+	}
+
+	store, err := NewS3ImageStore(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// virtual hosted bucket names do not resolve, every request goes to the fake
+	address := fake.Listener.Addr().String()
+	store.client.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, address)
+		},
+	}
+
+	return store
+}
+
+func TestS3ImageStorePut(t *testing.T) {
+	tests := []struct {
+		name string
+		opts S3Options
+		url  func(fake *fakeS3) string
+	}{
+		{
+			name: "path style",
+			opts: S3Options{PathStyle: true},
+			url:  func(fake *fakeS3) string { return fake.URL + "/thumbs/ab12/320.jpg" },
+		},
+		{
+			name: "virtual hosted",
+			url:  func(fake *fakeS3) string { return strings.Replace(fake.URL, "://", "://thumbs.", 1) + "/ab12/320.jpg" },
+		},
+		{
+			name: "public url",
+			opts: S3Options{PathStyle: true, PublicURL: "https://cdn.news-ags.example/"},
+			url:  func(*fakeS3) string { return "https://cdn.news-ags.example/ab12/320.jpg" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t)
+			store := newTestS3Store(t, fake, tt.opts)
+
+			got, err := store.Put(context.Background(), "ab12/320.jpg", "image/jpeg", []byte("jpeg bytes"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.url(fake); got != want {
+				t.Errorf("Put() = %q, want %q", got, want)
+			}
+
+			object, ok := fake.object("thumbs/ab12/320.jpg")
+			if !ok {
+				t.Fatalf("object not stored, store holds %v", fake.objects)
+			}
+			if string(object.data) != "jpeg bytes" || object.contentType != "image/jpeg" {
+				t.Errorf("stored %q as %q", object.data, object.contentType)
+			}
+			if !strings.Contains(object.cacheControl, "immutable") {
+				t.Errorf("cache control = %q, want thumbnails cached for good", object.cacheControl)
+			}
+		})
+	}
+}
+
+func TestS3ImageStorePutFailures(t *testing.T) {
+	t.Run("wrong secret", func(t *testing.T) {
+		fake := newFakeS3(t)
+		store := newTestS3Store(t, fake, S3Options{PathStyle: true, SecretKey: "not-the-secret"})
+
+		_, err := store.Put(context.Background(), "ab12/160.jpg", "image/jpeg", []byte("jpeg bytes"))
+		if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+			t.Fatalf("Put() error = %v, want the rejected signature", err)
+		}
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		fake := newFakeS3(t)
+		fake.failWith = http.StatusServiceUnavailable
+		store := newTestS3Store(t, fake, S3Options{PathStyle: true})
+
+		_, err := store.Put(context.Background(), "ab12/160.jpg", "image/jpeg", []byte("jpeg bytes"))
+		if err == nil || !strings.Contains(err.Error(), "503") {
+			t.Fatalf("Put() error = %v, want the store status", err)
+		}
+	})
+}
+
+func TestNewS3ImageStore(t *testing.T) {
+	if _, err := NewS3ImageStore(S3Options{Endpoint: "https://s3.example"}); err == nil {
+		t.Error("a store without a bucket was accepted")
+	}
+	if _, err := NewS3ImageStore(S3Options{Bucket: "thumbs"}); err == nil {
+		t.Error("a store without an endpoint was accepted")
+	}
+
+	store, err := NewS3ImageStore(S3Options{Endpoint: "https://s3.example/", Bucket: "thumbs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.opts.Region != "us-east-1" || store.opts.Timeout <= 0 {
+		t.Errorf("defaults not applied: %+v", store.opts)
+	}
+	if got := store.objectURL("a/b.jpg"); got != "https://thumbs.s3.example/a/b.jpg" {
+		t.Errorf("objectURL() = %q", got)
+	}
+}
+
+func TestDiskImageStorePut(t *testing.T) {
+	dir := t.TempDir()
+	store := NewDiskImageStore(dir, "https://news-ags.example/thumbnails/")
+
+	got, err := store.Put(context.Background(), "ab12/320.jpg", "image/jpeg", []byte("jpeg bytes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://news-ags.example/thumbnails/ab12/320.jpg"; got != want {
+		t.Errorf("Put() = %q, want %q", got, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "ab12", "320.jpg"))
+	if err != nil || string(data) != "jpeg bytes" {
+		t.Errorf("stored %q, %v", data, err)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, "ab12", ".thumbnail-*")); len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
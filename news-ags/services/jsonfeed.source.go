@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+type JSONFeedSource struct {
+	cfg         models.SourceConfig
+	upstream    *UpstreamClient
+	deadLetters DeadLetterService
+}
+
+func NewJSONFeedSource(cfg models.SourceConfig, upstream *UpstreamClient, deadLetters DeadLetterService) Source {
+	return &JSONFeedSource{
+		cfg:         cfg,
+		upstream:    upstream,
+		deadLetters: deadLetters,
+	}
+}
+
+func (js JSONFeedSource) Name() string {
+	return js.cfg.Name
+}
+
+// Fetch reads a JSON Feed 1.1 document
+func (js JSONFeedSource) Fetch(ctx context.Context, emit func([]models.Article) (int, error)) []models.FetchReport {
+	return fetchFeed(ctx, js.upstream, js.cfg, js.parse, js.deadLetters, emit)
+}
+
+// Reparse reads a feed document captured as a dead letter again
+func (js JSONFeedSource) Reparse(letter models.DeadLetter) ([]models.Article, error) {
+	return js.parse([]byte(letter.Payload))
+}
+
+func (js JSONFeedSource) parse(body []byte) ([]models.Article, error) {
+	var feed models.JSONFeed
+	if err := json.Unmarshal(body, &feed); err != nil {
+		return nil, err
+	}
+
+	articles := make([]models.Article, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		content := item.ContentText
+		if content == "" {
+			content = item.ContentHTML
+		}
+
+		authors := make([]string, 0, len(item.Authors))
+		for _, author := range item.Authors {
+			authors = append(authors, author.Name)
+		}
+
+		image := item.Image
+		if image == "" {
+			image = item.BannerImage
+		}
+		if image == "" {
+			image = utils.FirstImage(item.ContentHTML)
+		}
+
+		date := item.DatePublished
+		if date == "" {
+			date = item.DateModified
+		}
+
+		article := models.Article{
+			Title:       utils.CleanText(item.Title),
+			Description: utils.CleanText(item.Summary),
+			URL:         item.URL,
+			Author:      authors,
+			Image:       image,
+			Content:     utils.CleanText(content),
+			Keywords:    item.Tags,
+			Date:        date,
+			Language:    item.Language,
+		}
+		applySourceDefaults(&article, js.cfg)
+		articles = append(articles, article)
+	}
+
+	return articles, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+)
+
+func TestJSONFeedParse(t *testing.T) {
+	source := JSONFeedSource{cfg: models.SourceConfig{Name: "ph-post", Country: []string{"ng"}}}
+
+	articles, err := source.parse(readFeedFixture(t, "feed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(articles) != 3 {
+		t.Fatalf("parsed %d articles, want 3", len(articles))
+	}
+
+	refinery := articles[0]
+	if refinery.Content != "The refinery restarted on Monday." || refinery.Description != "Output resumes." {
+		t.Errorf("content = %q, description = %q", refinery.Content, refinery.Description)
+	}
+	if refinery.Image != "https://phpost.example.com/img/refinery.jpg" {
+		t.Errorf("image = %q, want the item image", refinery.Image)
+	}
+	if !slices.Equal(refinery.Author, []string{"Tari Ebi"}) || !slices.Equal(refinery.Keywords, []string{"energy", "oil"}) {
+		t.Errorf("authors = %q, keywords = %q", refinery.Author, refinery.Keywords)
+	}
+	if refinery.Date != "2024-09-02T07:00:00+01:00" || refinery.Language != "en" {
+		t.Errorf("date = %q, language = %q", refinery.Date, refinery.Language)
+	}
+	if refinery.Source != "ph-post" || !slices.Equal(refinery.Country, []string{"ng"}) {
+		t.Errorf("source defaults not applied: %+v", refinery)
+	}
+
+	bridge := articles[1]
+	if bridge.Image != "https://phpost.example.com/img/bridge.jpg" || bridge.Date != "2024-09-02T12:00:00+01:00" {
+		t.Errorf("image = %q, date = %q, want the banner and date_modified", bridge.Image, bridge.Date)
+	}
+
+	if market := articles[2]; market.Image != "https://phpost.example.com/img/market.jpg" {
+		t.Errorf("image = %q, want the embedded image", market.Image)
+	}
+}
+
+func TestJSONFeedParseRejectsInvalidJSON(t *testing.T) {
+	if _, err := (JSONFeedSource{}).parse([]byte(`{"items": [`)); err == nil {
+		t.Error("parsed a truncated feed, want an error")
+	}
+}
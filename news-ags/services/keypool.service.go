@@ -0,0 +1,217 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+)
+
+const (
+	newsdataQuotaPrefix    = "newsdata:quota:"
+	newsdataCooldownPrefix = "newsdata:cooldown:"
+	// usage counters outlive their day so the admin endpoint can still read
+	// yesterday while the clocks of replicas disagree around midnight
+	newsdataQuotaTTL = 48 * time.Hour
+)
+
+var (
+	ErrQuotaExhausted = errors.New("every newsdata api key is out of credits")
+	ErrNoNewsdataKeys = errors.New("no newsdata api key configured")
+)
+
+// reserveCreditScript takes one credit from a key unless it is cooling down or
+// its daily limit is spent
+var reserveCreditScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[2]) == 1 then
+	return -1
+end
+local used = redis.call("INCR", KEYS[1])
+if used == 1 then
+	redis.call("EXPIRE", KEYS[1], ARGV[2])
+end
+if used > tonumber(ARGV[1]) then
+	redis.call("DECR", KEYS[1])
+	return -1
+end
+return used`)
+
+// refundCreditScript gives a credit back to the day it was taken from, a day
+// whose counter already expired is left alone
+var refundCreditScript = redis.NewScript(`
+local used = tonumber(redis.call("GET", KEYS[1]))
+if used == nil or used <= 0 then
+	return 0
+end
+return redis.call("DECR", KEYS[1])`)
+
+// NewsdataKey is one api key of the pool and its daily credit limit
+type NewsdataKey struct {
+	Id    string
+	Value string
+	Limit int
+}
+
+// Masked shows enough of the key to recognise it
+func (nk NewsdataKey) Masked() string {
+	if len(nk.Value) <= 8 {
+		return strings.Repeat("*", len(nk.Value))
+	}
+
+	return nk.Value[:4] + strings.Repeat("*", len(nk.Value)-8) + nk.Value[len(nk.Value)-4:]
+}
+
+// NewsdataKeyPool hands out newsdata.io api keys with credits left for the
+// day. Keys are used in order, the next one is only touched once the previous
+// one is spent, and usage is counted in redis so every replica shares it
+type NewsdataKeyPool interface {
+	Acquire() (NewsdataKey, string, error)
+	Refund(key NewsdataKey, day string) error
+	Exhaust(key NewsdataKey) error
+	Cooldown(key NewsdataKey, wait time.Duration) error
+	Quota() (models.NewsdataQuota, error)
+}
+
+type NewsdataKeyPoolImp struct {
+	rClient *redis.Client
+	keys    []NewsdataKey
+}
+
+func NewNewsdataKeyPool(client *redis.Client, keys []NewsdataKey) NewsdataKeyPool {
+	return &NewsdataKeyPoolImp{rClient: client, keys: keys}
+}
+
+func quotaDay(now time.Time) string {
+	return now.UTC().Format("2006-01-02")
+}
+
+func quotaKey(key NewsdataKey, day string) string {
+	return newsdataQuotaPrefix + day + ":" + key.Id
+}
+
+// Acquire reserves a credit on the first key that has one, the credit is
+// counted as spent until it is refunded. The day the credit was taken from is
+// returned for the refund
+func (kp NewsdataKeyPoolImp) Acquire() (NewsdataKey, string, error) {
+	if len(kp.keys) == 0 {
+		return NewsdataKey{}, "", ErrNoNewsdataKeys
+	}
+
+	day := quotaDay(time.Now())
+	for _, key := range kp.keys {
+		used, err := reserveCreditScript.Run(kp.rClient,
+			[]string{quotaKey(key, day), newsdataCooldownPrefix + key.Id},
+			key.Limit, int(newsdataQuotaTTL.Seconds())).Int()
+		if err != nil {
+			return NewsdataKey{}, "", err
+		}
+		if used > 0 {
+			return key, day, nil
+		}
+	}
+
+	return NewsdataKey{}, "", ErrQuotaExhausted
+}
+
+// Refund gives back a credit for a request newsdata did not charge, on the
+// day Acquire took it from even when midnight passed in between
+func (kp NewsdataKeyPoolImp) Refund(key NewsdataKey, day string) error {
+	return refundCreditScript.Run(kp.rClient, []string{quotaKey(key, day)}).Err()
+}
+
+// Exhaust marks the key spent until midnight UTC, newsdata knows better than
+// our count when other clients share the key
+func (kp NewsdataKeyPoolImp) Exhaust(key NewsdataKey) error {
+	return kp.rClient.Set(quotaKey(key, quotaDay(time.Now())), key.Limit, newsdataQuotaTTL).Err()
+}
+
+// Cooldown skips the key for a while without touching its credits
+func (kp NewsdataKeyPoolImp) Cooldown(key NewsdataKey, wait time.Duration) error {
+	return kp.rClient.Set(newsdataCooldownPrefix+key.Id, 1, wait).Err()
+}
+
+// Quota reports the credits used and left on every key today
+func (kp NewsdataKeyPoolImp) Quota() (models.NewsdataQuota, error) {
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	quota := models.NewsdataQuota{
+		Day:      quotaDay(now),
+		ResetsAt: midnight.Add(24 * time.Hour),
+		Keys:     make([]models.NewsdataKeyQuota, 0, len(kp.keys)),
+	}
+
+	for _, key := range kp.keys {
+		used, err := kp.rClient.Get(quotaKey(key, quota.Day)).Int()
+		if err != nil && err != redis.Nil {
+			return quota, err
+		}
+		used = min(used, key.Limit)
+
+		keyQuota := models.NewsdataKeyQuota{
+			Id:        key.Id,
+			Key:       key.Masked(),
+			Limit:     key.Limit,
+			Used:      used,
+			Remaining: key.Limit - used,
+			Exhausted: used >= key.Limit,
+		}
+
+		cooling, err := kp.rClient.PTTL(newsdataCooldownPrefix + key.Id).Result()
+		if err != nil {
+			return quota, err
+		}
+		if cooling > 0 {
+			until := now.Add(cooling)
+			keyQuota.CoolingUntil = &until
+		}
+
+		quota.Limit += keyQuota.Limit
+		quota.Used += keyQuota.Used
+		quota.Remaining += keyQuota.Remaining
+		quota.Keys = append(quota.Keys, keyQuota)
+	}
+
+	return quota, nil
+}
+
+// ParseNewsdataKeys reads a comma separated list of api keys, each optionally
+// followed by its own daily credit limit as key:limit. Keys are identified by
+// a hash so they never end up in redis or logs
+func ParseNewsdataKeys(value string, limit int) ([]NewsdataKey, error) {
+	keys := make([]NewsdataKey, 0)
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		key := NewsdataKey{Value: field, Limit: limit}
+		if value, credits, ok := strings.Cut(field, ":"); ok {
+			parsed, err := strconv.Atoi(credits)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("newsdata key limit %q must be a positive number", credits)
+			}
+			key.Value, key.Limit = value, parsed
+		}
+		if key.Limit <= 0 {
+			return nil, fmt.Errorf("newsdata daily credits must be a positive number")
+		}
+		if seen[key.Value] {
+			continue
+		}
+		seen[key.Value] = true
+
+		sum := sha256.Sum256([]byte(key.Value))
+		key.Id = hex.EncodeToString(sum[:])[:12]
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
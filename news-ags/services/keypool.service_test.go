@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+)
+
+// newTestRedis starts an in memory redis that is closed with the test
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return server, client
+}
+
+func TestKeyPoolAcquireRotates(t *testing.T) {
+	_, client := newTestRedis(t)
+	keys, err := ParseNewsdataKeys("first-key-0001,second-key-0002:1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := NewNewsdataKeyPool(client, keys)
+
+	want := []string{keys[0].Id, keys[0].Id, keys[1].Id}
+	for i, id := range want {
+		key, _, err := pool.Acquire()
+		if err != nil {
+			t.Fatalf("Acquire() #%d error = %v", i+1, err)
+		}
+		if key.Id != id {
+			t.Errorf("Acquire() #%d = key %s, want %s", i+1, key.Id, id)
+		}
+	}
+
+	if _, _, err := pool.Acquire(); err != ErrQuotaExhausted {
+		t.Errorf("Acquire() on spent keys error = %v, want %v", err, ErrQuotaExhausted)
+	}
+}
+
+func TestKeyPoolCooldownSkipsKey(t *testing.T) {
+	server, client := newTestRedis(t)
+	keys, _ := ParseNewsdataKeys("first-key-0001,second-key-0002", 5)
+	pool := NewNewsdataKeyPool(client, keys)
+
+	if err := pool.Cooldown(keys[0], time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	key, _, err := pool.Acquire()
+	if err != nil || key.Id != keys[1].Id {
+		t.Fatalf("Acquire() while cooling = %s, %v, want %s", key.Id, err, keys[1].Id)
+	}
+
+	server.FastForward(2 * time.Minute)
+	key, _, err = pool.Acquire()
+	if err != nil || key.Id != keys[0].Id {
+		t.Fatalf("Acquire() after cooldown = %s, %v, want %s", key.Id, err, keys[0].Id)
+	}
+}
+
+func TestKeyPoolRefundUsesReservedDay(t *testing.T) {
+	server, client := newTestRedis(t)
+	keys, _ := ParseNewsdataKeys("first-key-0001", 5)
+	pool := NewNewsdataKeyPool(client, keys)
+
+	key, day, err := pool.Acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// midnight passed and today's counter already has a credit on it
+	yesterday, today := "2023-12-04", "2023-12-05"
+	server.Set(quotaKey(key, yesterday), "3")
+	server.Del(quotaKey(key, day))
+	server.Set(quotaKey(key, today), "1")
+
+	if err := pool.Refund(key, yesterday); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := server.Get(quotaKey(key, yesterday)); got != "2" {
+		t.Errorf("yesterday's count = %s, want 2", got)
+	}
+	if got, _ := server.Get(quotaKey(key, today)); got != "1" {
+		t.Errorf("today's count = %s, want 1", got)
+	}
+}
+
+func TestKeyPoolRefundSkipsExpiredDay(t *testing.T) {
+	server, client := newTestRedis(t)
+	keys, _ := ParseNewsdataKeys("first-key-0001", 5)
+	pool := NewNewsdataKeyPool(client, keys)
+
+	if err := pool.Refund(keys[0], "2023-12-04"); err != nil {
+		t.Fatal(err)
+	}
+	if server.Exists(quotaKey(keys[0], "2023-12-04")) {
+		t.Error("refund created a counter for a day that expired")
+	}
+}
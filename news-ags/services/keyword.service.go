@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// title and description words say more about the article than body words
+	titleTermWeight       = 3
+	descriptionTermWeight = 2
+	contentTermWeight     = 1
+
+	// upstreamKeywordBoost is added to keywords the publisher supplied
+	upstreamKeywordBoost = 0.5
+
+	// corpusLookupChunk bounds the size of a single $in term lookup
+	corpusLookupChunk = 5000
+)
+
+// KeywordService extracts weighted keywords with TF-IDF. Document frequencies
+// come from a rolling window of daily corpus statistics kept in mongo
+type KeywordService interface {
+	Extract(articles []models.Article) error
+	Record(articles []models.Article) error
+	createIndexes() error
+}
+
+type KeywordServiceImp struct {
+	ctx            context.Context
+	termCollection *mongo.Collection
+	dayCollection  *mongo.Collection
+	limit          int
+	window         time.Duration
+}
+
+func NewKeywordService(ctx context.Context, terms, days *mongo.Collection, limit, windowDays int) KeywordService {
+	if limit <= 0 {
+		limit = 10
+	}
+	if windowDays <= 0 {
+		windowDays = 30
+	}
+
+	return &KeywordServiceImp{
+		ctx:            ctx,
+		termCollection: terms,
+		dayCollection:  days,
+		limit:          limit,
+		window:         time.Duration(windowDays) * 24 * time.Hour,
+	}
+}
+
+type corpusTerm struct {
+	Term string `bson:"_id"`
+	DF   int    `bson:"df"`
+}
+
+// Extract sets the keywords and keyword weights of every article, upstream
+// keywords are kept and boosted and the extracted ones are appended. The
+// batch itself counts towards the document frequencies so a cold corpus
+// still favours terms that are specific to an article
+func (ks KeywordServiceImp) Extract(articles []models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	frequencies := make([]map[string]float64, len(articles))
+	batchDF := make(map[string]int)
+	for i, article := range articles {
+		tf := utils.TermFrequencies(article.Title, titleTermWeight, nil)
+		tf = utils.TermFrequencies(article.Description, descriptionTermWeight, tf)
+		tf = utils.TermFrequencies(article.Content, contentTermWeight, tf)
+
+		// a phrase seen once is usually two words that happen to be adjacent
+		occurrences := utils.TermFrequencies(article.Title+"\n"+article.Description+"\n"+article.Content, 1, nil)
+		for term := range tf {
+			if strings.Contains(term, " ") && occurrences[term] < 2 {
+				delete(tf, term)
+			}
+		}
+		frequencies[i] = tf
+
+		for term := range tf {
+			batchDF[term]++
+		}
+	}
+
+	df, documents, err := ks.corpusStats(batchDF)
+	if err != nil {
+		return err
+	}
+	for term, count := range batchDF {
+		df[term] += count
+	}
+	documents += len(articles)
+
+	for i := range articles {
+		ks.weigh(&articles[i], utils.TFIDF(frequencies[i], df, documents))
+	}
+
+	return nil
+}
+
+// weigh picks the best scoring terms, normalized to the top score, and merges
+// them with the upstream keywords
+func (ks KeywordServiceImp) weigh(article *models.Article, scores map[string]float64) {
+	ranked := make([]string, 0, len(scores))
+	top := 0.0
+	for term, score := range scores {
+		ranked = append(ranked, term)
+		top = math.Max(top, score)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if scores[ranked[i]] != scores[ranked[j]] {
+			return scores[ranked[i]] > scores[ranked[j]]
+		}
+		return ranked[i] < ranked[j]
+	})
+
+	normalized := func(term string) float64 {
+		if top == 0 {
+			return 0
+		}
+		return scores[term] / top
+	}
+
+	weights := make(map[string]float64)
+	keywords := make([]string, 0, len(article.Keywords)+ks.limit)
+	for _, keyword := range article.Keywords {
+		term := strings.ToLower(strings.TrimSpace(keyword))
+		if term == "" {
+			continue
+		}
+		if _, ok := weights[term]; ok {
+			continue
+		}
+
+		weights[term] = math.Min(1, normalized(term)+upstreamKeywordBoost)
+		keywords = append(keywords, strings.TrimSpace(keyword))
+	}
+
+	extracted := 0
+	for _, term := range ranked {
+		if extracted >= ks.limit {
+			break
+		}
+		if _, ok := weights[term]; ok || coveredByPhrase(term, weights) {
+			continue
+		}
+
+		weights[term] = normalized(term)
+		keywords = append(keywords, term)
+		extracted++
+	}
+
+	article.Keywords = keywords
+	article.KeywordWeights = make([]models.KeywordWeight, 0, len(weights))
+	for term, weight := range weights {
+		article.KeywordWeights = append(article.KeywordWeights, models.KeywordWeight{Term: term, Weight: math.Round(weight*1000) / 1000})
+	}
+	sort.Slice(article.KeywordWeights, func(i, j int) bool {
+		a, b := article.KeywordWeights[i], article.KeywordWeights[j]
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		return a.Term < b.Term
+	})
+}
+
+// coveredByPhrase reports whether a single word is already part of a chosen phrase
+func coveredByPhrase(term string, chosen map[string]float64) bool {
+	if strings.Contains(term, " ") {
+		return false
+	}
+
+	for phrase := range chosen {
+		for _, word := range strings.Fields(phrase) {
+			if word == term && phrase != term {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// corpusStats returns the document frequency of the given terms and the
+// number of documents recorded inside the window
+func (ks KeywordServiceImp) corpusStats(terms map[string]int) (map[string]int, int, error) {
+	since := ks.windowStart(time.Now())
+	df := make(map[string]int, len(terms))
+
+	chunk := make([]string, 0, corpusLookupChunk)
+	lookup := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"term": bson.M{"$in": chunk}, "day": bson.M{"$gte": since}}}},
+			{{Key: "$group", Value: bson.M{"_id": "$term", "df": bson.M{"$sum": "$df"}}}},
+		}
+		cursor, err := ks.termCollection.Aggregate(ks.ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ks.ctx)
+
+		var counts []corpusTerm
+		if err := cursor.All(ks.ctx, &counts); err != nil {
+			return err
+		}
+		for _, count := range counts {
+			df[count.Term] = count.DF
+		}
+
+		chunk = chunk[:0]
+		return nil
+	}
+
+	for term := range terms {
+		chunk = append(chunk, term)
+		if len(chunk) == corpusLookupChunk {
+			if err := lookup(); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	if err := lookup(); err != nil {
+		return nil, 0, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": bson.M{"$gte": since}}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "documents": bson.M{"$sum": "$documents"}}}},
+	}
+	cursor, err := ks.dayCollection.Aggregate(ks.ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ks.ctx)
+
+	var totals []struct {
+		Documents int `bson:"documents"`
+	}
+	if err := cursor.All(ks.ctx, &totals); err != nil {
+		return nil, 0, err
+	}
+
+	documents := 0
+	if len(totals) > 0 {
+		documents = totals[0].Documents
+	}
+
+	return df, documents, nil
+}
+
+// Record adds newly stored articles to today's corpus statistics, the daily
+// buckets expire once they leave the window
+func (ks KeywordServiceImp) Record(articles []models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	expires := day.Add(ks.window + 24*time.Hour)
+
+	df := make(map[string]int)
+	for _, article := range articles {
+		seen := make(map[string]struct{})
+		for _, term := range utils.Terms(article.Title + "\n" + article.Description + "\n" + article.Content) {
+			if _, ok := seen[term]; ok {
+				continue
+			}
+			seen[term] = struct{}{}
+			df[term]++
+		}
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(df))
+	for term, count := range df {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"term": term, "day": day}).
+			SetUpdate(bson.M{"$inc": bson.M{"df": count}, "$setOnInsert": bson.M{"expires_at": expires}}).
+			SetUpsert(true))
+	}
+	if len(writes) > 0 {
+		if _, err := ks.termCollection.BulkWrite(ks.ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+
+	_, err := ks.dayCollection.UpdateOne(ks.ctx,
+		bson.M{"_id": day},
+		bson.M{"$inc": bson.M{"documents": len(articles)}, "$setOnInsert": bson.M{"expires_at": expires}},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+func (ks KeywordServiceImp) windowStart(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour).Add(-ks.window)
+}
+
+func (ks KeywordServiceImp) createIndexes() error {
+	// Index model for term lookups and daily upserts
+	termIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "term", Value: 1}, {Key: "day", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	// Index model expiring buckets that left the window
+	expiryIndex := mongo.IndexModel{
+		Keys:    bson.M{"expires_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	if _, err := ks.termCollection.Indexes().CreateMany(ks.ctx, []mongo.IndexModel{termIndex, expiryIndex}); err != nil {
+		return err
+	}
+
+	_, err := ks.dayCollection.Indexes().CreateOne(ks.ctx, expiryIndex)
+	return err
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+const (
+	// languageMinConfidence is the posterior a guess needs to be recorded
+	languageMinConfidence = 0.8
+
+	// textLanguageField tells the mongo text index which analyzer to use, the
+	// default language field would reject languages mongo has no stemmer for
+	textLanguageField = "text_language"
+)
+
+// textLanguages maps the languages mongo can stem to their analyzer, every
+// other language is indexed without stemming or stopwords
+var textLanguages = map[string]string{
+	"da": "danish", "de": "german", "en": "english", "es": "spanish", "fi": "finnish",
+	"fr": "french", "hu": "hungarian", "it": "italian", "nb": "norwegian", "nl": "dutch",
+	"pt": "portuguese", "ro": "romanian", "ru": "russian", "sv": "swedish", "tr": "turkish",
+}
+
+// detectLanguages sets the language of every article from its text. A guess
+// too unsure to record falls back to the language upstream reported
+func detectLanguages(articles []models.Article) {
+	for i := range articles {
+		article := &articles[i]
+
+		language, confidence := utils.DetectLanguage(article.Title + "\n" + article.Description + "\n" + article.Content)
+		if confidence < languageMinConfidence {
+			language = utils.NormalizeLanguage(article.Language)
+		}
+		article.Language = language
+		article.TextLanguage = textLanguage(language)
+	}
+}
+
+// textLanguage returns the text index analyzer for a language
+func textLanguage(language string) string {
+	if analyzer, ok := textLanguages[language]; ok {
+		return analyzer
+	}
+	if language == "" {
+		return "english"
+	}
+
+	return "none"
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+)
+
+func TestDetectLanguages(t *testing.T) {
+	tests := []struct {
+		name     string
+		article  models.Article
+		language string
+		text     string
+	}{
+		{
+			name: "confident guess wins over upstream",
+			article: models.Article{
+				Title:    "Le gouvernement annonce une hausse des dépenses de santé",
+				Content:  "Le président a déclaré aux journalistes que la nouvelle politique aidera les personnes touchées par la crise.",
+				Language: "english",
+			},
+			language: "fr",
+			text:     "french",
+		},
+		{
+			name:     "unsure guess falls back to upstream",
+			article:  models.Article{Title: "Breaking", Language: "french"},
+			language: "fr",
+			text:     "french",
+		},
+		{
+			name:     "unsure guess with an upstream tag",
+			article:  models.Article{Title: "Breaking", Language: "pt-BR"},
+			language: "pt",
+			text:     "portuguese",
+		},
+		{
+			name:     "unsure guess without upstream",
+			article:  models.Article{Title: "Breaking"},
+			language: "",
+			text:     "english",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			articles := []models.Article{tt.article}
+			detectLanguages(articles)
+
+			if articles[0].Language != tt.language {
+				t.Errorf("language = %q, want %q", articles[0].Language, tt.language)
+			}
+			if articles[0].TextLanguage != tt.text {
+				t.Errorf("text language = %q, want %q", articles[0].TextLanguage, tt.text)
+			}
+		})
+	}
+}
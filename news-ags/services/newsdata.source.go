@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/go-resty/resty/v2"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+const (
+	newsdataURL          = "https://newsdata.io/api/1/news"
+	newsdataCursorPrefix = "newsdata:cursor:"
+	newsdataCursorTTL    = time.Hour
+	// newsdataRateLimitWait is how long a key that hit the short term rate
+	// limit rests when newsdata does not say
+	newsdataRateLimitWait = 15 * time.Minute
+)
+
+var newsResponsePool = sync.Pool{
+	New: func() interface{} {
+		return &models.NewsResponse{}
+	},
+}
+
+const newsdataWorkers = 4
+
+type NewsdataSource struct {
+	cfg         models.SourceConfig
+	queries     []models.NewsdataQuery
+	keys        NewsdataKeyPool
+	pageBudget  int
+	upstream    *UpstreamClient
+	rClient     *redis.Client
+	deadLetters DeadLetterService
+}
+
+// NewNewsdataSource builds the adapter for the given plan queries, without
+// queries one query per configured category is made with the source params
+func NewNewsdataSource(cfg models.SourceConfig, queries []models.NewsdataQuery, keys NewsdataKeyPool, pageBudget int, upstream *UpstreamClient, rClient *redis.Client, deadLetters DeadLetterService) Source {
+	if cfg.URL == "" {
+		cfg.URL = newsdataURL
+	}
+	if cfg.PageBudget > 0 {
+		pageBudget = cfg.PageBudget
+	}
+	if pageBudget <= 0 {
+		pageBudget = 1
+	}
+
+	if queries == nil {
+		queries = models.ScrapePlanEntry{Categories: cfg.Categories}.Queries()
+	}
+
+	return &NewsdataSource{
+		cfg:         cfg,
+		queries:     queries,
+		keys:        keys,
+		pageBudget:  pageBudget,
+		upstream:    upstream,
+		rClient:     rClient,
+		deadLetters: deadLetters,
+	}
+}
+
+func (ns NewsdataSource) Name() string {
+	return ns.cfg.Name
+}
+
+// Fetch walks the newsdata.io pages of every query until the results run out
+// or the page budget is spent, each page costs one credit. Queries are
+// reported separately so one failing query does not discard the others
+func (ns NewsdataSource) Fetch(ctx context.Context, emit func([]models.Article) (int, error)) []models.FetchReport {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, newsdataWorkers)
+
+	reports := make([]models.FetchReport, len(ns.queries))
+	for i, q := range ns.queries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, query models.NewsdataQuery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			reports[i] = models.FetchReport{
+				Source:   ns.cfg.Name,
+				Entry:    query.Entry,
+				Category: query.Category,
+				Language: query.Language,
+				Country:  query.Country,
+				Domain:   query.Domain,
+			}
+
+			count, queued, err := ns.walkPages(ctx, query, emit)
+			reports[i].Articles, reports[i].Queued = count, queued
+			if err != nil {
+				reports[i].Error = err.Error()
+			}
+		}(i, q)
+	}
+	wg.Wait()
+
+	return reports
+}
+
+// walkPages follows nextPage for one query, the cursor is stored after every
+// emitted page so an interrupted run resumes where it stopped. It returns the
+// articles fetched and how many of them emit queued
+func (ns NewsdataSource) walkPages(ctx context.Context, query models.NewsdataQuery, emit func([]models.Article) (int, error)) (int, int, error) {
+	cursorKey := newsdataCursorPrefix + ns.cfg.Name + ":" + query.Key()
+	count, queued := 0, 0
+
+	budget := ns.pageBudget
+	if query.PageBudget > 0 {
+		budget = query.PageBudget
+	}
+
+	nextPage, err := ns.rClient.Get(cursorKey).Result()
+	if err != nil && err != redis.Nil {
+		return count, queued, err
+	}
+
+	for page := 0; page < budget; page++ {
+		articles, next, err := ns.getNews(ctx, query, nextPage)
+		if err != nil {
+			return count, queued, err
+		}
+
+		emitted, err := emit(articles)
+		count += len(articles)
+		queued += emitted
+		if err != nil {
+			return count, queued, err
+		}
+
+		// a page whose results were all dead lettered still has more behind it
+		if next == "" {
+			break
+		}
+
+		nextPage = next
+		if err := ns.rClient.Set(cursorKey, nextPage, newsdataCursorTTL).Err(); err != nil {
+			return count, queued, err
+		}
+	}
+
+	// the walk finished or the budget ran out, the next run starts from the newest page
+	return count, queued, ns.rClient.Del(cursorKey).Err()
+}
+
+func (ns NewsdataSource) getNews(ctx context.Context, query models.NewsdataQuery, nextPage string) ([]models.Article, string, error) {
+	result := newsResponsePool.Get().(*models.NewsResponse)
+	defer newsResponsePool.Put(result)
+	*result = models.NewsResponse{}
+
+	params := map[string]string{
+		"full_content": "1",
+	}
+	for key, value := range ns.cfg.Params {
+		params[key] = value
+	}
+	for key, value := range query.Params {
+		params[key] = value
+	}
+	filters := map[string]string{
+		"category": query.Category,
+		"language": query.Language,
+		"country":  query.Country,
+		"domain":   query.Domain,
+	}
+	for key, value := range filters {
+		if value != "" {
+			params[key] = value
+		}
+	}
+	if nextPage != "" {
+		params["page"] = nextPage
+	}
+
+	resp, err := ns.request(ctx, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch resp.StatusCode() {
+	case 500:
+		return nil, "", errors.New("external api failure")
+	case 415, 422:
+		return nil, "", errors.New("malformed api querry")
+	case 409:
+		return nil, "", errors.New("duplicate api querry")
+	case 403:
+		return nil, "", errors.New("corse api error")
+	case 400:
+		return nil, "", errors.New("api param missing")
+	}
+
+	if resp.IsError() {
+		return nil, "", fmt.Errorf("unexpected api status %d", resp.StatusCode())
+	}
+
+	// Unmarshal the JSON response into the struct
+	err = json.Unmarshal(resp.Body(), result)
+	if err != nil {
+		return nil, "", err
+	}
+
+	articles := make([]models.Article, 0, len(result.Results))
+	for _, raw := range result.Results {
+		article, err := ns.parse(raw, query.Category)
+		if err != nil {
+			ns.deadLetters.Capture(models.DeadLetterStageParse, ns.cfg.Name, query.Category, raw, err)
+			continue
+		}
+		articles = append(articles, article)
+	}
+
+	return articles, result.NextPage, nil
+}
+
+// parse reads a single newsdata result, articles newsdata left uncategorized
+// take the category they were queried for
+func (ns NewsdataSource) parse(raw []byte, category string) (models.Article, error) {
+	var result models.NewsdataArticle
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return models.Article{}, err
+	}
+
+	article := result.Article
+	article.Weight = newsdataWeight(result.Weight)
+	if len(article.Category) == 0 && category != "" {
+		article.Category = []string{category}
+	}
+	applySourceDefaults(&article, ns.cfg)
+
+	return article, nil
+}
+
+// newsdataWeight maps the newsdata source priority, a rank where 1 is the
+// most prominent source, onto the higher wins weights of the other sources.
+// The top rank weighs 1000 and every tenfold rank loses 100
+func newsdataWeight(priority int) int {
+	if priority <= 0 {
+		return 0
+	}
+
+	return max(1, 1000-int(math.Round(100*math.Log10(float64(priority)))))
+}
+
+// Reparse reads a newsdata result captured as a dead letter again
+func (ns NewsdataSource) Reparse(letter models.DeadLetter) ([]models.Article, error) {
+	article, err := ns.parse([]byte(letter.Payload), letter.Category)
+	if err != nil {
+		return nil, err
+	}
+
+	return []models.Article{article}, nil
+}
+
+// request sends the query with the first key that has credits left. Keys that
+// run out or are rejected are set aside and the query is retried with the
+// next one, a failed request gives its credit back. Every key has its own
+// circuit breaker so one spent key does not stop the others
+func (ns NewsdataSource) request(ctx context.Context, params map[string]string) (*resty.Response, error) {
+	for {
+		key, day, err := ns.keys.Acquire()
+		if err != nil {
+			return nil, err
+		}
+		params["apiKey"] = key.Value
+
+		resp, err := ns.upstream.GetKeyed(ctx, ns.cfg.Name+":"+key.Id, ns.cfg.URL, params)
+		if err != nil || resp.IsError() {
+			if err := ns.keys.Refund(key, day); err != nil {
+				utils.LogErrorToFile("refund newsdata credit", err.Error())
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch resp.StatusCode() {
+		case 429:
+			err = ns.rotateKey(key, resp)
+		case 401:
+			utils.LogErrorToFile("newsdata key "+key.Masked(), "rejected as incorrect, skipped until tomorrow")
+			err = ns.keys.Exhaust(key)
+		default:
+			return resp, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rotateKey sets aside a key newsdata throttled. The short term rate limit
+// only rests the key, anything else means its daily credits are spent
+func (ns NewsdataSource) rotateKey(key NewsdataKey, resp *resty.Response) error {
+	var body struct {
+		Results struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"results"`
+	}
+	_ = json.Unmarshal(resp.Body(), &body)
+
+	if strings.Contains(strings.ToLower(body.Results.Code), "ratelimit") {
+		wait, ok := parseRetryAfter(resp.Header().Get("Retry-After"))
+		if !ok || wait <= 0 {
+			wait = newsdataRateLimitWait
+		}
+		return ns.keys.Cooldown(key, wait)
+	}
+
+	return ns.keys.Exhaust(key)
+}
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/newsdatafake"
+)
+
+// fixtureArticles is the number of distinct articles in testdata/newsdata
+const fixtureArticles = 28
+
+// recordedLetters keeps the dead letters a test captures
+type recordedLetters struct {
+	DeadLetterService
+	mu      sync.Mutex
+	letters []models.DeadLetter
+}
+
+func (rl *recordedLetters) Capture(stage, source, category string, payload []byte, reason error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.letters = append(rl.letters, models.DeadLetter{Stage: stage, Source: source, Category: category, Payload: string(payload), Reason: reason.Error()})
+}
+
+type newsdataHarness struct {
+	fake    *newsdatafake.Server
+	keys    []NewsdataKey
+	pool    NewsdataKeyPool
+	client  *redis.Client
+	letters *recordedLetters
+}
+
+func newNewsdataHarness(t *testing.T, dir string, opts newsdatafake.Options, keys string) *newsdataHarness {
+	t.Helper()
+
+	fake, err := newsdatafake.NewServer(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(fake.Close)
+
+	_, client := newTestRedis(t)
+	parsed, err := ParseNewsdataKeys(keys, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &newsdataHarness{
+		fake:    fake,
+		keys:    parsed,
+		pool:    NewNewsdataKeyPool(client, parsed),
+		client:  client,
+		letters: &recordedLetters{},
+	}
+}
+
+func (h *newsdataHarness) source(queries []models.NewsdataQuery, pageBudget int) Source {
+	upstream := NewUpstreamClient(UpstreamOptions{
+		MaxRetries:       2,
+		BaseDelay:        time.Millisecond,
+		MaxDelay:         5 * time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+	})
+	cfg := models.SourceConfig{Name: "newsdata", Type: "newsdata", URL: h.fake.NewsURL(), Enabled: true}
+
+	return NewNewsdataSource(cfg, queries, h.pool, pageBudget, upstream, h.client, h.letters)
+}
+
+func (h *newsdataHarness) used(t *testing.T, key int) int {
+	t.Helper()
+
+	quota, err := h.pool.Quota()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return quota.Keys[key].Used
+}
+
+// collect fetches with an emit that queues every article
+func collect(src Source) ([]models.Article, []models.FetchReport) {
+	var (
+		mu       sync.Mutex
+		articles []models.Article
+	)
+	reports := src.Fetch(context.Background(), func(batch []models.Article) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		articles = append(articles, batch...)
+		return len(batch), nil
+	})
+
+	return articles, reports
+}
+
+func TestNewsdataFetchFollowsNextPage(t *testing.T) {
+	h := newNewsdataHarness(t, "../testdata/newsdata", newsdatafake.Options{PageSize: 10}, "first-key-0001")
+
+	articles, reports := collect(h.source(nil, 5))
+
+	if len(reports) != 1 || reports[0].Error != "" {
+		t.Fatalf("reports = %+v, want one without error", reports)
+	}
+	if len(articles) != fixtureArticles || reports[0].Articles != fixtureArticles || reports[0].Queued != fixtureArticles {
+		t.Errorf("fetched %d articles, report %+v, want %d", len(articles), reports[0], fixtureArticles)
+	}
+	if got := h.fake.Requests(); got != 3 {
+		t.Errorf("requests = %d, want 3 pages", got)
+	}
+	if got := h.used(t, 0); got != 3 {
+		t.Errorf("credits used = %d, want 3", got)
+	}
+
+	seen := make(map[string]bool)
+	for _, article := range articles {
+		if seen[article.URL] {
+			t.Errorf("article %s fetched twice", article.URL)
+		}
+		seen[article.URL] = true
+		if article.Id.IsZero() {
+			t.Errorf("article %s has no id", article.URL)
+		}
+	}
+}
+
+func TestNewsdataFetchWalksPastUnparseablePage(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"page-1.json", "page-2.json", "page-3.json"} {
+		data, err := os.ReadFile(filepath.Join("../testdata/newsdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first page is made of results that fail to parse
+	broken := make([]string, 0, 10)
+	for i := 0; i < 10; i++ {
+		broken = append(broken, fmt.Sprintf(`{"article_id": "broken-%d", "title": %d}`, i, i))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "page-0.json"), []byte(`{"results": [`+strings.Join(broken, ",")+`]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newNewsdataHarness(t, dir, newsdatafake.Options{PageSize: 10}, "first-key-0001")
+	articles, reports := collect(h.source(nil, 5))
+
+	if len(reports) != 1 || reports[0].Error != "" {
+		t.Fatalf("reports = %+v, want one without error", reports)
+	}
+	if len(articles) != fixtureArticles {
+		t.Errorf("fetched %d articles, want the %d behind the unparseable page", len(articles), fixtureArticles)
+	}
+	if got := h.fake.Requests(); got != 4 {
+		t.Errorf("requests = %d, want 4 pages", got)
+	}
+	if got := len(h.letters.letters); got != 10 {
+		t.Errorf("dead letters = %d, want the 10 unparseable results", got)
+	}
+}
+
+func TestNewsdataFetchResumesFromCursor(t *testing.T) {
+	h := newNewsdataHarness(t, "../testdata/newsdata", newsdatafake.Options{PageSize: 10}, "first-key-0001")
+	src := h.source(nil, 5)
+
+	// the stream goes away after the first page
+	var first []models.Article
+	pages := 0
+	reports := src.Fetch(context.Background(), func(batch []models.Article) (int, error) {
+		pages++
+		if pages > 1 {
+			return 0, errors.New("stream unavailable")
+		}
+		first = append(first, batch...)
+		return len(batch), nil
+	})
+	if reports[0].Error != "stream unavailable" || reports[0].Queued != 10 {
+		t.Fatalf("interrupted report = %+v, want 10 queued and the emit error", reports[0])
+	}
+
+	second, reports := collect(src)
+	if reports[0].Error != "" || len(second) != fixtureArticles-10 {
+		t.Fatalf("resumed run fetched %d articles, report %+v, want the %d after the first page", len(second), reports[0], fixtureArticles-10)
+	}
+	for _, article := range second {
+		for _, seen := range first {
+			if article.URL == seen.URL {
+				t.Fatalf("resumed run fetched %s again", article.URL)
+			}
+		}
+	}
+
+	// the walk finished so the next run starts from the newest page
+	third, _ := collect(src)
+	if len(third) != fixtureArticles || third[0].URL != first[0].URL {
+		t.Fatalf("next run fetched %d articles starting at %s, want all from the first page", len(third), third[0].URL)
+	}
+}
+
+func TestNewsdataFetchStopsAtPageBudget(t *testing.T) {
+	h := newNewsdataHarness(t, "../testdata/newsdata", newsdatafake.Options{PageSize: 10}, "first-key-0001")
+
+	articles, reports := collect(h.source(nil, 2))
+
+	if reports[0].Error != "" || len(articles) != 20 {
+		t.Fatalf("fetched %d articles, report %+v, want two pages", len(articles), reports[0])
+	}
+	if got := h.used(t, 0); got != 2 {
+		t.Errorf("credits used = %d, want 2", got)
+	}
+}
+
+func TestNewsdataFetchFilters(t *testing.T) {
+	h := newNewsdataHarness(t, "../testdata/newsdata", newsdatafake.Options{}, "first-key-0001")
+	queries := []models.NewsdataQuery{{Category: "business"}, {Language: "fr"}}
+
+	_, reports := collect(h.source(queries, 5))
+
+	want := []int{5, 3}
+	for i, report := range reports {
+		if report.Error != "" || report.Articles != want[i] {
+			t.Errorf("query %d report = %+v, want %d articles", i, report, want[i])
+		}
+	}
+}
+
+func TestNewsdataRotatesRejectedKey(t *testing.T) {
+	h := newNewsdataHarness(t, "../testdata/newsdata", newsdatafake.Options{
+		Keys:     []string{"second-key-0002"},
+		PageSize: 50,
+	}, "first-key-0001,second-key-0002")
+
+	articles, reports := collect(h.source(nil, 5))
+
+	if reports[0].Error != "" || len(articles) != fixtureArticles {
+		t.Fatalf("report = %+v, want every article through the second key", reports[0])
+	}
+	if got := h.fake.Used("second-key-0002"); got != 1 {
+		t.Errorf("second key used %d credits upstream, want 1", got)
+	}
+
+	quota, _ := h.pool.Quota()
+	if !quota.Keys[0].Exhausted {
+		t.Errorf("rejected key not set aside, quota %+v", quota.Keys[0])
+	}
+	if quota.Keys[1].Used != 1 {
+		t.Errorf("second key counted %d credits, want 1", quota.Keys[1].Used)
+	}
+}
+
+func TestNewsdataRotatesRateLimitedKey(t *testing.T) {
+	h := newNewsdataHarness(t, "../testdata/newsdata", newsdatafake.Options{PageSize: 50}, "first-key-0001,second-key-0002")
+	h.fake.Fail(429, 1)
+
+	articles, reports := collect(h.source(nil, 5))
+
+	if reports[0].Error != "" || len(articles) != fixtureArticles {
+		t.Fatalf("report = %+v, want every article through the second key", reports[0])
+	}
+	// the 429 goes straight to rotation, it is not retried on the same key
+	if got := h.fake.Requests(); got != 2 {
+		t.Errorf("requests = %d, want the throttled one and one on the next key", got)
+	}
+
+	quota, _ := h.pool.Quota()
+	if quota.Keys[0].CoolingUntil == nil || quota.Keys[0].Exhausted || quota.Keys[0].Used != 0 {
+		t.Errorf("throttled key = %+v, want it cooling with its credit refunded", quota.Keys[0])
+	}
+	if quota.Keys[1].Used != 1 {
+		t.Errorf("second key counted %d credits, want 1", quota.Keys[1].Used)
+	}
+}
+
+func TestNewsdataRotatesSpentKey(t *testing.T) {
+	h := newNewsdataHarness(t, "../testdata/newsdata", newsdatafake.Options{Credits: 1, PageSize: 10}, "first-key-0001,second-key-0002")
+
+	articles, reports := collect(h.source(nil, 2))
+
+	if reports[0].Error != "" || len(articles) != 20 {
+		t.Fatalf("report = %+v, want two pages", reports[0])
+	}
+	if got := h.fake.Requests(); got != 3 {
+		t.Errorf("requests = %d, want the spent key tried once and not retried", got)
+	}
+
+	quota, _ := h.pool.Quota()
+	if !quota.Keys[0].Exhausted {
+		t.Errorf("spent key not exhausted, quota %+v", quota.Keys[0])
+	}
+	if h.fake.Used("first-key-0001") != 1 || h.fake.Used("second-key-0002") != 1 {
+		t.Errorf("upstream credits = %d and %d, want one on each key", h.fake.Used("first-key-0001"), h.fake.Used("second-key-0002"))
+	}
+}
+
+func TestNewsdataBreakerOpensOnServerErrors(t *testing.T) {
+	h := newNewsdataHarness(t, "../testdata/newsdata", newsdatafake.Options{}, "first-key-0001")
+	h.fake.Fail(500, 100)
+	src := h.source(nil, 5)
+
+	for run := 1; run <= 2; run++ {
+		_, reports := collect(src)
+		if reports[0].Error != "external api failure" {
+			t.Fatalf("run %d error = %q, want the api failure", run, reports[0].Error)
+		}
+	}
+	// one request and two retries per run
+	if got := h.fake.Requests(); got != 6 {
+		t.Errorf("requests = %d, want 6", got)
+	}
+
+	_, reports := collect(src)
+	if !strings.Contains(reports[0].Error, ErrCircuitOpen.Error()) {
+		t.Errorf("third run error = %q, want the breaker open", reports[0].Error)
+	}
+	if got := h.fake.Requests(); got != 6 {
+		t.Errorf("requests = %d after the breaker opened, want no more", got)
+	}
+	if got := h.used(t, 0); got != 0 {
+		t.Errorf("credits used = %d, want failed requests refunded", got)
+	}
+}
+
+func TestNewsdataCapturesMalformedArticle(t *testing.T) {
+	dir := t.TempDir()
+	fixture := `{"status":"success","results":[
+		{"article_id":"a1","title":"Readable article","link":"https://example.com/a1","pubDate":"2023-11-20 10:00:00","category":["top"]},
+		{"article_id":"a2","title":"Broken article","link":"https://example.com/a2","creator":42,"category":["top"]}
+	]}`
+	if err := os.WriteFile(filepath.Join(dir, "page.json"), []byte(fixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := newNewsdataHarness(t, dir, newsdatafake.Options{}, "first-key-0001")
+	src := h.source(nil, 5)
+
+	articles, reports := collect(src)
+
+	if reports[0].Error != "" || len(articles) != 1 || articles[0].URL != "https://example.com/a1" {
+		t.Fatalf("report = %+v with %d articles, want the readable one kept", reports[0], len(articles))
+	}
+	if len(h.letters.letters) != 1 || h.letters.letters[0].Stage != models.DeadLetterStageParse {
+		t.Fatalf("dead letters = %+v, want the broken article", h.letters.letters)
+	}
+	if !strings.Contains(h.letters.letters[0].Payload, `"a2"`) {
+		t.Errorf("dead letter payload = %s, want the broken article", h.letters.letters[0].Payload)
+	}
+}
+
+func TestNewsdataWeightPrefersProminentSources(t *testing.T) {
+	tests := []struct {
+		priority int
+		weight   int
+	}{
+		{priority: 0, weight: 0},
+		{priority: 1, weight: 1000},
+		{priority: 120, weight: 792},
+		{priority: 12000, weight: 592},
+		{priority: 1e12, weight: 1},
+	}
+	for _, tt := range tests {
+		if got := newsdataWeight(tt.priority); got != tt.weight {
+			t.Errorf("newsdataWeight(%d) = %d, want %d", tt.priority, got, tt.weight)
+		}
+	}
+
+	// a reuters copy beats the same story from a lower ranked site
+	prominent := models.Article{Weight: newsdataWeight(120), Content: "short"}
+	obscure := models.Article{Weight: newsdataWeight(250000), Content: "a much longer body of the same story"}
+	if !preferArticle(prominent, obscure) || preferArticle(obscure, prominent) {
+		t.Error("preferArticle kept the copy from the lower ranked source")
+	}
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	scrapePlanKey     = "scrape:plan"
+	scrapePlanRunsKey = "scrape:plan:runs"
+)
+
+var (
+	ErrInvalidPlan   = errors.New("invalid scrape plan")
+	ErrEntryNotFound = errors.New("scrape plan entry not found")
+)
+
+// ScrapePlanService keeps the newsdata scrape plan in redis so every replica
+// sees runtime edits, the plan file only seeds it on first start
+type ScrapePlanService interface {
+	Plan() (*models.ScrapePlan, error)
+	Replace(plan models.ScrapePlan) (*models.ScrapePlan, error)
+	PutEntry(entry models.ScrapePlanEntry) (*models.ScrapePlan, error)
+	DeleteEntry(name string) (*models.ScrapePlan, error)
+	Due(now time.Time) ([]models.ScrapePlanEntry, error)
+	MarkRun(name string, at time.Time) error
+}
+
+type ScrapePlanServiceImp struct {
+	rClient *redis.Client
+	path    string
+}
+
+func NewScrapePlanService(client *redis.Client, path string) ScrapePlanService {
+	return &ScrapePlanServiceImp{
+		rClient: client,
+		path:    path,
+	}
+}
+
+// Plan returns the current plan, seeding it from the plan file when redis has none
+func (ps ScrapePlanServiceImp) Plan() (*models.ScrapePlan, error) {
+	plan, err := ps.load(ps.rClient.Get(scrapePlanKey))
+	if err != redis.Nil {
+		return plan, err
+	}
+
+	plan, err = ps.readFile()
+	if err != nil {
+		return nil, err
+	}
+
+	planJSON, err := json.Marshal(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	// another replica may have seeded or edited the plan in the meantime
+	stored, err := ps.rClient.SetNX(scrapePlanKey, planJSON, 0).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		return ps.load(ps.rClient.Get(scrapePlanKey))
+	}
+
+	return plan, nil
+}
+
+func (ps ScrapePlanServiceImp) Replace(plan models.ScrapePlan) (*models.ScrapePlan, error) {
+	if err := validatePlan(plan); err != nil {
+		return nil, err
+	}
+
+	plan.UpdatedAt = time.Now().UTC()
+	planJSON, err := json.Marshal(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ps.rClient.Set(scrapePlanKey, planJSON, 0).Err(); err != nil {
+		return nil, err
+	}
+
+	return &plan, nil
+}
+
+// PutEntry adds the entry or replaces the one with the same name
+func (ps ScrapePlanServiceImp) PutEntry(entry models.ScrapePlanEntry) (*models.ScrapePlan, error) {
+	return ps.update(func(plan *models.ScrapePlan) error {
+		for i := range plan.Entries {
+			if plan.Entries[i].Name == entry.Name {
+				plan.Entries[i] = entry
+				return nil
+			}
+		}
+
+		plan.Entries = append(plan.Entries, entry)
+		return nil
+	})
+}
+
+func (ps ScrapePlanServiceImp) DeleteEntry(name string) (*models.ScrapePlan, error) {
+	return ps.update(func(plan *models.ScrapePlan) error {
+		for i := range plan.Entries {
+			if plan.Entries[i].Name == name {
+				plan.Entries = append(plan.Entries[:i], plan.Entries[i+1:]...)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%w: %s", ErrEntryNotFound, name)
+	})
+}
+
+// Due returns the enabled entries with their own schedule whose next fire time
+// since their last run has passed, entries that never ran are due at once
+func (ps ScrapePlanServiceImp) Due(now time.Time) ([]models.ScrapePlanEntry, error) {
+	plan, err := ps.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := ps.rClient.HGetAll(scrapePlanRunsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	due := make([]models.ScrapePlanEntry, 0)
+	for _, entry := range plan.Entries {
+		if !entry.Enabled || entry.Schedule == "" {
+			continue
+		}
+
+		schedule, err := cron.ParseStandard(entry.Schedule)
+		if err != nil {
+			continue
+		}
+
+		lastRun, err := time.Parse(time.RFC3339, runs[entry.Name])
+		if err != nil || !schedule.Next(lastRun).After(now) {
+			due = append(due, entry)
+		}
+	}
+
+	return due, nil
+}
+
+func (ps ScrapePlanServiceImp) MarkRun(name string, at time.Time) error {
+	return ps.rClient.HSet(scrapePlanRunsKey, name, at.UTC().Format(time.RFC3339)).Err()
+}
+
+// update applies a change to the stored plan, retrying when another replica
+// wrote the plan between the read and the write
+func (ps ScrapePlanServiceImp) update(change func(*models.ScrapePlan) error) (*models.ScrapePlan, error) {
+	if _, err := ps.Plan(); err != nil {
+		return nil, err
+	}
+
+	var plan *models.ScrapePlan
+	txf := func(tx *redis.Tx) error {
+		current, err := ps.load(tx.Get(scrapePlanKey))
+		if err != nil {
+			return err
+		}
+
+		if err := change(current); err != nil {
+			return err
+		}
+		if err := validatePlan(*current); err != nil {
+			return err
+		}
+
+		current.UpdatedAt = time.Now().UTC()
+		planJSON, err := json.Marshal(current)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(func(pipe redis.Pipeliner) error {
+			pipe.Set(scrapePlanKey, planJSON, 0)
+			return nil
+		})
+		plan = current
+		return err
+	}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		err := ps.rClient.Watch(txf, scrapePlanKey)
+		if err == redis.TxFailedErr {
+			continue
+		}
+		return plan, err
+	}
+
+	return nil, errors.New("scrape plan is being edited concurrently, try again")
+}
+
+func (ps ScrapePlanServiceImp) load(cmd *redis.StringCmd) (*models.ScrapePlan, error) {
+	planJSON, err := cmd.Result()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &models.ScrapePlan{}
+	if err := json.Unmarshal([]byte(planJSON), plan); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// readFile reads the seed plan, a missing file gives an empty plan so
+// newsdata sources fall back to their own categories and params
+func (ps ScrapePlanServiceImp) readFile() (*models.ScrapePlan, error) {
+	plan := &models.ScrapePlan{Entries: []models.ScrapePlanEntry{}}
+
+	data, err := os.ReadFile(ps.path)
+	if os.IsNotExist(err) {
+		return plan, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading scrape plan file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("error parsing scrape plan file: %w", err)
+	}
+	if err := validatePlan(*plan); err != nil {
+		return nil, err
+	}
+	plan.UpdatedAt = time.Now().UTC()
+
+	return plan, nil
+}
+
+func validatePlan(plan models.ScrapePlan) error {
+	names := make(map[string]bool)
+	for _, entry := range plan.Entries {
+		if entry.Name == "" {
+			return fmt.Errorf("%w: entry without a name", ErrInvalidPlan)
+		}
+		if names[entry.Name] {
+			return fmt.Errorf("%w: duplicate entry %s", ErrInvalidPlan, entry.Name)
+		}
+		names[entry.Name] = true
+
+		if entry.Schedule != "" {
+			if _, err := cron.ParseStandard(entry.Schedule); err != nil {
+				return fmt.Errorf("%w: entry %s has a bad schedule: %s", ErrInvalidPlan, entry.Name, err)
+			}
+		}
+		if entry.PageBudget < 0 {
+			return fmt.Errorf("%w: entry %s has a negative page budget", ErrInvalidPlan, entry.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+)
+
+func entryNames(plan *models.ScrapePlan) []string {
+	names := make([]string, 0, len(plan.Entries))
+	for _, entry := range plan.Entries {
+		names = append(names, entry.Name)
+	}
+
+	return names
+}
+
+func TestScrapePlanSeedsFromFile(t *testing.T) {
+	_, client := newTestRedis(t)
+	data, err := os.ReadFile("../scrape-plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "scrape-plan.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plans := NewScrapePlanService(client, path)
+
+	plan, err := plans.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(entryNames(plan), ","); names != "global-en,west-africa" {
+		t.Fatalf("seeded entries %s, want the plan file", names)
+	}
+	if plan.Entries[1].Schedule != "0 * * * *" || plan.Entries[1].PageBudget != 2 || plan.UpdatedAt.IsZero() {
+		t.Errorf("seeded %+v, want the file entry with its schedule and budget", plan.Entries[1])
+	}
+
+	// redis owns the plan once seeded, the file is not read again
+	if err := os.WriteFile(path, []byte(`{"entries": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = plans.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Entries) != 2 {
+		t.Errorf("plan has %d entries after the file changed, want the stored 2", len(plan.Entries))
+	}
+}
+
+func TestScrapePlanSeedKeepsStoredPlan(t *testing.T) {
+	_, client := newTestRedis(t)
+	stored, _ := json.Marshal(models.ScrapePlan{Entries: []models.ScrapePlanEntry{{Name: "edited"}}})
+	client.Set(scrapePlanKey, stored, 0)
+
+	plan, err := NewScrapePlanService(client, "../scrape-plan.json").Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := entryNames(plan); len(names) != 1 || names[0] != "edited" {
+		t.Errorf("entries %q, want the stored plan over the file", names)
+	}
+}
+
+func TestScrapePlanSeedFile(t *testing.T) {
+	dir := t.TempDir()
+
+	_, client := newTestRedis(t)
+	plan, err := NewScrapePlanService(client, filepath.Join(dir, "missing.json")).Plan()
+	if err != nil || len(plan.Entries) != 0 {
+		t.Errorf("Plan() = %+v, %v, want an empty plan without a file", plan, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`{"entries": [{"name": "a"}, {"name": "a"}]}`), 0o644)
+	_, client = newTestRedis(t)
+	if _, err := NewScrapePlanService(client, invalid).Plan(); !errors.Is(err, ErrInvalidPlan) {
+		t.Errorf("err = %v, want an invalid plan", err)
+	}
+	if exists, _ := client.Exists(scrapePlanKey).Result(); exists != 0 {
+		t.Error("an invalid plan file was stored")
+	}
+}
+
+func TestScrapePlanEditEntries(t *testing.T) {
+	_, client := newTestRedis(t)
+	plans := NewScrapePlanService(client, "../scrape-plan.json")
+
+	plan, err := plans.PutEntry(models.ScrapePlanEntry{Name: "west-africa", Enabled: true, Schedule: "*/30 * * * *"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Entries) != 2 || !plan.Entries[1].Enabled || plan.Entries[1].Schedule != "*/30 * * * *" {
+		t.Errorf("entries %+v, want west-africa replaced in place", plan.Entries)
+	}
+
+	plan, err = plans.PutEntry(models.ScrapePlanEntry{Name: "east-africa", Countries: []string{"ke"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(entryNames(plan), ","); names != "global-en,west-africa,east-africa" {
+		t.Errorf("entries %s, want east-africa appended", names)
+	}
+
+	if _, err := plans.PutEntry(models.ScrapePlanEntry{Name: "broken", Schedule: "every hour"}); !errors.Is(err, ErrInvalidPlan) {
+		t.Errorf("err = %v, want a bad schedule rejected", err)
+	}
+	if _, err := plans.DeleteEntry("missing"); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("err = %v, want a missing entry", err)
+	}
+
+	if _, err := plans.DeleteEntry("global-en"); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = plans.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(entryNames(plan), ","); names != "west-africa,east-africa" {
+		t.Errorf("stored entries %s, want the edits kept", names)
+	}
+}
+
+func TestScrapePlanUpdateRetriesConcurrentWrite(t *testing.T) {
+	_, client := newTestRedis(t)
+	plans := NewScrapePlanService(client, "../scrape-plan.json").(*ScrapePlanServiceImp)
+
+	// another replica adds an entry between the read and the write
+	attempts := 0
+	plan, err := plans.update(func(plan *models.ScrapePlan) error {
+		attempts++
+		if attempts == 1 {
+			other := *plan
+			other.Entries = append(append([]models.ScrapePlanEntry{}, plan.Entries...), models.ScrapePlanEntry{Name: "replica"})
+			otherJSON, _ := json.Marshal(other)
+			if err := client.Set(scrapePlanKey, otherJSON, 0).Err(); err != nil {
+				return err
+			}
+		}
+		plan.Entries = append(plan.Entries, models.ScrapePlanEntry{Name: "local"})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("change applied %d times, want a retry after the conflict", attempts)
+	}
+	if names := strings.Join(entryNames(plan), ","); names != "global-en,west-africa,replica,local" {
+		t.Errorf("entries %s, want both edits", names)
+	}
+
+	stored, err := plans.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Entries) != 4 {
+		t.Errorf("stored %d entries, want 4", len(stored.Entries))
+	}
+}
+
+func TestScrapePlanUpdateGivesUpOnConstantConflicts(t *testing.T) {
+	_, client := newTestRedis(t)
+	plans := NewScrapePlanService(client, "../scrape-plan.json").(*ScrapePlanServiceImp)
+
+	attempts := 0
+	_, err := plans.update(func(plan *models.ScrapePlan) error {
+		attempts++
+		return client.Set(scrapePlanKey, client.Get(scrapePlanKey).Val(), 0).Err()
+	})
+	if err == nil || !strings.Contains(err.Error(), "concurrently") || attempts != 5 {
+		t.Errorf("err = %v after %d attempts, want a conflict error after 5", err, attempts)
+	}
+}
+
+func TestScrapePlanDue(t *testing.T) {
+	_, client := newTestRedis(t)
+	plans := NewScrapePlanService(client, filepath.Join(t.TempDir(), "missing.json"))
+	_, err := plans.Replace(models.ScrapePlan{Entries: []models.ScrapePlanEntry{
+		{Name: "never-ran", Enabled: true, Schedule: "0 * * * *"},
+		{Name: "ran-this-hour", Enabled: true, Schedule: "0 * * * *"},
+		{Name: "ran-last-hour", Enabled: true, Schedule: "0 * * * *"},
+		{Name: "disabled", Enabled: false, Schedule: "0 * * * *"},
+		{Name: "global", Enabled: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 9, 2, 10, 30, 0, 0, time.UTC)
+	if err := plans.MarkRun("ran-this-hour", now.Add(-25*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := plans.MarkRun("ran-last-hour", now.Add(-90*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := plans.MarkRun("disabled", now.Add(-90*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	due, err := plans.Due(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(entryNames(&models.ScrapePlan{Entries: due}), ","); names != "never-ran,ran-last-hour" {
+		t.Errorf("due %s, want never-ran,ran-last-hour", names)
+	}
+
+	// a run at the fire time itself is not repeated until the next one
+	if err := plans.MarkRun("ran-last-hour", time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if err := plans.MarkRun("never-ran", now); err != nil {
+		t.Fatal(err)
+	}
+	due, err = plans.Due(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 0 {
+		t.Errorf("due %+v after every entry ran, want none", due)
+	}
+	if due, _ := plans.Due(now.Add(31 * time.Minute)); len(due) != 3 {
+		t.Errorf("due %+v at the next hour, want the three scheduled entries", due)
+	}
+}
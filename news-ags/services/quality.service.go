@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-redis/redis"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const qualityRulesKey = "quality:rules"
+
+var ErrInvalidRules = errors.New("invalid quality rules")
+
+// QualityService is the gate articles pass before they are written to mongo.
+// The rules live in redis so edits reach every replica with the next batch,
+// the rules file only seeds them on first start
+type QualityService interface {
+	Rules() (*models.QualityRules, error)
+	Replace(rules models.QualityRules) (*models.QualityRules, error)
+	Check(articles []models.Article) ([]models.Article, []models.QualityRejection, error)
+}
+
+type QualityServiceImp struct {
+	ctx               context.Context
+	rClient           *redis.Client
+	articleCollection *mongo.Collection
+	path              string
+}
+
+func NewQualityService(ctx context.Context, client *redis.Client, articles *mongo.Collection, path string) QualityService {
+	return &QualityServiceImp{
+		ctx:               ctx,
+		rClient:           client,
+		articleCollection: articles,
+		path:              path,
+	}
+}
+
+// Rules returns the current rules, seeding them from the rules file when redis has none
+func (qs QualityServiceImp) Rules() (*models.QualityRules, error) {
+	rules, err := qs.load()
+	if err != redis.Nil {
+		return rules, err
+	}
+
+	rules, err = qs.readFile()
+	if err != nil {
+		return nil, err
+	}
+
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	// another replica may have seeded or edited the rules in the meantime
+	stored, err := qs.rClient.SetNX(qualityRulesKey, rulesJSON, 0).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		return qs.load()
+	}
+
+	return rules, nil
+}
+
+func (qs QualityServiceImp) Replace(rules models.QualityRules) (*models.QualityRules, error) {
+	if err := validateRules(rules); err != nil {
+		return nil, err
+	}
+
+	rules.UpdatedAt = time.Now().UTC()
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := qs.rClient.Set(qualityRulesKey, rulesJSON, 0).Err(); err != nil {
+		return nil, err
+	}
+
+	return &rules, nil
+}
+
+// Check splits the batch into the articles to save and the ones the rules
+// reject, each rejection carries the first rule the article broke
+func (qs QualityServiceImp) Check(articles []models.Article) ([]models.Article, []models.QualityRejection, error) {
+	rules, err := qs.Rules()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return qs.check(*rules, articles)
+}
+
+func (qs QualityServiceImp) check(rules models.QualityRules, articles []models.Article) ([]models.Article, []models.QualityRejection, error) {
+	boilerplate := utils.NewPhraseMatcher(rules.Boilerplate)
+	keywords := utils.NewPhraseMatcher(rules.BlockedKeywords)
+	now := time.Now()
+
+	kept := make([]models.Article, 0, len(articles))
+	rejected := make([]models.QualityRejection, 0)
+	for _, article := range articles {
+		reason := ""
+		switch missing := missingField(article, rules.RequiredFields); {
+		case missing != "":
+			reason = models.RejectMissingPrefix + missing
+		case boilerplate.Match(article.Content) && contentLength(boilerplate.Strip(article.Content)) < max(rules.MinContentLength, 1):
+			reason = models.RejectBoilerplate
+		case contentLength(article.Content) < rules.MinContentLength:
+			reason = models.RejectContentShort
+		case utils.MatchDomain(article.URL, rules.BlockedDomains):
+			reason = models.RejectBlockedDomain
+		case keywords.Match(article.Title + "\n" + article.Description + "\n" + article.Content):
+			reason = models.RejectBlockedKeyword
+		case rules.MaxAgeHours > 0 && article.PublishedAt.Before(now.Add(-time.Duration(rules.MaxAgeHours)*time.Hour)):
+			reason = models.RejectTooOld
+		}
+
+		if reason != "" {
+			rejected = append(rejected, models.QualityRejection{Article: article, Reason: reason})
+			continue
+		}
+		kept = append(kept, article)
+	}
+
+	if rules.DuplicateTitleHours > 0 {
+		window := now.Add(-time.Duration(rules.DuplicateTitleHours) * time.Hour)
+		var (
+			duplicates []models.QualityRejection
+			err        error
+		)
+		kept, duplicates, err = qs.guardTitles(kept, window)
+		if err != nil {
+			return nil, nil, err
+		}
+		rejected = append(rejected, duplicates...)
+	}
+
+	return kept, rejected, nil
+}
+
+// ReplayQualityService applies the quality rules to recorded responses, which
+// are older than any age limit so it is left out
+type ReplayQualityService struct {
+	QualityServiceImp
+}
+
+func NewReplayQualityService(ctx context.Context, client *redis.Client, articles *mongo.Collection, path string) QualityService {
+	return &ReplayQualityService{QualityServiceImp{
+		ctx:               ctx,
+		rClient:           client,
+		articleCollection: articles,
+		path:              path,
+	}}
+}
+
+func (rq ReplayQualityService) Check(articles []models.Article) ([]models.Article, []models.QualityRejection, error) {
+	rules, err := rq.Rules()
+	if err != nil {
+		return nil, nil, err
+	}
+	rules.MaxAgeHours = 0
+
+	return rq.check(*rules, articles)
+}
+
+// guardTitles rejects articles whose title an article at another url already
+// carries, in the batch or in mongo since the window start. The same article
+// seen again keeps its title
+func (qs QualityServiceImp) guardTitles(articles []models.Article, window time.Time) ([]models.Article, []models.QualityRejection, error) {
+	keys := make([]string, 0, len(articles))
+	for _, article := range articles {
+		if article.TitleKey != "" {
+			keys = append(keys, article.TitleKey)
+		}
+	}
+	if len(keys) == 0 {
+		return articles, nil, nil
+	}
+
+	projection := options.Find().SetProjection(bson.M{"title_key": 1, "canonical_key": 1, "source_priority": 1})
+	cursor, err := qs.articleCollection.Find(qs.ctx, bson.M{
+		"title_key":    bson.M{"$in": keys},
+		"published_at": bson.M{"$gte": window},
+	}, projection)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(qs.ctx)
+
+	var persisted []models.Article
+	if err := cursor.All(qs.ctx, &persisted); err != nil {
+		return nil, nil, err
+	}
+
+	kept, rejected := keepTitleOwners(articles, persisted)
+	return kept, rejected, nil
+}
+
+// keepTitleOwners splits the articles into the ones free to use their title
+// and the ones repeating a title the persisted articles or an earlier article
+// of the batch already own
+func keepTitleOwners(articles, persisted []models.Article) ([]models.Article, []models.QualityRejection) {
+	owners := make(map[string][]models.Article, len(persisted))
+	for _, article := range persisted {
+		owners[article.TitleKey] = append(owners[article.TitleKey], article)
+	}
+
+	kept := make([]models.Article, 0, len(articles))
+	rejected := make([]models.QualityRejection, 0)
+	for _, article := range articles {
+		if article.TitleKey == "" {
+			kept = append(kept, article)
+			continue
+		}
+
+		taken := owners[article.TitleKey]
+		if titleTaken(article, taken) {
+			rejected = append(rejected, models.QualityRejection{Article: article, Reason: models.RejectDuplicateTitle})
+			continue
+		}
+
+		owners[article.TitleKey] = append(taken, article)
+		kept = append(kept, article)
+	}
+
+	return kept, rejected
+}
+
+// titleTaken tells whether an owner at another url keeps the title from the
+// article. A copy from a source weighing more than every owner is let through
+// so the near duplicate check can prefer it over the stored copy
+func titleTaken(article models.Article, owners []models.Article) bool {
+	for _, owner := range owners {
+		if owner.CanonicalKey == article.CanonicalKey {
+			return false
+		}
+	}
+	for _, owner := range owners {
+		if owner.Weight >= article.Weight {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contentLength(content string) int {
+	return utf8.RuneCountInString(strings.TrimSpace(content))
+}
+
+// missingField returns the first required field the article leaves empty
+func missingField(article models.Article, fields []string) string {
+	for _, field := range fields {
+		empty := false
+		switch field {
+		case "title":
+			empty = strings.TrimSpace(article.Title) == ""
+		case "link":
+			empty = strings.TrimSpace(article.URL) == ""
+		case "description":
+			empty = strings.TrimSpace(article.Description) == ""
+		case "content":
+			empty = strings.TrimSpace(article.Content) == ""
+		case "image_url":
+			empty = strings.TrimSpace(article.Image) == ""
+		case "creator":
+			empty = len(article.Author) == 0
+		}
+		if empty {
+			return field
+		}
+	}
+
+	return ""
+}
+
+func (qs QualityServiceImp) load() (*models.QualityRules, error) {
+	rulesJSON, err := qs.rClient.Get(qualityRulesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &models.QualityRules{}
+	if err := json.Unmarshal([]byte(rulesJSON), rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// readFile reads the seed rules, a missing file lets every article through
+func (qs QualityServiceImp) readFile() (*models.QualityRules, error) {
+	rules := &models.QualityRules{}
+
+	data, err := os.ReadFile(qs.path)
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading quality rules file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, rules); err != nil {
+		return nil, fmt.Errorf("error parsing quality rules file: %w", err)
+	}
+	if err := validateRules(*rules); err != nil {
+		return nil, err
+	}
+	rules.UpdatedAt = time.Now().UTC()
+
+	return rules, nil
+}
+
+func validateRules(rules models.QualityRules) error {
+	for _, field := range rules.RequiredFields {
+		if !slices.Contains(models.QualityFields, field) {
+			return fmt.Errorf("%w: unknown required field %q, use one of %s", ErrInvalidRules, field, strings.Join(models.QualityFields, ", "))
+		}
+	}
+	if rules.MinContentLength < 0 || rules.MaxAgeHours < 0 || rules.DuplicateTitleHours < 0 {
+		return fmt.Errorf("%w: lengths and hours can not be negative", ErrInvalidRules)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+)
+
+func TestKeepTitleOwners(t *testing.T) {
+	article := func(url string, weight int) models.Article {
+		return models.Article{URL: url, CanonicalKey: url, TitleKey: "cocoa prices hit a record high", Weight: weight}
+	}
+
+	tests := []struct {
+		name      string
+		persisted []models.Article
+		batch     []models.Article
+		kept      []string
+	}{
+		{
+			name:  "first owner in the batch",
+			batch: []models.Article{article("a.example/cocoa", 500), article("b.example/cocoa", 500)},
+			kept:  []string{"a.example/cocoa"},
+		},
+		{
+			name:      "stored owner of equal weight",
+			persisted: []models.Article{article("a.example/cocoa", 500)},
+			batch:     []models.Article{article("b.example/cocoa", 500)},
+		},
+		{
+			name:      "lighter source",
+			persisted: []models.Article{article("a.example/cocoa", 800)},
+			batch:     []models.Article{article("b.example/cocoa", 300)},
+		},
+		{
+			name:      "heavier source reaches the near duplicate check",
+			persisted: []models.Article{article("a.example/cocoa", 300)},
+			batch:     []models.Article{article("b.example/cocoa", 800)},
+			kept:      []string{"b.example/cocoa"},
+		},
+		{
+			name:      "heavier than only one owner",
+			persisted: []models.Article{article("a.example/cocoa", 300), article("c.example/cocoa", 900)},
+			batch:     []models.Article{article("b.example/cocoa", 800)},
+		},
+		{
+			name:  "heavier copy later in the batch",
+			batch: []models.Article{article("a.example/cocoa", 300), article("b.example/cocoa", 800), article("c.example/cocoa", 500)},
+			kept:  []string{"a.example/cocoa", "b.example/cocoa"},
+		},
+		{
+			name:      "same article seen again",
+			persisted: []models.Article{article("a.example/cocoa", 800)},
+			batch:     []models.Article{article("a.example/cocoa", 300)},
+			kept:      []string{"a.example/cocoa"},
+		},
+		{
+			name:  "no title",
+			batch: []models.Article{{URL: "a.example/x", CanonicalKey: "a.example/x"}, {URL: "b.example/x", CanonicalKey: "b.example/x"}},
+			kept:  []string{"a.example/x", "b.example/x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, rejected := keepTitleOwners(tt.batch, tt.persisted)
+
+			urls := make([]string, 0, len(kept))
+			for _, article := range kept {
+				urls = append(urls, article.URL)
+			}
+			if len(urls) != len(tt.kept) {
+				t.Fatalf("kept %v, want %v", urls, tt.kept)
+			}
+			for i := range urls {
+				if urls[i] != tt.kept[i] {
+					t.Fatalf("kept %v, want %v", urls, tt.kept)
+				}
+			}
+			if len(kept)+len(rejected) != len(tt.batch) {
+				t.Errorf("%d kept and %d rejected out of %d", len(kept), len(rejected), len(tt.batch))
+			}
+			for _, rejection := range rejected {
+				if rejection.Reason != models.RejectDuplicateTitle {
+					t.Errorf("rejected for %q", rejection.Reason)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"math"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+// ReadingMeter sets the word count, reading time and readability of articles
+type ReadingMeter struct {
+	wordsPerMinute int
+}
+
+func NewReadingMeter(wordsPerMinute int) *ReadingMeter {
+	if wordsPerMinute <= 0 {
+		wordsPerMinute = 238
+	}
+
+	return &ReadingMeter{wordsPerMinute: wordsPerMinute}
+}
+
+// Measure sets the reading metrics of every article from its body. The
+// readability formulas count english syllables, other languages only get
+// their length measured
+func (rm *ReadingMeter) Measure(articles []models.Article) {
+	for i := range articles {
+		article := &articles[i]
+
+		stats := utils.MeasureText(article.Content)
+		article.WordCount = stats.Words
+		article.ReadingTime = stats.ReadingMinutes(rm.wordsPerMinute)
+		article.ReadingEase, article.GradeLevel = nil, nil
+
+		if stats.Words > 0 && (article.Language == "" || article.Language == "en") {
+			ease := math.Round(stats.ReadingEase()*10) / 10
+			grade := math.Round(stats.GradeLevel()*10) / 10
+			article.ReadingEase, article.GradeLevel = &ease, &grade
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+type RSSSource struct {
+	cfg         models.SourceConfig
+	upstream    *UpstreamClient
+	deadLetters DeadLetterService
+}
+
+func NewRSSSource(cfg models.SourceConfig, upstream *UpstreamClient, deadLetters DeadLetterService) Source {
+	return &RSSSource{
+		cfg:         cfg,
+		upstream:    upstream,
+		deadLetters: deadLetters,
+	}
+}
+
+func (rs RSSSource) Name() string {
+	return rs.cfg.Name
+}
+
+// Fetch reads an RSS 2.0 feed
+func (rs RSSSource) Fetch(ctx context.Context, emit func([]models.Article) (int, error)) []models.FetchReport {
+	return fetchFeed(ctx, rs.upstream, rs.cfg, rs.parse, rs.deadLetters, emit)
+}
+
+// Reparse reads a feed document captured as a dead letter again
+func (rs RSSSource) Reparse(letter models.DeadLetter) ([]models.Article, error) {
+	return rs.parse([]byte(letter.Payload))
+}
+
+func (rs RSSSource) parse(body []byte) ([]models.Article, error) {
+	var feed models.RSSFeed
+	if err := decodeFeed(body, &feed); err != nil {
+		return nil, err
+	}
+
+	articles := make([]models.Article, 0, len(feed.Channel.Items))
+	for _, item := range feed.Channel.Items {
+		link := strings.TrimSpace(item.Link)
+		if link == "" && strings.HasPrefix(item.GUID, "http") {
+			link = strings.TrimSpace(item.GUID)
+		}
+
+		content := item.Content
+		if content == "" {
+			content = item.Description
+		}
+
+		authors := item.Creator
+		if item.Author != "" {
+			authors = append(authors, item.Author)
+		}
+
+		article := models.Article{
+			Title:       utils.CleanText(item.Title),
+			Description: utils.CleanText(item.Description),
+			URL:         link,
+			Author:      authors,
+			Image:       rssImage(item, content),
+			Content:     utils.CleanText(content),
+			Keywords:    item.Categories,
+			Date:        strings.TrimSpace(item.PubDate),
+		}
+		applySourceDefaults(&article, rs.cfg)
+		articles = append(articles, article)
+	}
+
+	return articles, nil
+}
+
+func rssImage(item models.RSSItem, content string) string {
+	if strings.HasPrefix(item.Enclosure.Type, "image/") {
+		return item.Enclosure.URL
+	}
+
+	for _, media := range item.Media {
+		if media.Medium == "" || media.Medium == "image" {
+			return media.URL
+		}
+	}
+
+	return utils.FirstImage(content)
+}
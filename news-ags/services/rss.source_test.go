@@ -0,0 +1,96 @@
+package services
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+)
+
+func readFeedFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	body, err := os.ReadFile("../testdata/feeds/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body
+}
+
+func TestRSSParse(t *testing.T) {
+	source := RSSSource{cfg: models.SourceConfig{Name: "lagos-daily", Weight: 300, Categories: []string{"top"}}}
+
+	articles, err := source.parse(readFeedFixture(t, "rss.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(articles) != 3 {
+		t.Fatalf("parsed %d articles, want 3", len(articles))
+	}
+
+	// ISO-8859-1 is read as UTF-8 and the permalink guid stands in for the link
+	cafe := articles[0]
+	if cafe.Title != "Café owners in Lagos count the cost of fuel" {
+		t.Errorf("title = %q", cafe.Title)
+	}
+	if cafe.URL != "https://lagosdaily.example.com/news/cafe-owners" {
+		t.Errorf("url = %q, want the guid", cafe.URL)
+	}
+	if cafe.Description != "Prices at the café rose again." || cafe.Content != cafe.Description {
+		t.Errorf("description = %q, content = %q", cafe.Description, cafe.Content)
+	}
+	if !slices.Equal(cafe.Author, []string{"Adé Bello", "Chioma Okafor"}) {
+		t.Errorf("authors = %q, want both dc:creator", cafe.Author)
+	}
+	if cafe.Image != "https://lagosdaily.example.com/img/cafe.jpg" {
+		t.Errorf("image = %q, want the enclosure", cafe.Image)
+	}
+	if cafe.Source != "lagos-daily" || cafe.Weight != 300 || !slices.Equal(cafe.Category, []string{"top"}) || cafe.Id.IsZero() {
+		t.Errorf("source defaults not applied: %+v", cafe)
+	}
+
+	squad := articles[1]
+	if squad.URL != "https://lagosdaily.example.com/sport/squad" {
+		t.Errorf("url = %q, want the link over a non-permalink guid", squad.URL)
+	}
+	if squad.Content != "The coach named 23 players ." {
+		t.Errorf("content = %q, want content:encoded", squad.Content)
+	}
+	if !slices.Equal(squad.Author, []string{"sport@lagosdaily.example.com"}) {
+		t.Errorf("authors = %q", squad.Author)
+	}
+	if squad.Image != "https://lagosdaily.example.com/img/squad.jpg" {
+		t.Errorf("image = %q, want the media:content image", squad.Image)
+	}
+	if !slices.Equal(squad.Keywords, []string{"sports", "football"}) || squad.Date != "Tue, 03 Sep 2024 09:00:00 +0100" {
+		t.Errorf("keywords = %q, date = %q", squad.Keywords, squad.Date)
+	}
+
+	// an audio enclosure is not an image, the description's image is used
+	if podcast := articles[2]; podcast.Image != "https://lagosdaily.example.com/img/podcast.png" {
+		t.Errorf("image = %q, want the embedded image", podcast.Image)
+	}
+}
+
+func TestRSSParseWindows1252(t *testing.T) {
+	body := append([]byte(`<?xml version="1.0" encoding="windows-1252"?><rss><channel><item><title>`),
+		0x93, 'Q', 'u', 'o', 't', 'e', 'd', 0x94, ' ', 0x96, ' ', 'n', 'e', 'w', 's')
+	body = append(body, []byte(`</title><link>https://example.com/a</link></item></channel></rss>`)...)
+
+	articles, err := RSSSource{}.parse(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(articles) != 1 || articles[0].Title != "“Quoted” – news" {
+		t.Errorf("parsed %+v, want the windows-1252 title as UTF-8", articles)
+	}
+}
+
+func TestRSSParseUnknownCharset(t *testing.T) {
+	body := []byte(`<?xml version="1.0" encoding="x-unknown"?><rss><channel></channel></rss>`)
+	if _, err := (RSSSource{}).parse(body); err == nil {
+		t.Error("parsed a feed in an unknown charset, want an error")
+	}
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrRunNotFound = errors.New("ingestion run not found")
+
+// RunService keeps the history of scrape and save runs in mongo. Recording is
+// best effort, a run is never failed because its report could not be stored
+type RunService interface {
+	Start(kind string) *models.IngestionRun
+	Finish(run *models.IngestionRun, err error)
+	Runs(filter models.RunFilter) ([]models.IngestionRun, int64, error)
+	Run(id string) (*models.IngestionRun, error)
+}
+
+type RunServiceImp struct {
+	ctx           context.Context
+	runCollection *mongo.Collection
+	retention     time.Duration
+	host          string
+	indexes       *sync.Once
+}
+
+// NewRunService builds the run history, runs are dropped once older than the retention
+func NewRunService(ctx context.Context, runs *mongo.Collection, retention time.Duration) RunService {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "news-ags"
+	}
+
+	return &RunServiceImp{
+		ctx:           ctx,
+		runCollection: runs,
+		retention:     retention,
+		host:          host,
+		indexes:       &sync.Once{},
+	}
+}
+
+// Start stores a running run so it shows up while it is still in progress
+func (rs RunServiceImp) Start(kind string) *models.IngestionRun {
+	rs.indexes.Do(func() {
+		if err := rs.createIndexes(); err != nil {
+			utils.LogErrorToFile("create ingestion run indexes", err.Error())
+		}
+	})
+
+	now := time.Now()
+	run := &models.IngestionRun{
+		Id:        primitive.NewObjectID(),
+		Kind:      kind,
+		Status:    models.RunStatusRunning,
+		Host:      rs.host,
+		StartedAt: now,
+		Breakdown: make([]models.RunBreakdown, 0),
+		ExpiresAt: now.Add(rs.retention),
+	}
+	rs.save(run)
+
+	return run
+}
+
+// Finish totals the run and replaces the stored copy
+func (rs RunServiceImp) Finish(run *models.IngestionRun, err error) {
+	run.Finish(err)
+	rs.save(run)
+}
+
+func (rs RunServiceImp) save(run *models.IngestionRun) {
+	_, err := rs.runCollection.ReplaceOne(rs.ctx, bson.M{"_id": run.Id}, run, options.Replace().SetUpsert(true))
+	if err != nil {
+		utils.LogErrorToFile("record ingestion run "+run.Id.Hex(), err.Error())
+	}
+}
+
+// Runs lists runs newest first without their breakdown, along with the total
+// number of runs the filter matches
+func (rs RunServiceImp) Runs(filter models.RunFilter) ([]models.IngestionRun, int64, error) {
+	query := bson.M{}
+	if filter.Kind != "" {
+		query["kind"] = filter.Kind
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Source != "" {
+		query["breakdown.source"] = filter.Source
+	}
+	if !filter.Since.IsZero() {
+		query["started_at"] = bson.M{"$gte": filter.Since}
+	}
+
+	total, err := rs.runCollection.CountDocuments(rs.ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}}).
+		SetProjection(bson.M{"breakdown": 0}).
+		SetSkip(filter.Skip).
+		SetLimit(filter.Limit)
+	cursor, err := rs.runCollection.Find(rs.ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(rs.ctx)
+
+	runs := make([]models.IngestionRun, 0)
+	if err := cursor.All(rs.ctx, &runs); err != nil {
+		return nil, 0, err
+	}
+
+	return runs, total, nil
+}
+
+// Run returns a single run with its per source and category breakdown
+func (rs RunServiceImp) Run(id string) (*models.IngestionRun, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrRunNotFound
+	}
+
+	var run models.IngestionRun
+	err = rs.runCollection.FindOne(rs.ctx, bson.M{"_id": objectId}).Decode(&run)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrRunNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+func (rs RunServiceImp) createIndexes() error {
+	// Index model for the history listing
+	startedIndex := mongo.IndexModel{Keys: bson.D{{Key: "started_at", Value: -1}}}
+	kindIndex := mongo.IndexModel{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "started_at", Value: -1}}}
+
+	// Index model for runs that touched a source
+	sourceIndex := mongo.IndexModel{Keys: bson.M{"breakdown.source": 1}}
+
+	// Index model expiring runs past the retention
+	expiryIndex := mongo.IndexModel{
+		Keys:    bson.M{"expires_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err := rs.runCollection.Indexes().CreateMany(rs.ctx, []mongo.IndexModel{startedIndex, kindIndex, sourceIndex, expiryIndex})
+	return err
+}
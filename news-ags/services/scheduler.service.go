@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	schedulerLockPrefix   = "scheduler:lock:"
+	schedulerStatusPrefix = "scheduler:status:"
+	// minSchedulerLockTTL leaves the lock refresh at least a second between ticks
+	minSchedulerLockTTL = 3 * time.Second
+)
+
+var ErrJobRunning = errors.New("job is already running")
+
+// releaseLockScript deletes the lock only when it is still held by the caller
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// extendLockScript pushes the lock expiry forward only when it is still held by the caller
+var extendLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+type SchedulerService interface {
+	Register(name, spec string, job func() error) error
+	Run(name string, job func() error) error
+	Start()
+	Stop()
+	Status() ([]models.JobStatus, error)
+}
+
+type scheduledJob struct {
+	name    string
+	spec    string
+	entry   cron.EntryID
+	run     func() error
+	running int32
+}
+
+type SchedulerServiceImp struct {
+	ctx     context.Context
+	rClient *redis.Client
+	cron    *cron.Cron
+	lockTTL time.Duration
+	owner   string
+	mu      sync.RWMutex
+	jobs    []*scheduledJob
+	manual  map[string]*scheduledJob
+}
+
+// NewScheduler builds the scheduler, the lock ttl bounds how long a crashed
+// replica keeps a job locked
+func NewScheduler(ctx context.Context, client *redis.Client, lockTTL time.Duration) (SchedulerService, error) {
+	if lockTTL < minSchedulerLockTTL {
+		return nil, fmt.Errorf("scheduler lock ttl must be at least %s", minSchedulerLockTTL)
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "news-ags"
+	}
+
+	return &SchedulerServiceImp{
+		ctx:     ctx,
+		rClient: client,
+		cron:    cron.New(),
+		lockTTL: lockTTL,
+		owner:   fmt.Sprintf("%s:%d", host, os.Getpid()),
+		manual:  make(map[string]*scheduledJob),
+	}, nil
+}
+
+// Register adds a job to the scheduler, an empty spec leaves the job disabled
+func (s *SchedulerServiceImp) Register(name, spec string, job func() error) error {
+	if spec == "" {
+		return nil
+	}
+
+	sj := &scheduledJob{name: name, spec: spec, run: job}
+	id, err := s.cron.AddFunc(spec, func() { _ = s.execute(sj, sj.run) })
+	if err != nil {
+		return fmt.Errorf("invalid schedule for job %s: %w", name, err)
+	}
+	sj.entry = id
+
+	s.mu.Lock()
+	s.jobs = append(s.jobs, sj)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Run runs a job now under the same locks as its scheduled runs, so a manual
+// run never overlaps a scheduled one. ErrJobRunning is returned when the job
+// is already running here or on another replica
+func (s *SchedulerServiceImp) Run(name string, job func() error) error {
+	return s.execute(s.job(name), job)
+}
+
+// job returns the registered job of that name, a job without a schedule is
+// tracked on its own so its manual runs still do not overlap
+func (s *SchedulerServiceImp) job(name string) *scheduledJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.name == name {
+			return job
+		}
+	}
+
+	if s.manual[name] == nil {
+		s.manual[name] = &scheduledJob{name: name}
+	}
+
+	return s.manual[name]
+}
+
+func (s *SchedulerServiceImp) Start() {
+	s.cron.Start()
+}
+
+// Stop halts the scheduler and waits for running jobs to finish
+func (s *SchedulerServiceImp) Stop() {
+	<-s.cron.Stop().Done()
+}
+
+// Status reports the last recorded run of every job across all replicas
+// together with the next local fire time
+func (s *SchedulerServiceImp) Status() ([]models.JobStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]models.JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status, err := s.loadStatus(job.name)
+		if err != nil {
+			return nil, err
+		}
+
+		status.Name = job.name
+		status.Schedule = job.spec
+		status.NextRun = s.cron.Entry(job.entry).Next
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// execute runs the job unless it is already running, the outcome is recorded
+// for the status endpoint and the error of the run returned
+func (s *SchedulerServiceImp) execute(job *scheduledJob, run func() error) error {
+	// overlap protection within this replica
+	if !atomic.CompareAndSwapInt32(&job.running, 0, 1) {
+		utils.LogErrorToFile("scheduler "+job.name, "still running, skipped")
+		return ErrJobRunning
+	}
+	defer atomic.StoreInt32(&job.running, 0)
+
+	// overlap protection across replicas
+	lockKey := schedulerLockPrefix + job.name
+	acquired, err := s.rClient.SetNX(lockKey, s.owner, s.lockTTL).Result()
+	if err != nil {
+		utils.LogErrorToFile("scheduler lock "+job.name, err.Error())
+		return err
+	}
+	if !acquired {
+		utils.LogErrorToFile("scheduler "+job.name, "locked by another replica, skipped")
+		return ErrJobRunning
+	}
+
+	stop := make(chan struct{})
+	go s.keepLock(lockKey, stop)
+	defer func() {
+		close(stop)
+		if err := releaseLockScript.Run(s.rClient, []string{lockKey}, s.owner).Err(); err != nil {
+			utils.LogErrorToFile("scheduler unlock "+job.name, err.Error())
+		}
+	}()
+
+	started := time.Now()
+	status := models.JobStatus{
+		Name:      job.name,
+		Schedule:  job.spec,
+		Running:   true,
+		LastRun:   started,
+		Outcome:   models.JobOutcomeRunning,
+		RunBy:     s.owner,
+		UpdatedAt: started,
+	}
+	s.saveStatus(status)
+
+	err = s.runJob(run)
+
+	status.Running = false
+	status.Duration = time.Since(started).Milliseconds()
+	status.UpdatedAt = time.Now()
+	status.Outcome = models.JobOutcomeSuccess
+	if err != nil {
+		status.Outcome = models.JobOutcomeFailed
+		status.Error = err.Error()
+		utils.LogErrorToFile("scheduled job "+job.name, err.Error())
+	}
+	s.saveStatus(status)
+
+	return err
+}
+
+// runJob shields the scheduler from panics raised by a job
+func (s *SchedulerServiceImp) runJob(run func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return run()
+}
+
+// keepLock refreshes the lock expiry until the job finishes so long runs
+// are not picked up by another replica
+func (s *SchedulerServiceImp) keepLock(key string, stop <-chan struct{}) {
+	ticker := time.NewTicker(s.lockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := extendLockScript.Run(s.rClient, []string{key}, s.owner, s.lockTTL.Milliseconds()).Err()
+			if err != nil {
+				utils.LogErrorToFile("scheduler extend lock", err.Error())
+			}
+		}
+	}
+}
+
+func (s *SchedulerServiceImp) saveStatus(status models.JobStatus) {
+	statusJSON, err := json.Marshal(status)
+	if err != nil {
+		utils.LogErrorToFile("scheduler status "+status.Name, err.Error())
+		return
+	}
+
+	if err := s.rClient.Set(schedulerStatusPrefix+status.Name, statusJSON, 0).Err(); err != nil {
+		utils.LogErrorToFile("scheduler status "+status.Name, err.Error())
+	}
+}
+
+func (s *SchedulerServiceImp) loadStatus(name string) (models.JobStatus, error) {
+	var status models.JobStatus
+
+	jsonStr, err := s.rClient.Get(schedulerStatusPrefix + name).Result()
+	if err == redis.Nil {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+
+	err = json.Unmarshal([]byte(jsonStr), &status)
+	return status, err
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+)
+
+func TestNewSchedulerRejectsShortLockTTL(t *testing.T) {
+	_, client := newTestRedis(t)
+
+	for _, ttl := range []time.Duration{0, time.Second, 2 * time.Second} {
+		if _, err := NewScheduler(context.Background(), client, ttl); err == nil {
+			t.Errorf("NewScheduler(%s) accepted the ttl", ttl)
+		}
+	}
+	if _, err := NewScheduler(context.Background(), client, minSchedulerLockTTL); err != nil {
+		t.Errorf("NewScheduler(%s) error = %v", minSchedulerLockTTL, err)
+	}
+}
+
+func TestSchedulerRunRecordsOutcome(t *testing.T) {
+	_, client := newTestRedis(t)
+	scheduler, _ := NewScheduler(context.Background(), client, time.Minute)
+	if err := scheduler.Register("save", "@every 1h", func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("mongo unavailable")
+	if err := scheduler.Run("save", func() error { return failure }); err != failure {
+		t.Fatalf("Run() error = %v, want the job error", err)
+	}
+
+	statuses, err := scheduler.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[0].Outcome != models.JobOutcomeFailed || statuses[0].Error != failure.Error() {
+		t.Errorf("status = %+v, want the failed manual run", statuses[0])
+	}
+
+	if exists, _ := client.Exists(schedulerLockPrefix + "save").Result(); exists != 0 {
+		t.Error("lock still held after the run")
+	}
+}
+
+func TestSchedulerRunSkipsLockedJob(t *testing.T) {
+	_, client := newTestRedis(t)
+	scheduler, _ := NewScheduler(context.Background(), client, time.Minute)
+
+	// another replica holds the lock
+	client.Set(schedulerLockPrefix+"scrape", "other:1", time.Minute)
+
+	ran := false
+	err := scheduler.Run("scrape", func() error { ran = true; return nil })
+	if !errors.Is(err, ErrJobRunning) || ran {
+		t.Fatalf("Run() = %v with the job run %v, want ErrJobRunning without running", err, ran)
+	}
+	if owner, _ := client.Get(schedulerLockPrefix + "scrape").Result(); owner != "other:1" {
+		t.Errorf("lock owner = %q, want the other replica kept", owner)
+	}
+}
+
+func TestSchedulerRunSkipsRunningJob(t *testing.T) {
+	_, client := newTestRedis(t)
+	scheduler, _ := NewScheduler(context.Background(), client, time.Minute)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- scheduler.Run("scrape", func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	if err := scheduler.Run("scrape", func() error { return nil }); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("overlapping Run() error = %v, want ErrJobRunning", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first Run() error = %v", err)
+	}
+	if err := scheduler.Run("scrape", func() error { return nil }); err != nil {
+		t.Errorf("Run() after the first finished error = %v", err)
+	}
+}
@@ -5,134 +5,325 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-redis/redis"
-	"github.com/go-resty/resty/v2"
 	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-var newsResponsePool = sync.Pool{
-	New: func() interface{} {
-		return &models.NewsResponse{}
-	},
-}
+var ErrNotAnArticle = errors.New("dead letter payload is not an article")
 
 type ScrapeArticleService interface {
-	ParseArticle() error
-	getNews(apiKey, category, nextPage string) error
-	cacheArticle(article *models.Article, key string) error
+	ParseArticle() (*models.ScrapeReport, error)
+	ScrapeScheduled() (*models.ScrapeReport, error)
+	Requeue(letter models.DeadLetter) error
+	getNews(source Source) []models.FetchReport
+	cacheArticle(article *models.Article) error
 }
 
 type ScrapeArticleServiceImp struct {
-	ctx     context.Context
-	rClient *redis.Client
-	apikey  string
+	ctx         context.Context
+	rClient     *redis.Client
+	factory     SourceFactory
+	registry    SourceRegistry
+	plan        ScrapePlanService
+	stream      StreamOptions
+	enricher    *ArticleEnricher
+	runs        RunService
+	deadLetters DeadLetterService
 }
 
-func NewScrapper(ctx context.Context, client *redis.Client, key string) ScrapeArticleService {
+// NewScrapper builds the scraper, a nil enricher skips fetching article pages
+func NewScrapper(ctx context.Context, client *redis.Client, factory SourceFactory, registry SourceRegistry, plan ScrapePlanService, stream StreamOptions, enricher *ArticleEnricher, runs RunService, deadLetters DeadLetterService) ScrapeArticleService {
 	return &ScrapeArticleServiceImp{
-		ctx:     ctx,
-		rClient: client,
-		apikey:  key,
+		ctx:         ctx,
+		rClient:     client,
+		factory:     factory,
+		registry:    registry,
+		plan:        plan,
+		stream:      stream,
+		enricher:    enricher,
+		runs:        runs,
+		deadLetters: deadLetters,
 	}
 }
 
-func (as ScrapeArticleServiceImp) ParseArticle() error {
-	var wg sync.WaitGroup
-	categories := []string{"business", "entertainment", "health", "science", "sports", "technology", "politics", "tourism", "environment", "domestic"}
-	ch := make(chan error, len(categories)+1)
-	for _, cat := range categories {
+// ParseArticle fetches every registered source, newsdata sources covered by the
+// scrape plan only run the enabled entries without a schedule of their own.
+// A failing source or query is recorded in the report without discarding what
+// the others produced
+func (as ScrapeArticleServiceImp) ParseArticle() (*models.ScrapeReport, error) {
+	run := as.runs.Start(models.RunKindScrape)
+
+	configs, err := as.registry.Sources()
+	if err != nil {
+		as.runs.Finish(run, err)
+		return nil, err
+	}
+
+	plan, err := as.plan.Plan()
+	if err != nil {
+		as.runs.Finish(run, err)
+		return nil, err
+	}
+
+	report := &models.ScrapeReport{RunId: run.Id.Hex(), StartedAt: run.StartedAt}
+	sources := make([]Source, 0, len(configs))
+	for _, cfg := range configs {
+		if cfg.Type == models.SourceTypeNewsdata {
+			if entries := planEntries(plan.Entries, cfg.Name); len(entries) > 0 {
+				unscheduled := make([]models.ScrapePlanEntry, 0, len(entries))
+				for _, entry := range entries {
+					if entry.Enabled && entry.Schedule == "" {
+						unscheduled = append(unscheduled, entry)
+					}
+				}
+				if len(unscheduled) > 0 {
+					sources = append(sources, as.factory.BuildPlanned(cfg, unscheduled))
+				}
+				continue
+			}
+		}
+
+		source, err := as.factory.Build(cfg)
+		if err != nil {
+			report.Add(models.FetchReport{Source: cfg.Name, Error: err.Error()})
+			continue
+		}
+		sources = append(sources, source)
+	}
+
+	return as.record(run, as.run(report, sources)), nil
+}
+
+// ScrapeScheduled runs the plan entries whose own schedule is due, the run is
+// recorded up front so a failing entry waits for its next slot. Ticks with
+// nothing due are not kept in the run history
+func (as ScrapeArticleServiceImp) ScrapeScheduled() (*models.ScrapeReport, error) {
+	now := time.Now()
+	report := &models.ScrapeReport{StartedAt: now}
+
+	due, err := as.plan.Due(now)
+	if err != nil {
+		return nil, err
+	}
+	if len(due) == 0 {
+		report.FinishedAt = now
+		return report, nil
+	}
+
+	run := as.runs.Start(models.RunKindPlan)
+	report.RunId = run.Id.Hex()
+
+	configs, err := as.registry.Sources()
+	if err != nil {
+		as.runs.Finish(run, err)
+		return nil, err
+	}
+
+	for _, entry := range due {
+		if err := as.plan.MarkRun(entry.Name, now); err != nil {
+			as.runs.Finish(run, err)
+			return nil, err
+		}
+	}
+
+	sources := make([]Source, 0)
+	for _, cfg := range configs {
+		if cfg.Type != models.SourceTypeNewsdata {
+			continue
+		}
+		if entries := planEntries(due, cfg.Name); len(entries) > 0 {
+			sources = append(sources, as.factory.BuildPlanned(cfg, entries))
+		}
+	}
+
+	return as.record(run, as.run(report, sources)), nil
+}
+
+func (as ScrapeArticleServiceImp) run(report *models.ScrapeReport, sources []Source) *models.ScrapeReport {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+
+	for _, source := range sources {
 		wg.Add(1)
-		go func(category string) {
+		go func(src Source) {
 			defer wg.Done()
-			err := as.getNews(as.apikey, category, "")
-			if err != nil {
-				ch <- err
+			reports := as.getNews(src)
+
+			mu.Lock()
+			report.Add(reports...)
+			mu.Unlock()
+		}(source)
+	}
+	wg.Wait()
+	report.FinishedAt = time.Now()
+
+	for _, fetch := range report.Fetches {
+		if fetch.Error != "" {
+			utils.LogErrorToFile(fmt.Sprintf("scrape %s %s %s", fetch.Source, fetch.Entry, fetch.Category), fetch.Error)
+		}
+	}
+
+	return report
+}
+
+// record stores the outcome of every fetch in the run history, per source and
+// category. Articles fetched but never queued count as failed
+func (as ScrapeArticleServiceImp) record(run *models.IngestionRun, report *models.ScrapeReport) *models.ScrapeReport {
+	for _, fetch := range report.Fetches {
+		row := run.Row(fetch.Source, fetch.Category)
+		row.Fetched += fetch.Articles
+		row.Parsed += fetch.Queued
+		row.Failed += fetch.Articles - fetch.Queued
+		if fetch.Error != "" {
+			row.Failed++
+			sample := fetch.Error
+			if fetch.Entry != "" {
+				sample = fetch.Entry + ": " + sample
+			}
+			run.Sample(row, sample)
+		}
+	}
+	as.runs.Finish(run, nil)
+
+	return report
+}
+
+// planEntries returns the plan entries that target the named newsdata source,
+// entries without a source target every newsdata source
+func planEntries(entries []models.ScrapePlanEntry, source string) []models.ScrapePlanEntry {
+	matched := make([]models.ScrapePlanEntry, 0)
+	for _, entry := range entries {
+		if entry.Source == "" || entry.Source == source {
+			matched = append(matched, entry)
+		}
+	}
+
+	return matched
+}
+
+// getNews queues what the source fetches, articles that can not be queued
+// are kept as dead letters
+func (as ScrapeArticleServiceImp) getNews(source Source) []models.FetchReport {
+	return source.Fetch(as.ctx, func(articles []models.Article) (int, error) {
+		as.prepare(articles)
+
+		queued := 0
+		for i := range articles {
+			if err := as.cacheArticle(&articles[i]); err != nil {
+				utils.LogErrorToFile("cache article", err.Error())
+				as.captureArticle(models.DeadLetterStageCache, source.Name(), articles[i], err)
+				continue
 			}
+			queued++
+		}
+		return queued, nil
+	})
+}
+
+// prepare enriches fresh articles and stamps what the saver relies on
+func (as ScrapeArticleServiceImp) prepare(articles []models.Article) {
+	if as.enricher != nil {
+		as.enricher.EnrichAll(as.ctx, articles)
+	}
+
+	now := time.Now()
+	for i := range articles {
+		stampArticle(&articles[i], now)
+		fingerprintArticle(&articles[i])
+		canonicalizeArticle(&articles[i])
+	}
+}
 
-		}(cat)
+func (as ScrapeArticleServiceImp) captureArticle(stage, source string, article models.Article, reason error) {
+	category := ""
+	if len(article.Category) > 0 {
+		category = article.Category[0]
 	}
-	go func() {
-		wg.Wait()
-		close(ch)
-	}()
 
-	// Check for errors from goroutines
-	for err := range ch {
+	payload, _ := json.Marshal(article)
+	as.deadLetters.Capture(stage, source, category, payload, reason)
+}
+
+// Requeue puts the articles of a dead letter back on the stream. Upstream
+// payloads are parsed again by the source that captured them, the other
+// stages already hold an article
+func (as ScrapeArticleServiceImp) Requeue(letter models.DeadLetter) error {
+	var articles []models.Article
+	if letter.Stage == models.DeadLetterStageParse {
+		source, err := as.source(letter.Source)
 		if err != nil {
-			// Return the first error received
 			return err
 		}
+		if articles, err = source.Reparse(letter); err != nil {
+			return err
+		}
+		as.prepare(articles)
+	} else {
+		var article models.Article
+		if err := json.Unmarshal([]byte(letter.Payload), &article); err != nil {
+			return err
+		}
+		// quality letters leave the id out so repeats fold together
+		if article.Id.IsZero() {
+			article.Id = primitive.NewObjectID()
+		}
+		articles = []models.Article{article}
+	}
+
+	// a decode letter may hold a whole stream entry, which decodes into an
+	// empty article without complaint
+	for _, article := range articles {
+		if strings.TrimSpace(article.URL) == "" || strings.TrimSpace(article.Title) == "" {
+			return fmt.Errorf("%w, it has no link or title", ErrNotAnArticle)
+		}
 	}
 
-	return nil // No errors were received
+	for i := range articles {
+		if err := as.cacheArticle(&articles[i]); err != nil {
+			return err
+		}
+	}
 
+	return nil
 }
 
-func (as ScrapeArticleServiceImp) getNews(apiKey, category, nextPage string) error {
-	result := newsResponsePool.Get().(*models.NewsResponse)
-	defer newsResponsePool.Put(result)
-	client := resty.New()
-	url := "https://newsdata.io/api/1/news"
-
-	// ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	// defer cancel()
-
-	resp, err := client.R().
-		// SetContext(ctx).
-		SetQueryParam("language", "en").
-		SetQueryParam("apiKey", apiKey).
-		SetQueryParam("category", category).
-		SetQueryParam("full_content", "1").
-		SetQueryParam("prioritydomain", "top").
-		SetQueryParam("timeframe", "30m").
-		Get(url)
-
-	if err != nil {
-		return err
-	}
-
-	switch resp.StatusCode() {
-	case 429:
-		return errors.New("api rate limit reached")
-	case 500:
-		return errors.New("external api failure")
-	case 415, 422:
-		return errors.New("malformed api querry")
-	case 409:
-		return errors.New("duplicate api querry")
-	case 403:
-		return errors.New("corse api error")
-	case 400:
-		return errors.New("api param missing")
-	case 401:
-		return errors.New("incorrect api key")
-	}
-
-	// Unmarshal the JSON response into the struct
-	err = json.Unmarshal(resp.Body(), &result)
+// source builds the adapter of a registered source by name
+func (as ScrapeArticleServiceImp) source(name string) (Source, error) {
+	configs, err := as.registry.Sources()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, article := range result.Articles {
-		as.cacheArticle(&article, "articleKey:"+article.Id.Hex())
+	for _, cfg := range configs {
+		if cfg.Name == name {
+			return as.factory.Build(cfg)
+		}
 	}
 
-	return nil
+	return nil, fmt.Errorf("source %q is not registered", name)
 }
 
-func (as ScrapeArticleServiceImp) cacheArticle(article *models.Article, key string) error {
+// cacheArticle appends the article to the stream the saver consumes
+func (as ScrapeArticleServiceImp) cacheArticle(article *models.Article) error {
 	articleJSON, err := json.Marshal(article)
 	if err != nil {
 		return fmt.Errorf("error marshaling article: %w", err)
 	}
 
-	err = as.rClient.SetNX(key, articleJSON, 2*time.Hour).Err()
+	err = as.rClient.XAdd(&redis.XAddArgs{
+		Stream: articleStream,
+		Values: map[string]interface{}{articleField: string(articleJSON)},
+	}).Err()
 	if err != nil {
-		return fmt.Errorf("error storing article in Redis: %w", err)
+		return fmt.Errorf("error adding article to Redis stream: %w", err)
 	}
 
 	return nil
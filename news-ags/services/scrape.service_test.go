@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRequeueDeadLetter(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		err     error
+	}{
+		{
+			name:    "article",
+			payload: `{"title":"Cocoa prices hit a record high","link":"https://marketdesk.example/markets/cocoa","source_id":"marketdesk"}`,
+		},
+		{
+			name:    "whole stream entry",
+			payload: `{"payload":"{\"title\":\"Cocoa\"}","origin":"import"}`,
+			err:     ErrNotAnArticle,
+		},
+		{
+			name:    "article without link",
+			payload: `{"title":"Cocoa prices hit a record high"}`,
+			err:     ErrNotAnArticle,
+		},
+		{
+			name:    "article without title",
+			payload: `{"link":"https://marketdesk.example/markets/cocoa"}`,
+			err:     ErrNotAnArticle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, client := newTestRedis(t)
+			scraper := NewScrapper(context.Background(), client, SourceFactory{}, nil, nil, StreamOptions{}, nil, nil, nil)
+
+			err := scraper.Requeue(models.DeadLetter{Stage: models.DeadLetterStageDecode, Payload: tt.payload})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Requeue() error = %v, want %v", err, tt.err)
+			}
+
+			queued, err := client.XLen(articleStream).Result()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := int64(1)
+			if tt.err != nil {
+				want = 0
+			}
+			if queued != want {
+				t.Errorf("stream holds %d entries, want %d", queued, want)
+			}
+		})
+	}
+}
+
+func TestRequeueQualityLetterGetsAnId(t *testing.T) {
+	_, client := newTestRedis(t)
+	scraper := NewScrapper(context.Background(), client, SourceFactory{}, nil, nil, StreamOptions{}, nil, nil, nil)
+
+	payload := `{"article_id":"000000000000000000000000","title":"Cocoa prices hit a record high","link":"https://marketdesk.example/markets/cocoa","source_id":"marketdesk"}`
+	if err := scraper.Requeue(models.DeadLetter{Stage: models.DeadLetterStageQuality, Payload: payload}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := client.XRange(articleStream, "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("stream holds %d entries, want 1", len(entries))
+	}
+	article, err := decodeStreamArticle(entries[0].Values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Id.IsZero() {
+		t.Error("requeued article kept the zero id")
+	}
+}
+
+func TestRequeueStreamLetter(t *testing.T) {
+	_, client := newTestRedis(t)
+	scraper := NewScrapper(context.Background(), client, SourceFactory{}, nil, nil, StreamOptions{}, nil, nil, nil)
+
+	id := primitive.NewObjectID()
+	payload := `{"article_id":"` + id.Hex() + `","title":"Cocoa prices hit a record high","link":"https://marketdesk.example/markets/cocoa","source_id":"marketdesk"}`
+	if err := scraper.Requeue(models.DeadLetter{Stage: models.DeadLetterStageStream, Payload: payload}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := client.XRange(articleStream, "-", "+").Result()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("stream holds %d entries, %v, want 1", len(entries), err)
+	}
+	article, err := decodeStreamArticle(entries[0].Values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Id != id || article.Source != "marketdesk" {
+		t.Errorf("requeued %+v, want the dead lettered article as it was", article)
+	}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"math"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+const (
+	// the headline sets the tone readers take away from an article
+	titleSentimentWeight = 2
+	// sentimentBodyWords bounds the body words scored
+	sentimentBodyWords = 2000
+)
+
+// scoreSentiment sets the sentiment and tone of every article from the lexicon,
+// the lexicon is english so articles in other languages are left unscored
+func scoreSentiment(articles []models.Article) {
+	for i := range articles {
+		article := &articles[i]
+		if article.Language != "" && article.Language != "en" {
+			article.Sentiment, article.Tone = 0, ""
+			continue
+		}
+
+		raw, magnitude := utils.SentimentScore(article.Title, titleSentimentWeight, 0)
+		for _, text := range []string{article.Description, article.Content} {
+			r, m := utils.SentimentScore(text, 1, sentimentBodyWords)
+			raw += r
+			magnitude += m
+		}
+
+		article.Sentiment = math.Round(utils.Sentiment(raw, magnitude)*1000) / 1000
+		article.Tone = models.Tone(article.Sentiment)
+	}
+}
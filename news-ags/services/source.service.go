@@ -0,0 +1,226 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+
+	"github.com/go-redis/redis"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/net/html/charset"
+)
+
+// Source is an upstream publisher that produces articles, Fetch hands each
+// batch to emit as soon as it is read and emit may be called concurrently.
+// Emit returns how many articles of the batch it queued for the saver.
+// Payloads that can not be read are kept as dead letters, Reparse turns one
+// back into articles once it is retried.
+// Every unit of work (a feed, a category) is reported on its own so partial
+// failures do not hide what succeeded
+type Source interface {
+	Name() string
+	Fetch(ctx context.Context, emit func([]models.Article) (int, error)) []models.FetchReport
+	Reparse(letter models.DeadLetter) ([]models.Article, error)
+}
+
+// SourceRegistry lists the upstream sources the scraper should read from
+type SourceRegistry interface {
+	Sources() ([]models.SourceConfig, error)
+}
+
+type FileSourceRegistry struct {
+	path string
+}
+
+func NewFileSourceRegistry(path string) SourceRegistry {
+	return &FileSourceRegistry{path: path}
+}
+
+// Sources reads the enabled sources from a json file
+func (fr FileSourceRegistry) Sources() ([]models.SourceConfig, error) {
+	data, err := os.ReadFile(fr.path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading sources file: %w", err)
+	}
+
+	var configs []models.SourceConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("error parsing sources file: %w", err)
+	}
+
+	enabled := make([]models.SourceConfig, 0, len(configs))
+	for _, cfg := range configs {
+		if cfg.Enabled {
+			enabled = append(enabled, cfg)
+		}
+	}
+
+	return enabled, nil
+}
+
+type MongoSourceRegistry struct {
+	ctx        context.Context
+	collection *mongo.Collection
+}
+
+func NewMongoSourceRegistry(ctx context.Context, collection *mongo.Collection) SourceRegistry {
+	return &MongoSourceRegistry{
+		ctx:        ctx,
+		collection: collection,
+	}
+}
+
+// Sources reads the enabled sources from the sources collection
+func (mr MongoSourceRegistry) Sources() ([]models.SourceConfig, error) {
+	cursor, err := mr.collection.Find(mr.ctx, bson.M{"enabled": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mr.ctx)
+
+	var configs []models.SourceConfig
+	if err := cursor.All(mr.ctx, &configs); err != nil {
+		return nil, err
+	}
+
+	return configs, nil
+}
+
+// ReplaySourceRegistry keeps the newsdata sources of another registry for
+// replaying recorded responses, feeds can not be replayed. Their urls are
+// cleared so the factory points them at the replay server
+type ReplaySourceRegistry struct {
+	registry SourceRegistry
+}
+
+func NewReplaySourceRegistry(registry SourceRegistry) SourceRegistry {
+	return &ReplaySourceRegistry{registry: registry}
+}
+
+func (rr ReplaySourceRegistry) Sources() ([]models.SourceConfig, error) {
+	configs, err := rr.registry.Sources()
+	if err != nil {
+		return nil, err
+	}
+
+	replayed := make([]models.SourceConfig, 0, len(configs))
+	for _, cfg := range configs {
+		if cfg.Type == models.SourceTypeNewsdata {
+			cfg.URL = ""
+			replayed = append(replayed, cfg)
+		}
+	}
+
+	return replayed, nil
+}
+
+// SourceFactory holds what the adapters share across runs, NewsdataURL is
+// where newsdata sources without a url of their own are read from
+type SourceFactory struct {
+	Keys        NewsdataKeyPool
+	NewsdataURL string
+	PageBudget  int
+	Upstream    *UpstreamClient
+	RClient     *redis.Client
+	DeadLetters DeadLetterService
+}
+
+// Build creates the adapter matching a source config
+func (sf SourceFactory) Build(cfg models.SourceConfig) (Source, error) {
+	switch cfg.Type {
+	case models.SourceTypeNewsdata:
+		if cfg.URL == "" {
+			cfg.URL = sf.NewsdataURL
+		}
+		return NewNewsdataSource(cfg, nil, sf.Keys, sf.PageBudget, sf.Upstream, sf.RClient, sf.DeadLetters), nil
+	case models.SourceTypeRSS:
+		return NewRSSSource(cfg, sf.Upstream, sf.DeadLetters), nil
+	case models.SourceTypeAtom:
+		return NewAtomSource(cfg, sf.Upstream, sf.DeadLetters), nil
+	case models.SourceTypeJSONFeed:
+		return NewJSONFeedSource(cfg, sf.Upstream, sf.DeadLetters), nil
+	}
+
+	return nil, fmt.Errorf("unknown source type %q for source %s", cfg.Type, cfg.Name)
+}
+
+// BuildPlanned creates a newsdata adapter limited to the given plan entries
+func (sf SourceFactory) BuildPlanned(cfg models.SourceConfig, entries []models.ScrapePlanEntry) Source {
+	queries := make([]models.NewsdataQuery, 0)
+	for _, entry := range entries {
+		queries = append(queries, entry.Queries()...)
+	}
+
+	if cfg.URL == "" {
+		cfg.URL = sf.NewsdataURL
+	}
+
+	return NewNewsdataSource(cfg, queries, sf.Keys, sf.PageBudget, sf.Upstream, sf.RClient, sf.DeadLetters)
+}
+
+// fetchFeed downloads a single feed document, parses it and emits its articles
+func fetchFeed(ctx context.Context, upstream *UpstreamClient, cfg models.SourceConfig,
+	parse func([]byte) ([]models.Article, error), deadLetters DeadLetterService, emit func([]models.Article) (int, error)) []models.FetchReport {
+	report := models.FetchReport{Source: cfg.Name}
+
+	err := func() error {
+		resp, err := upstream.Get(ctx, cfg.Name, cfg.URL, cfg.Params)
+		if err != nil {
+			return err
+		}
+
+		if resp.IsError() {
+			return fmt.Errorf("feed %s returned status %d", cfg.URL, resp.StatusCode())
+		}
+
+		articles, err := parse(resp.Body())
+		if err != nil {
+			deadLetters.Capture(models.DeadLetterStageParse, cfg.Name, "", resp.Body(), err)
+			return err
+		}
+
+		report.Articles = len(articles)
+		report.Queued, err = emit(articles)
+		return err
+	}()
+
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	return []models.FetchReport{report}
+}
+
+// decodeFeed reads an XML feed document into feed, converting the encoding
+// its declaration names, such as ISO-8859-1 or windows-1252, to UTF-8
+func decodeFeed(body []byte, feed any) error {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	return decoder.Decode(feed)
+}
+
+// applySourceDefaults fills the fields a feed does not carry from the source config
+func applySourceDefaults(article *models.Article, cfg models.SourceConfig) {
+	if article.Id.IsZero() {
+		article.Id = primitive.NewObjectID()
+	}
+	if article.Source == "" {
+		article.Source = cfg.Name
+	}
+	if article.Weight == 0 {
+		article.Weight = cfg.Weight
+	}
+	if len(article.Category) == 0 {
+		article.Category = cfg.Categories
+	}
+	if len(article.Country) == 0 {
+		article.Country = cfg.Country
+	}
+}
@@ -3,157 +3,792 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"os"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/go-redis/redis"
 	"github.com/joey1123455/news-aggregator-service/news-ags/models"
 	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ArticleSaverService interface {
-	retrieveAllArticles() ([]models.Article, error)
-	compareArticles([]models.Article) ([]models.Article, error)
-	SaveArticles() error
+	claimStale() ([]redis.XMessage, error)
+	readArticles() ([]redis.XMessage, error)
+	compareArticles([]models.Article) ([]models.Article, []primitive.ObjectID, error)
+	SaveArticles() (*models.IngestionRun, error)
 }
 
 type ArticleSaverServiceImp struct {
 	ctx               context.Context
 	rClient           *redis.Client
 	articleCollection *mongo.Collection
-	articleLists      []any
+	keywords          KeywordService
+	classifier        ClassifierService
+	entities          EntityService
+	summarizer        *Summarizer
+	meter             *ReadingMeter
+	images            ImageService
+	stories           StoryService
+	trending          TrendingService
+	quality           QualityService
+	runs              RunService
+	deadLetters       DeadLetterService
+	stream            StreamOptions
+	consumer          string
 }
 
-func NewArticleSaver(cont context.Context, redDB *redis.Client, monDB *mongo.Collection) ArticleSaverService {
+// NewArticleSaver builds the saver, a nil image service leaves lead images unprocessed
+func NewArticleSaver(cont context.Context, redDB *redis.Client, monDB *mongo.Collection, keywords KeywordService, classifier ClassifierService, entities EntityService, summarizer *Summarizer, meter *ReadingMeter, images ImageService, stories StoryService, trending TrendingService, quality QualityService, runs RunService, deadLetters DeadLetterService, stream StreamOptions) ArticleSaverService {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "news-ags"
+	}
+
 	return &ArticleSaverServiceImp{
 		ctx:               cont,
 		rClient:           redDB,
 		articleCollection: monDB,
-		articleLists:      make([]any, 0),
+		keywords:          keywords,
+		classifier:        classifier,
+		entities:          entities,
+		summarizer:        summarizer,
+		meter:             meter,
+		images:            images,
+		stories:           stories,
+		trending:          trending,
+		quality:           quality,
+		runs:              runs,
+		deadLetters:       deadLetters,
+		stream:            stream,
+		consumer:          fmt.Sprintf("%s:%d", host, os.Getpid()),
 	}
 }
 
-// RetrieveAllArticles retrieves all articles from the Redis cache
-func (aSS ArticleSaverServiceImp) retrieveAllArticles() ([]models.Article, error) {
-	// Key pattern for articles in Redis
-	keyPattern := "article:*"
+// claimStale takes over entries another saver read but never acknowledged,
+// usually because it crashed before the mongo write finished. The pending
+// list is paged through so stale entries behind ones a live saver holds are
+// still found. Entries delivered too often without being saved are dead
+// lettered instead, they would fail every run
+func (aSS ArticleSaverServiceImp) claimStale() ([]redis.XMessage, error) {
+	ids := make([]string, 0, aSS.stream.BatchSize)
+	capped := make(map[string]int64)
+	for start := "-"; int64(len(ids)) < aSS.stream.BatchSize; {
+		pending, err := aSS.rClient.XPendingExt(&redis.XPendingExtArgs{
+			Stream: articleStream,
+			Group:  articleSaverGroup,
+			Start:  start,
+			End:    "+",
+			Count:  aSS.stream.BatchSize,
+		}).Result()
+		if err == redis.Nil {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
 
-	// Get all keys matching the pattern
-	keys, err := aSS.rClient.Keys(keyPattern).Result()
-	if err != nil {
+		for _, entry := range pending {
+			switch {
+			case entry.Idle < aSS.stream.ClaimIdle:
+			case aSS.stream.MaxDeliveries > 0 && entry.RetryCount >= aSS.stream.MaxDeliveries:
+				capped[entry.Id] = entry.RetryCount
+			case int64(len(ids)) < aSS.stream.BatchSize:
+				ids = append(ids, entry.Id)
+			}
+		}
+		if int64(len(pending)) < aSS.stream.BatchSize {
+			break
+		}
+		start = nextStreamId(pending[len(pending)-1].Id)
+	}
+	if err := aSS.deadLetterCapped(capped); err != nil {
 		return nil, err
 	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
 
-	// Initialize a slice to store articles
-	var articles []models.Article
+	return aSS.rClient.XClaim(&redis.XClaimArgs{
+		Stream:   articleStream,
+		Group:    articleSaverGroup,
+		Consumer: aSS.consumer,
+		MinIdle:  aSS.stream.ClaimIdle,
+		Messages: ids,
+	}).Result()
+}
 
-	// Iterate over keys and get values
-	for _, key := range keys {
-		// Get the article JSON from Redis
-		jsonStr, err := aSS.rClient.Get(key).Result()
-		if err != nil {
-			return nil, err
+// deadLetterCapped moves entries past the delivery cap to the dead letters
+// and acknowledges them. They are claimed first so a saver still working on
+// one keeps it
+func (aSS ArticleSaverServiceImp) deadLetterCapped(deliveries map[string]int64) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(deliveries))
+	for id := range deliveries {
+		ids = append(ids, id)
+	}
+	messages, err := aSS.rClient.XClaim(&redis.XClaimArgs{
+		Stream:   articleStream,
+		Group:    articleSaverGroup,
+		Consumer: aSS.consumer,
+		MinIdle:  aSS.stream.ClaimIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	// entries claimed before the failure was recorded only have their count
+	failures, err := aSS.rClient.HMGet(articleFailures, ids...).Result()
+	if err != nil {
+		return err
+	}
+	lastFailure := make(map[string]string, len(ids))
+	for i, failure := range failures {
+		if failure, ok := failure.(string); ok {
+			lastFailure[ids[i]] = failure
 		}
+	}
 
-		var article models.Article
-		err = json.Unmarshal([]byte(jsonStr), &article)
-		if err != nil {
-			return nil, err
+	claimed := make([]string, 0, len(messages))
+	for _, message := range messages {
+		source, category := "", ""
+		if article, err := decodeStreamArticle(message.Values); err == nil {
+			row := articleRunRow(article)
+			source, category = row.source, row.category
 		}
 
-		articles = append(articles, article)
+		reason := fmt.Errorf("stream entry %s was not saved after %d deliveries", message.ID, deliveries[message.ID])
+		if failure := lastFailure[message.ID]; failure != "" {
+			reason = fmt.Errorf("%w: %s", reason, failure)
+		}
+		utils.LogErrorToFile("dead letter stream entry "+message.ID, reason.Error())
+		aSS.deadLetters.Capture(models.DeadLetterStageStream, source, category, streamPayload(message.Values), reason)
+		claimed = append(claimed, message.ID)
 	}
 
-	return articles, nil
+	if err := aSS.rClient.XAck(articleStream, articleSaverGroup, claimed...).Err(); err != nil {
+		return err
+	}
+	return aSS.rClient.HDel(articleFailures, ids...).Err()
 }
 
-func (aSS ArticleSaverServiceImp) compareArticles(lst []models.Article) ([]models.Article, error) {
-	result := make([]models.Article, len(lst))
-	copy(result, lst)
+// trimStream drops the entries every saver acknowledged, those before the
+// oldest pending entry and the last one delivered to the group. Entries a
+// saver still holds or never read are kept
+func (aSS ArticleSaverServiceImp) trimStream() error {
+	groups, err := aSS.rClient.Do("XINFO", "GROUPS", articleStream).Result()
+	if err != nil {
+		return err
+	}
 
-	for i := 0; i < len(lst); i++ {
-		for j := i + 1; j < len(lst); j++ {
-			if i == j {
-				continue
-			}
-			if strings.Join(lst[i].Keywords, ":") != strings.Join(lst[j].Keywords, ":") {
-				continue
+	lastDelivered := ""
+	entries, _ := groups.([]interface{})
+	for _, entry := range entries {
+		fields, _ := entry.([]interface{})
+		info := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			if key, ok := fields[i].(string); ok {
+				info[key] = fields[i+1]
 			}
+		}
+		if info["name"] == articleSaverGroup {
+			lastDelivered, _ = info["last-delivered-id"].(string)
+		}
+	}
+	if lastDelivered == "" || lastDelivered == "0-0" {
+		return nil
+	}
+
+	minId := nextStreamId(lastDelivered)
+	pending, err := aSS.rClient.XPending(articleStream, articleSaverGroup).Result()
+	if err != nil {
+		return err
+	}
+	if pending.Count > 0 {
+		minId = pending.Lower
+	}
+
+	return aSS.rClient.Do("XTRIM", articleStream, "MINID", "~", minId).Err()
+}
+
+// readArticles reads the next batch of entries never delivered to the group
+func (aSS ArticleSaverServiceImp) readArticles() ([]redis.XMessage, error) {
+	streams, err := aSS.rClient.XReadGroup(&redis.XReadGroupArgs{
+		Group:    articleSaverGroup,
+		Consumer: aSS.consumer,
+		Streams:  []string{articleStream, ">"},
+		Count:    aSS.stream.BatchSize,
+		Block:    -1,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []redis.XMessage
+	for _, stream := range streams {
+		messages = append(messages, stream.Messages...)
+	}
+
+	return messages, nil
+}
+
+// compareArticles drops near duplicates within the batch and against articles
+// already in mongo, keeping the copy with the highest source weight. Persisted
+// articles beaten by a batch copy are returned so they can be replaced
+func (aSS ArticleSaverServiceImp) compareArticles(lst []models.Article) ([]models.Article, []primitive.ObjectID, error) {
+	batch := clusterDuplicates(lst)
+	filter, ok := duplicateFilter(batch, aSS.stream.DedupWindow)
+	if !ok {
+		return batch, nil, nil
+	}
 
-			score := utils.CalculateSimilarity(lst[i].Content, lst[j].Content)
-			// Check if the similarity score is greater than 0.8
-			if score > 0.008988012168019019 {
-				// Remove the article with the higher weight
-				if result[i].Weight > result[j].Weight {
-					result = append(result[:j], result[j+1:]...)
-				} else {
-					result = append(result[:i], result[i+1:]...)
+	projection := options.Find().SetProjection(bson.M{"fingerprint": 1, "fingerprint_bands": 1, "canonical_key": 1, "source_priority": 1, "content_length": 1})
+	cursor, err := aSS.articleCollection.Find(aSS.ctx, filter, projection)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(aSS.ctx)
+
+	var persisted []models.Article
+	if err := cursor.All(aSS.ctx, &persisted); err != nil {
+		return nil, nil, err
+	}
+
+	buckets := make(map[string][]int)
+	for i, article := range persisted {
+		for _, band := range article.FingerprintBands {
+			buckets[band] = append(buckets[band], i)
+		}
+	}
+
+	kept := make([]models.Article, 0, len(batch))
+	superseded := make([]primitive.ObjectID, 0)
+	for _, article := range batch {
+		fingerprint, ok := articleFingerprint(&article)
+		if !ok {
+			kept = append(kept, article)
+			continue
+		}
+
+		duplicates := make(map[int]struct{})
+		for _, band := range article.FingerprintBands {
+			for _, i := range buckets[band] {
+				// the same article seen again is updated in place, not deduplicated
+				if persisted[i].CanonicalKey == article.CanonicalKey {
+					continue
 				}
+				other, err := utils.ParseFingerprint(persisted[i].Fingerprint)
+				if err != nil || other == 0 || utils.HammingDistance(fingerprint, other) > utils.NearDuplicateDistance {
+					continue
+				}
+				duplicates[i] = struct{}{}
+			}
+		}
+
+		keep := true
+		for i := range duplicates {
+			if !preferArticle(article, persisted[i]) {
+				keep = false
+				break
 			}
+		}
+		if !keep {
+			continue
+		}
 
+		for i := range duplicates {
+			superseded = append(superseded, persisted[i].Id)
 		}
+		kept = append(kept, article)
 	}
 
-	return nil, nil
+	return kept, superseded, nil
 }
 
-func (aSS ArticleSaverServiceImp) SaveArticles() error {
-	var wg sync.WaitGroup
-	categoryMap := make(map[string][]models.Article)
-	articles, err := aSS.retrieveAllArticles()
-	if err != nil {
+// duplicateFilter finds the stored articles sharing a fingerprint band with
+// the batch, published within window of its articles when window is set. It
+// reports false when no article of the batch has a fingerprint
+func duplicateFilter(batch []models.Article, window time.Duration) (bson.M, bool) {
+	bands := make([]string, 0, len(batch)*4)
+	var earliest, latest time.Time
+	for _, article := range batch {
+		bands = append(bands, article.FingerprintBands...)
+		if article.PublishedAt.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || article.PublishedAt.Before(earliest) {
+			earliest = article.PublishedAt
+		}
+		if article.PublishedAt.After(latest) {
+			latest = article.PublishedAt
+		}
+	}
+	if len(bands) == 0 {
+		return nil, false
+	}
+
+	filter := bson.M{"fingerprint_bands": bson.M{"$in": bands}}
+	if window > 0 && !earliest.IsZero() {
+		filter["published_at"] = bson.M{"$gte": earliest.Add(-window), "$lte": latest.Add(window)}
+	}
+
+	return filter, true
+}
+
+// SaveArticles drains the article stream into mongo in batches, entries are
+// only acknowledged after their batch was written. The run is recorded in the
+// run history and returned, even when it stopped early
+func (aSS ArticleSaverServiceImp) SaveArticles() (*models.IngestionRun, error) {
+	run := aSS.runs.Start(models.RunKindSave)
+	err := aSS.drain(run)
+	aSS.runs.Finish(run, err)
+
+	return run, err
+}
+
+func (aSS ArticleSaverServiceImp) drain(run *models.IngestionRun) error {
+	err := aSS.rClient.XGroupCreateMkStream(articleStream, articleSaverGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
 		return err
 	}
 
-	for _, article := range articles {
-		key := strings.Join(article.Category, " : ")
-		categoryMap[key] = append(categoryMap[key], article)
+	if err := aSS.createIndexes(); err != nil {
+		return err
+	}
+	if err := aSS.keywords.createIndexes(); err != nil {
+		return err
+	}
+	if err := aSS.stories.createIndexes(); err != nil {
+		return err
+	}
+	if aSS.images != nil {
+		if err := aSS.images.createIndexes(); err != nil {
+			return err
+		}
 	}
-	ch := make(chan []models.Article, len(categoryMap)+1)
 
-	for _, catArticles := range categoryMap {
-		wg.Add(1)
-		go func(l []models.Article) {
-			defer wg.Done()
-			res, err := aSS.compareArticles(l)
+	failed := aSS.consume(run, aSS.saveBatch)
+
+	// the saved entries are only wasting memory, a failed trim is caught up
+	// by the next run
+	if err := aSS.trimStream(); err != nil {
+		utils.LogErrorToFile("trim article stream", err.Error())
+	}
+
+	return failed
+}
+
+// consume hands the stale entries and then the unread ones to save batch by
+// batch. A failing batch stays pending with its error recorded and the saver
+// moves on to the next reader, so an entry that can never be saved only holds
+// back the entries read along with it until the delivery cap dead letters
+// them. The error of the last failed batch is returned
+func (aSS ArticleSaverServiceImp) consume(run *models.IngestionRun, save func(*models.IngestionRun, []redis.XMessage) error) error {
+	var failed error
+	for _, read := range []func() ([]redis.XMessage, error){aSS.claimStale, aSS.readArticles} {
+		for {
+			messages, err := read()
 			if err != nil {
-				ch <- res
+				return err
+			}
+			if len(messages) == 0 {
+				break
+			}
+
+			ids := make([]string, 0, len(messages))
+			for _, message := range messages {
+				ids = append(ids, message.ID)
 			}
 
-		}(catArticles)
+			// the failure is kept for the dead letter of entries that never get
+			// saved, a lost record only costs the letter its reason
+			if err := save(run, messages); err != nil {
+				utils.LogErrorToFile("save stream batch", err.Error())
+				failures := make(map[string]interface{}, len(ids))
+				for _, id := range ids {
+					failures[id] = err.Error()
+				}
+				if err := aSS.rClient.HMSet(articleFailures, failures).Err(); err != nil {
+					utils.LogErrorToFile("record stream failures", err.Error())
+				}
+				failed = err
+				break
+			}
+			if err := aSS.rClient.HDel(articleFailures, ids...).Err(); err != nil {
+				utils.LogErrorToFile("clear stream failures", err.Error())
+			}
+		}
+	}
+
+	return failed
+}
+
+// runRow is the source and category an article is counted under in the run
+// history, taken before classification can change the category
+type runRow struct {
+	source   string
+	category string
+}
+
+func articleRunRow(article models.Article) runRow {
+	row := runRow{source: article.Source}
+	if len(article.Category) > 0 {
+		row.category = article.Category[0]
+	}
+
+	return row
+}
+
+// captureRejection keeps a rejected article as a dead letter so it can be
+// looked at and retried once the rules change. The id and ingestion time are
+// left out, they differ on every scrape and would keep the same article
+// rejected again from folding into its letter. So is a publish time taken
+// from the ingestion time, saving the article stamps both again
+func (aSS ArticleSaverServiceImp) captureRejection(rejection models.QualityRejection) {
+	article := rejection.Article
+	if article.PublishedAt.Equal(article.IngestedAt) {
+		article.PublishedAt = time.Time{}
 	}
+	article.Id = primitive.NilObjectID
+	article.IngestedAt = time.Time{}
 
-	for articles := range ch {
-		aSS.articleLists = append(aSS.articleLists, articles)
+	category := ""
+	if len(article.Category) > 0 {
+		category = article.Category[0]
 	}
 
-	_, err = aSS.articleCollection.InsertMany(aSS.ctx, aSS.articleLists)
+	payload, _ := json.Marshal(article)
+	aSS.deadLetters.Capture(models.DeadLetterStageQuality, article.Source, category, payload, fmt.Errorf("rejected by the quality rules: %s", rejection.Reason))
+}
+
+func (aSS ArticleSaverServiceImp) saveBatch(run *models.IngestionRun, messages []redis.XMessage) error {
+	now := time.Now()
+	ids := make([]string, 0, len(messages))
+	articles := make([]models.Article, 0, len(messages))
+	// rows are keyed on the canonical key, the story assignment swaps the id
+	// of an article seen before for the id of its stored copy
+	rows := make(map[string]runRow, len(messages))
+	counts := make(map[runRow]*models.RunCounts)
+	count := func(row runRow) *models.RunCounts {
+		if counts[row] == nil {
+			counts[row] = &models.RunCounts{}
+		}
+		return counts[row]
+	}
+
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+
+		article, err := decodeStreamArticle(message.Values)
+		if err != nil {
+			utils.LogErrorToFile("decode stream entry "+message.ID, err.Error())
+			aSS.deadLetters.Capture(models.DeadLetterStageDecode, "", "", streamPayload(message.Values), err)
+			unknown := count(runRow{source: models.RunSourceUnknown})
+			unknown.Fetched++
+			unknown.Failed++
+			run.Sample(nil, "decode stream entry "+message.ID+": "+err.Error())
+			continue
+		}
+		stampArticle(&article, now)
+		canonicalizeArticle(&article)
+		articles = append(articles, article)
+
+		row := articleRunRow(article)
+		rows[article.CanonicalKey] = row
+		count(row).Fetched++
+		count(row).Parsed++
+	}
+
+	articles, rejected, err := aSS.quality.Check(articles)
 	if err != nil {
 		return err
 	}
+	for _, rejection := range rejected {
+		count(rows[rejection.Article.CanonicalKey]).Reject(rejection.Reason, 1)
+		aSS.captureRejection(rejection)
+	}
+
+	candidates := articles
+	articles, superseded, err := aSS.compareArticles(articles)
+	if err != nil {
+		return err
+	}
+	if len(articles) < len(candidates) {
+		// the same url twice in a batch is kept once
+		kept := make(map[string]int, len(articles))
+		for _, article := range articles {
+			kept[article.CanonicalKey]++
+		}
+		for _, article := range candidates {
+			if kept[article.CanonicalKey] > 0 {
+				kept[article.CanonicalKey]--
+				continue
+			}
+			count(rows[article.CanonicalKey]).Deduplicated++
+		}
+	}
+
+	if len(superseded) > 0 {
+		if _, err := aSS.articleCollection.DeleteMany(aSS.ctx, bson.M{"_id": bson.M{"$in": superseded}}); err != nil {
+			return err
+		}
+		if err := aSS.stories.Detach(superseded); err != nil {
+			return err
+		}
+	}
+
+	detectLanguages(articles)
+	aSS.classifier.Classify(articles)
+	aSS.entities.Extract(articles)
+	scoreSentiment(articles)
+	aSS.summarizer.Summarize(articles)
+	aSS.meter.Measure(articles)
+	if aSS.images != nil {
+		aSS.images.Process(articles)
+	}
+
+	if err := aSS.keywords.Extract(articles); err != nil {
+		return err
+	}
 
+	if err := aSS.stories.Assign(articles); err != nil {
+		return err
+	}
+
+	inserted, err := aSS.upsertArticles(articles)
+	if err != nil {
+		return err
+	}
+
+	for _, article := range articles {
+		count(rows[article.CanonicalKey]).Updated++
+	}
+	for _, article := range inserted {
+		count(rows[article.CanonicalKey]).Updated--
+		count(rows[article.CanonicalKey]).Inserted++
+	}
+
+	// the batch only joins the run once it is written, a failing batch stays
+	// in the stream and is counted by the run that saves it
+	for row, batch := range counts {
+		run.Row(row.source, row.category).Add(*batch)
+	}
+
+	// corpus statistics, trending counts and image uses are best effort, a
+	// missed batch only skews the weights slightly
+	if err := aSS.keywords.Record(inserted); err != nil {
+		utils.LogErrorToFile("record corpus statistics", err.Error())
+	}
+	if err := aSS.trending.Record(inserted); err != nil {
+		utils.LogErrorToFile("record trending counts", err.Error())
+	}
+	if aSS.images != nil {
+		if err := aSS.images.Record(inserted); err != nil {
+			utils.LogErrorToFile("record image uses", err.Error())
+		}
+	}
+
+	return aSS.rClient.XAck(articleStream, articleSaverGroup, ids...).Err()
+}
+
+func (aSS ArticleSaverServiceImp) createIndexes() error {
 	// Index model for categories
 	categoriesIndex := mongo.IndexModel{
 		Keys:    bson.M{"category": 1},
 		Options: options.Index().SetUnique(false),
 	}
 
-	// Index model for content
-	contentIndex := mongo.IndexModel{
-		Keys: bson.D{{Key: "content", Value: "text"}},
+	// Index model for title and content search, mongo allows a single text index per collection.
+	// Every article is analyzed in its own language
+	textIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "title", Value: "text"}, {Key: "content", Value: "text"}},
+		Options: options.Index().SetLanguageOverride(textLanguageField),
+	}
+	if err := aSS.dropStaleTextIndex(); err != nil {
+		return err
 	}
 
-	// Index for title search
-	// Index model for categories
-	titleIndex := mongo.IndexModel{Keys: bson.D{{Key: "title", Value: "text"}}}
+	// Index model for language preferences
+	languageIndex := mongo.IndexModel{Keys: bson.D{{Key: "language", Value: 1}, {Key: "published_at", Value: -1}}}
+
+	// Index model for the feed ordering
+	publishedIndex := mongo.IndexModel{Keys: bson.D{{Key: "published_at", Value: -1}}}
+	ingestedIndex := mongo.IndexModel{Keys: bson.D{{Key: "ingested_at", Value: -1}}}
+
+	// Index model for related article lookups
+	keywordsIndex := mongo.IndexModel{Keys: bson.M{"keyword_weights.term": 1}}
+
+	// Index model for quick read filters
+	readingIndex := mongo.IndexModel{Keys: bson.D{{Key: "reading_time", Value: 1}, {Key: "published_at", Value: -1}}}
+
+	// Index model for tone filters
+	sentimentIndex := mongo.IndexModel{Keys: bson.D{{Key: "tone", Value: 1}, {Key: "published_at", Value: -1}}}
+
+	// Index model for entity filters
+	entitiesIndex := mongo.IndexModel{Keys: bson.M{"entities.id": 1}}
+
+	// Index model for entity lookups by name prefix
+	entityNamesIndex := mongo.IndexModel{Keys: bson.M{"entities.name_keys": 1}}
+
+	// Index model for near duplicate lookups
+	fingerprintIndex := mongo.IndexModel{Keys: bson.D{{Key: "fingerprint_bands", Value: 1}, {Key: "published_at", Value: -1}}}
+
+	// Index model for the duplicate title guard
+	titleIndex := mongo.IndexModel{Keys: bson.D{{Key: "title_key", Value: 1}, {Key: "published_at", Value: -1}}}
+
+	// Index model for upserts, documents saved before canonical keys existed are left out
+	canonicalIndex := mongo.IndexModel{
+		Keys: bson.M{"canonical_key": 1},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"canonical_key": bson.M{"$type": "string"}}),
+	}
 
 	// Create indexes
-	if _, err := aSS.articleCollection.Indexes().CreateMany(aSS.ctx, []mongo.IndexModel{categoriesIndex, contentIndex, titleIndex}); err != nil {
+	_, err := aSS.articleCollection.Indexes().CreateMany(aSS.ctx, []mongo.IndexModel{categoriesIndex, textIndex, languageIndex, publishedIndex, ingestedIndex, keywordsIndex, readingIndex, sentimentIndex, entitiesIndex, entityNamesIndex, fingerprintIndex, titleIndex, canonicalIndex})
+	return err
+}
+
+// dropStaleTextIndex drops a text index built before articles carried their
+// language, it can not be changed in place
+func (aSS ArticleSaverServiceImp) dropStaleTextIndex() error {
+	cursor, err := aSS.articleCollection.Indexes().List(aSS.ctx)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(aSS.ctx)
+
+	var indexes []bson.M
+	if err := cursor.All(aSS.ctx, &indexes); err != nil {
 		return err
 	}
 
+	for _, index := range indexes {
+		if _, ok := index["textIndexVersion"]; !ok || index["language_override"] == textLanguageField {
+			continue
+		}
+		name, _ := index["name"].(string)
+		if _, err := aSS.articleCollection.Indexes().DropOne(aSS.ctx, name); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// upsertArticles writes the batch keyed on the canonical url so re-running the
+// saver updates articles instead of duplicating them. The revision only moves
+// when the article body changed since it was last seen. The articles stored
+// for the first time are returned
+func (aSS ArticleSaverServiceImp) upsertArticles(articles []models.Article) ([]models.Article, error) {
+	if len(articles) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(articles))
+	for i := range articles {
+		canonicalizeArticle(&articles[i])
+		keys = append(keys, articles[i].CanonicalKey)
+	}
+
+	projection := options.Find().SetProjection(bson.M{"canonical_key": 1, "fingerprint": 1})
+	cursor, err := aSS.articleCollection.Find(aSS.ctx, bson.M{"canonical_key": bson.M{"$in": keys}}, projection)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(aSS.ctx)
+
+	var existing []models.Article
+	if err := cursor.All(aSS.ctx, &existing); err != nil {
+		return nil, err
+	}
+
+	fingerprints := make(map[string]string, len(existing))
+	for _, article := range existing {
+		fingerprints[article.CanonicalKey] = article.Fingerprint
+	}
+
+	writes, err := upsertWrites(articles, fingerprints, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := aSS.articleCollection.BulkWrite(aSS.ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return nil, err
+	}
+
+	inserted := make([]models.Article, 0, len(result.UpsertedIDs))
+	for i := range result.UpsertedIDs {
+		inserted = append(inserted, articles[i])
+	}
+
+	return inserted, nil
+}
+
+// upsertWrites builds the upserts of upsertArticles from the fingerprints of
+// the copies already stored, keyed on their canonical key
+func upsertWrites(articles []models.Article, fingerprints map[string]string, now time.Time) ([]mongo.WriteModel, error) {
+	writes := make([]mongo.WriteModel, 0, len(articles))
+	for _, article := range articles {
+		article.LastSeenAt = now
+
+		fields, err := articleFields(article)
+		if err != nil {
+			return nil, err
+		}
+
+		revision := 1
+		if fingerprint, ok := fingerprints[article.CanonicalKey]; ok && fingerprint == article.Fingerprint {
+			revision = 0
+		}
+
+		update := bson.M{
+			"$set":         fields,
+			"$setOnInsert": bson.M{"_id": article.Id, "first_seen_at": now, "ingested_at": article.IngestedAt},
+			"$inc":         bson.M{"revision": revision},
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"canonical_key": article.CanonicalKey}).
+			SetUpdate(update).
+			SetUpsert(true))
+	}
+
+	return writes, nil
+}
+
+// articleFields returns the fields an upsert overwrites, leaving the ones owned
+// by the first insert alone
+func articleFields(article models.Article) (bson.M, error) {
+	data, err := bson.Marshal(article)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields bson.M
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	delete(fields, "_id")
+	delete(fields, "first_seen_at")
+	delete(fields, "ingested_at")
+	delete(fields, "revision")
+
+	return fields, nil
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newTestSaver(t *testing.T, client *redis.Client, consumer string) ArticleSaverServiceImp {
+	t.Helper()
+
+	if err := client.XGroupCreateMkStream(articleStream, articleSaverGroup, "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return ArticleSaverServiceImp{
+		rClient:  client,
+		stream:   StreamOptions{BatchSize: 2, ClaimIdle: time.Minute},
+		consumer: consumer,
+	}
+}
+
+func addEntries(t *testing.T, client *redis.Client, n int) []string {
+	t.Helper()
+
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		id, err := client.XAdd(&redis.XAddArgs{Stream: articleStream, Values: map[string]interface{}{articleField: "{}"}}).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func readEntries(t *testing.T, client *redis.Client, consumer string, n int64) {
+	t.Helper()
+
+	err := client.XReadGroup(&redis.XReadGroupArgs{
+		Group:    articleSaverGroup,
+		Consumer: consumer,
+		Streams:  []string{articleStream, ">"},
+		Count:    n,
+		Block:    -1,
+	}).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClaimStalePagesPastLiveEntries(t *testing.T) {
+	server, client := newTestRedis(t)
+	start := time.Date(2023, 12, 1, 12, 0, 0, 0, time.UTC)
+	server.SetTime(start)
+
+	saver := newTestSaver(t, client, "saver-b")
+	ids := addEntries(t, client, 5)
+
+	// a saver that crashed read four entries, a live one took over the first two
+	readEntries(t, client, "crashed", 4)
+	server.SetTime(start.Add(10 * time.Minute))
+	err := client.XClaim(&redis.XClaimArgs{
+		Stream:   articleStream,
+		Group:    articleSaverGroup,
+		Consumer: "saver-a",
+		Messages: ids[:2],
+	}).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err := saver.claimStale()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != ids[2] || claimed[1].ID != ids[3] {
+		t.Fatalf("claimed %v, want %v", claimed, ids[2:4])
+	}
+
+	claimed, err = saver.claimStale()
+	if err != nil || len(claimed) != 0 {
+		t.Fatalf("second claim = %v, %v, want nothing left", claimed, err)
+	}
+}
+
+func TestTrimStreamKeepsUnacknowledged(t *testing.T) {
+	_, client := newTestRedis(t)
+	saver := newTestSaver(t, client, "saver-a")
+	ids := addEntries(t, client, 6)
+
+	// two saved, one still held and three never read
+	readEntries(t, client, "saver-a", 3)
+	if err := client.XAck(articleStream, articleSaverGroup, ids[0], ids[1]).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saver.trimStream(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := client.XRange(articleStream, "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[0].ID != ids[2] {
+		t.Fatalf("stream starts at %s with %d entries, want %s with 4", entries[0].ID, len(entries), ids[2])
+	}
+
+	// once everything read is saved only the unread entries stay
+	if err := client.XAck(articleStream, articleSaverGroup, ids[2]).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := saver.trimStream(); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ = client.XRange(articleStream, "-", "+").Result()
+	if len(entries) != 3 || entries[0].ID != ids[3] {
+		t.Fatalf("stream holds %d entries, want the 3 unread", len(entries))
+	}
+}
+
+func TestNextStreamId(t *testing.T) {
+	tests := map[string]string{
+		"1700000000000-0": "1700000000000-1",
+		"1700000000000-9": "1700000000000-10",
+		"bogus":           "+",
+	}
+	for id, want := range tests {
+		if got := nextStreamId(id); got != want {
+			t.Errorf("nextStreamId(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestCaptureRejectionFoldsRepeats(t *testing.T) {
+	letters := &recordedLetters{}
+	saver := ArticleSaverServiceImp{deadLetters: letters}
+
+	for i := 0; i < 2; i++ {
+		article := models.Article{
+			Id:       primitive.NewObjectID(),
+			Title:    "Sponsored post",
+			URL:      "https://example.com/sponsored",
+			Source:   "example",
+			Category: []string{"business"},
+		}
+		stampArticle(&article, time.Now().Add(time.Duration(i)*time.Hour))
+		saver.captureRejection(models.QualityRejection{Article: article, Reason: models.RejectBlockedKeyword})
+	}
+
+	if len(letters.letters) != 2 {
+		t.Fatalf("captured %d letters, want 2", len(letters.letters))
+	}
+	first, second := letters.letters[0], letters.letters[1]
+	if first.Stage != models.DeadLetterStageQuality || first.Source != "example" || first.Category != "business" {
+		t.Errorf("letter = %+v, want a quality letter of example/business", first)
+	}
+	if !strings.Contains(first.Reason, models.RejectBlockedKeyword) {
+		t.Errorf("reason %q does not name the rule", first.Reason)
+	}
+	// the same payload is what folds the repeat into one letter
+	if first.Payload != second.Payload {
+		t.Errorf("payloads differ between scrapes:\n%s\n%s", first.Payload, second.Payload)
+	}
+}
+
+func TestConsumeDeadLettersEntriesPastTheDeliveryCap(t *testing.T) {
+	_, client := newTestRedis(t)
+	letters := &recordedLetters{}
+	saver := newTestSaver(t, client, "saver-a")
+	saver.stream = StreamOptions{BatchSize: 2, MaxDeliveries: 3}
+	saver.deadLetters = letters
+
+	add := func(title string) string {
+		t.Helper()
+		payload := `{"title":"` + title + `","link":"https://example.com/` + title + `","source_id":"example","category":["business"]}`
+		id, err := client.XAdd(&redis.XAddArgs{Stream: articleStream, Values: map[string]interface{}{articleField: payload}}).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	// the poison entry fails its whole batch, every other batch is saved
+	poison := add("poison")
+	heldBack := add("held-back")
+	saved := make(map[string]bool)
+	save := func(run *models.IngestionRun, messages []redis.XMessage) error {
+		for _, message := range messages {
+			if message.ID == poison {
+				return errors.New("E11000 duplicate key error")
+			}
+		}
+		ids := make([]string, 0, len(messages))
+		for _, message := range messages {
+			saved[message.ID] = true
+			ids = append(ids, message.ID)
+		}
+		return client.XAck(articleStream, articleSaverGroup, ids...).Err()
+	}
+
+	for run := 1; run <= 4; run++ {
+		ids := []string{add(fmt.Sprintf("fresh-%d-a", run)), add(fmt.Sprintf("fresh-%d-b", run))}
+
+		err := saver.consume(&models.IngestionRun{}, save)
+		if run < 4 && err == nil {
+			t.Errorf("run %d hid the failed batch", run)
+		}
+		if run == 1 {
+			continue
+		}
+		// from the second run on the poison batch is claimed, fails and the
+		// saver still moves on to the new entries
+		for _, id := range ids {
+			if !saved[id] {
+				t.Errorf("run %d did not save the new entry %s", run, id)
+			}
+		}
+	}
+
+	if saved[poison] || saved[heldBack] {
+		t.Fatal("the failing batch was saved")
+	}
+	if len(letters.letters) != 2 {
+		t.Fatalf("dead lettered %d entries, want the 2 of the failing batch", len(letters.letters))
+	}
+	for _, letter := range letters.letters {
+		if letter.Stage != models.DeadLetterStageStream || letter.Source != "example" || letter.Category != "business" {
+			t.Errorf("letter = %+v, want a stream letter of example/business", letter)
+		}
+		if !strings.Contains(letter.Payload, `"title":"poison"`) && !strings.Contains(letter.Payload, `"title":"held-back"`) {
+			t.Errorf("letter payload %s is not the stream article", letter.Payload)
+		}
+	}
+
+	// the letters carry the batch error so they can be judged before a retry
+	for _, letter := range letters.letters {
+		if !strings.Contains(letter.Reason, "3 deliveries") || !strings.Contains(letter.Reason, "E11000") {
+			t.Errorf("reason %q does not tell why the entry was not saved", letter.Reason)
+		}
+	}
+	if failures, err := client.HLen(articleFailures).Result(); err != nil || failures != 0 {
+		t.Errorf("%d failures left recorded, %v", failures, err)
+	}
+
+	pending, err := client.XPending(articleStream, articleSaverGroup).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Count != 0 {
+		t.Errorf("%d entries still pending, want the dead lettered ones acknowledged", pending.Count)
+	}
+}
+
+func TestUpsertWritesRevision(t *testing.T) {
+	now := time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC)
+	ingested := now.Add(-time.Minute)
+	articles := []models.Article{
+		{Id: primitive.NewObjectID(), CanonicalKey: "https://example.com/new", Fingerprint: "00000000000000aa", IngestedAt: ingested},
+		{Id: primitive.NewObjectID(), CanonicalKey: "https://example.com/same", Fingerprint: "00000000000000bb", IngestedAt: ingested},
+		{Id: primitive.NewObjectID(), CanonicalKey: "https://example.com/edited", Fingerprint: "00000000000000cc", IngestedAt: ingested},
+	}
+	stored := map[string]string{
+		"https://example.com/same":   "00000000000000bb",
+		"https://example.com/edited": "00000000000000c0",
+	}
+
+	writes, err := upsertWrites(articles, stored, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(writes) != len(articles) {
+		t.Fatalf("built %d writes, want %d", len(writes), len(articles))
+	}
+
+	// a new article starts at revision 1 and an unchanged one keeps its revision
+	for i, want := range []int{1, 0, 1} {
+		write := writes[i].(*mongo.UpdateOneModel)
+		if filter := write.Filter.(bson.M); filter["canonical_key"] != articles[i].CanonicalKey {
+			t.Errorf("write %d filters on %v, want the canonical key", i, filter)
+		}
+		if write.Upsert == nil || !*write.Upsert {
+			t.Errorf("write %d is not an upsert", i)
+		}
+
+		update := write.Update.(bson.M)
+		if revision := update["$inc"].(bson.M)["revision"]; revision != want {
+			t.Errorf("write %d increments the revision by %v, want %d", i, revision, want)
+		}
+
+		onInsert := update["$setOnInsert"].(bson.M)
+		if onInsert["_id"] != articles[i].Id || onInsert["first_seen_at"] != now || onInsert["ingested_at"] != ingested {
+			t.Errorf("write %d sets %v on insert, want the id, first seen and ingested times", i, onInsert)
+		}
+
+		fields := update["$set"].(bson.M)
+		for _, owned := range []string{"_id", "first_seen_at", "ingested_at", "revision"} {
+			if _, ok := fields[owned]; ok {
+				t.Errorf("write %d overwrites %s", i, owned)
+			}
+		}
+		if fields["fingerprint"] != articles[i].Fingerprint || fields["last_seen_at"] != primitive.NewDateTimeFromTime(now) {
+			t.Errorf("write %d sets fingerprint %v and last seen %v", i, fields["fingerprint"], fields["last_seen_at"])
+		}
+	}
+}
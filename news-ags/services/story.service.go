@@ -0,0 +1,565 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// storyLookupTerms is how many of an article's keywords are used to find candidate stories
+	storyLookupTerms = 8
+	// storyCentroidTerms bounds the keyword vector kept on a story
+	storyCentroidTerms = 30
+	// storyCandidates bounds the stories loaded for a single batch
+	storyCandidates = 500
+
+	// keywords carry most of the similarity, the headline words catch short articles
+	storyKeywordShare = 0.7
+	storyTitleShare   = 0.3
+)
+
+// StoryService clusters related articles into stories. An article joins the
+// most similar story active within the time window, or starts a new one
+type StoryService interface {
+	Assign(articles []models.Article) error
+	Detach(ids []primitive.ObjectID) error
+	createIndexes() error
+}
+
+type StoryServiceImp struct {
+	ctx               context.Context
+	storyCollection   *mongo.Collection
+	articleCollection *mongo.Collection
+	window            time.Duration
+	threshold         float64
+}
+
+func NewStoryService(ctx context.Context, stories, articles *mongo.Collection, window time.Duration, threshold float64) StoryService {
+	return &StoryServiceImp{
+		ctx:               ctx,
+		storyCollection:   stories,
+		articleCollection: articles,
+		window:            window,
+		threshold:         threshold,
+	}
+}
+
+// storyState is a story being built up while a batch is clustered
+type storyState struct {
+	story    models.Story
+	vector   map[string]float64
+	headline []string
+	created  bool
+	added    []models.Article
+}
+
+// Assign sets the story id of every article in the batch. Articles already
+// stored keep their story and their stored id, so story members always point
+// at the persisted document
+func (ss StoryServiceImp) Assign(articles []models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	if err := ss.resolveStored(articles); err != nil {
+		return err
+	}
+
+	pending := make([]*models.Article, 0, len(articles))
+	for i := range articles {
+		if articles[i].StoryId.IsZero() {
+			pending = append(pending, &articles[i])
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].PublishedAt.Before(pending[j].PublishedAt)
+	})
+
+	states, err := ss.candidates(pending)
+	if err != nil {
+		return err
+	}
+
+	for _, article := range pending {
+		vector := keywordVector(article.KeywordWeights)
+		title := utils.Tokenize(article.Title)
+
+		var (
+			best      *storyState
+			bestScore float64
+		)
+		for _, state := range states {
+			if article.PublishedAt.Before(state.story.FirstPublishedAt.Add(-ss.window)) ||
+				article.PublishedAt.After(state.story.LastPublishedAt.Add(ss.window)) {
+				continue
+			}
+
+			score := storyKeywordShare*utils.CosineSimilarity(vector, state.vector) +
+				storyTitleShare*utils.JaccardSimilarity(title, state.headline)
+			if score > bestScore {
+				best, bestScore = state, score
+			}
+		}
+
+		if best == nil || bestScore < ss.threshold {
+			best = newStoryState(*article)
+			states = append(states, best)
+		} else {
+			best.join(*article, vector)
+		}
+		article.StoryId = best.story.Id
+	}
+
+	return ss.write(states)
+}
+
+// resolveStored copies the id and story of the stored copy of every article
+func (ss StoryServiceImp) resolveStored(articles []models.Article) error {
+	keys := make([]string, 0, len(articles))
+	for i := range articles {
+		canonicalizeArticle(&articles[i])
+		keys = append(keys, articles[i].CanonicalKey)
+	}
+
+	projection := options.Find().SetProjection(bson.M{"canonical_key": 1, "story_id": 1})
+	cursor, err := ss.articleCollection.Find(ss.ctx, bson.M{"canonical_key": bson.M{"$in": keys}}, projection)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ss.ctx)
+
+	var stored []models.Article
+	if err := cursor.All(ss.ctx, &stored); err != nil {
+		return err
+	}
+
+	byKey := make(map[string]models.Article, len(stored))
+	for _, article := range stored {
+		byKey[article.CanonicalKey] = article
+	}
+
+	for i := range articles {
+		if existing, ok := byKey[articles[i].CanonicalKey]; ok {
+			articles[i].Id = existing.Id
+			articles[i].StoryId = existing.StoryId
+		}
+	}
+
+	return nil
+}
+
+// candidates loads the stories sharing a keyword with the batch that were
+// active around the time it was published
+func (ss StoryServiceImp) candidates(pending []*models.Article) ([]*storyState, error) {
+	terms := make([]string, 0)
+	seen := make(map[string]bool)
+	from, to := pending[0].PublishedAt, pending[len(pending)-1].PublishedAt
+	for _, article := range pending {
+		for i, keyword := range article.KeywordWeights {
+			if i >= storyLookupTerms {
+				break
+			}
+			if !seen[keyword.Term] {
+				seen[keyword.Term] = true
+				terms = append(terms, keyword.Term)
+			}
+		}
+	}
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	filter := bson.M{
+		"keyword_weights.term": bson.M{"$in": terms},
+		"last_published_at":    bson.M{"$gte": from.Add(-ss.window)},
+		"first_published_at":   bson.M{"$lte": to.Add(ss.window)},
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "last_published_at", Value: -1}}).
+		SetLimit(storyCandidates).
+		SetProjection(bson.M{"article_ids": 0})
+
+	cursor, err := ss.storyCollection.Find(ss.ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ss.ctx)
+
+	var stories []models.Story
+	if err := cursor.All(ss.ctx, &stories); err != nil {
+		return nil, err
+	}
+
+	states := make([]*storyState, 0, len(stories))
+	for _, story := range stories {
+		states = append(states, &storyState{
+			story:    story,
+			vector:   keywordVector(story.KeywordWeights),
+			headline: utils.Tokenize(story.Headline),
+		})
+	}
+
+	return states, nil
+}
+
+func newStoryState(article models.Article) *storyState {
+	now := time.Now().UTC()
+	state := &storyState{
+		story: models.Story{
+			Id:                primitive.NewObjectID(),
+			Headline:          article.Title,
+			HeadlineArticleId: article.Id,
+			HeadlineWeight:    article.Weight,
+			ArticleIds:        []primitive.ObjectID{article.Id},
+			ArticleCount:      1,
+			Sources:           []string{article.Source},
+			SourcesCount:      1,
+			Categories:        append([]string{}, article.Category...),
+			KeywordWeights:    topKeywords(keywordVector(article.KeywordWeights)),
+			SentimentSum:      article.Sentiment,
+			Sentiment:         article.Sentiment,
+			Tone:              article.Tone,
+			FirstPublishedAt:  article.PublishedAt,
+			LastPublishedAt:   article.PublishedAt,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		},
+		created: true,
+	}
+	state.vector = keywordVector(state.story.KeywordWeights)
+	state.headline = utils.Tokenize(article.Title)
+
+	return state
+}
+
+// join adds the article to the story in memory, the keyword centroid is the
+// running mean of the member vectors
+func (st *storyState) join(article models.Article, vector map[string]float64) {
+	story := &st.story
+	members := float64(story.ArticleCount)
+
+	centroid := make(map[string]float64, len(st.vector)+len(vector))
+	for term, weight := range st.vector {
+		centroid[term] = weight * members / (members + 1)
+	}
+	for term, weight := range vector {
+		centroid[term] += weight / (members + 1)
+	}
+	story.KeywordWeights = topKeywords(centroid)
+	st.vector = keywordVector(story.KeywordWeights)
+
+	if article.Weight > story.HeadlineWeight {
+		story.Headline = article.Title
+		story.HeadlineArticleId = article.Id
+		story.HeadlineWeight = article.Weight
+		st.headline = utils.Tokenize(article.Title)
+	}
+	if article.PublishedAt.Before(story.FirstPublishedAt) {
+		story.FirstPublishedAt = article.PublishedAt
+	}
+	if article.PublishedAt.After(story.LastPublishedAt) {
+		story.LastPublishedAt = article.PublishedAt
+	}
+
+	story.ArticleIds = append(story.ArticleIds, article.Id)
+	story.ArticleCount++
+	story.SentimentSum += article.Sentiment
+	story.Sentiment = story.SentimentSum / float64(story.ArticleCount)
+	story.Tone = models.Tone(story.Sentiment)
+	if !contains(story.Sources, article.Source) {
+		story.Sources = append(story.Sources, article.Source)
+		story.SourcesCount++
+	}
+	for _, category := range article.Category {
+		if !contains(story.Categories, category) {
+			story.Categories = append(story.Categories, category)
+		}
+	}
+	story.UpdatedAt = time.Now().UTC()
+
+	st.added = append(st.added, article)
+}
+
+// write inserts the new stories and merges the members added to the stored
+// ones, sets are merged on the server so concurrent savers do not lose members
+func (ss StoryServiceImp) write(states []*storyState) error {
+	writes := make([]mongo.WriteModel, 0, len(states))
+	for _, state := range states {
+		story := state.story
+
+		if state.created {
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(story))
+			continue
+		}
+		if len(state.added) == 0 {
+			continue
+		}
+
+		ids := make([]primitive.ObjectID, 0, len(state.added))
+		sources := make([]string, 0, len(state.added))
+		headliner := state.added[0]
+		sentiment := 0.0
+		for _, article := range state.added {
+			ids = append(ids, article.Id)
+			sentiment += article.Sentiment
+			sources = append(sources, article.Source)
+			if article.Weight > headliner.Weight {
+				headliner = article
+			}
+		}
+
+		replaceHeadline := bson.M{"$gt": bson.A{headliner.Weight, "$headline_weight"}}
+		update := bson.A{
+			bson.M{"$set": bson.M{
+				"article_ids":         bson.M{"$setUnion": bson.A{"$article_ids", ids}},
+				"sources":             bson.M{"$setUnion": bson.A{"$sources", sources}},
+				"categories":          bson.M{"$setUnion": bson.A{"$categories", story.Categories}},
+				"keyword_weights":     story.KeywordWeights,
+				"first_published_at":  bson.M{"$min": bson.A{"$first_published_at", story.FirstPublishedAt}},
+				"last_published_at":   bson.M{"$max": bson.A{"$last_published_at", story.LastPublishedAt}},
+				"headline":            bson.M{"$cond": bson.A{replaceHeadline, headliner.Title, "$headline"}},
+				"headline_article_id": bson.M{"$cond": bson.A{replaceHeadline, headliner.Id, "$headline_article_id"}},
+				"headline_weight":     bson.M{"$max": bson.A{"$headline_weight", headliner.Weight}},
+				"sentiment_sum":       bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$sentiment_sum", 0}}, sentiment}},
+				"updated_at":          story.UpdatedAt,
+			}},
+			bson.M{"$set": bson.M{
+				"article_count": bson.M{"$size": "$article_ids"},
+				"sources_count": bson.M{"$size": "$sources"},
+				"sentiment":     bson.M{"$round": bson.A{bson.M{"$divide": bson.A{"$sentiment_sum", bson.M{"$size": "$article_ids"}}}, 3}},
+			}},
+			bson.M{"$set": bson.M{"tone": toneExpression("$sentiment")}},
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": story.Id}).SetUpdate(update))
+	}
+
+	if len(writes) == 0 {
+		return nil
+	}
+
+	_, err := ss.storyCollection.BulkWrite(ss.ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// Detach removes deleted articles from their stories and drops stories left
+// without members. The sentiment of the stories is averaged again over the
+// remaining members, and a story that lost its headline article takes the
+// heaviest of them as headline. Sources are left as they are, they record who
+// covered the story
+func (ss StoryServiceImp) Detach(ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	filter := bson.M{"article_ids": bson.M{"$in": ids}}
+	cursor, err := ss.storyCollection.Find(ss.ctx, filter, options.Find().SetProjection(bson.M{"_id": 1, "headline_article_id": 1}))
+	if err != nil {
+		return err
+	}
+	var affected []models.Story
+	if err := cursor.All(ss.ctx, &affected); err != nil {
+		return err
+	}
+
+	update := bson.A{
+		bson.M{"$set": bson.M{"article_ids": bson.M{"$setDifference": bson.A{"$article_ids", ids}}}},
+		bson.M{"$set": bson.M{"article_count": bson.M{"$size": "$article_ids"}}},
+	}
+	if _, err := ss.storyCollection.UpdateMany(ss.ctx, filter, update); err != nil {
+		return err
+	}
+
+	if _, err := ss.storyCollection.DeleteMany(ss.ctx, bson.M{"article_count": 0}); err != nil {
+		return err
+	}
+
+	detached := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		detached[id] = true
+	}
+	storyIds := make([]primitive.ObjectID, 0, len(affected))
+	beheaded := make(map[primitive.ObjectID]bool)
+	for _, story := range affected {
+		storyIds = append(storyIds, story.Id)
+		if detached[story.HeadlineArticleId] {
+			beheaded[story.Id] = true
+		}
+	}
+
+	return ss.refresh(storyIds, beheaded)
+}
+
+// refresh sets the sentiment of the stories from their members, and the
+// headline of the beheaded ones from their heaviest member. A story whose
+// members can not be found keeps its sentiment and gets its headline weight
+// cleared, so the next member to join takes over
+func (ss StoryServiceImp) refresh(storyIds []primitive.ObjectID, beheaded map[primitive.ObjectID]bool) error {
+	if len(storyIds) == 0 {
+		return nil
+	}
+
+	members, err := ss.members(storyIds)
+	if err != nil {
+		return err
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(storyIds))
+	for _, id := range storyIds {
+		set := bson.M{}
+		if sum, mean, ok := storySentiment(members[id]); ok {
+			set["sentiment_sum"] = sum
+			set["sentiment"] = mean
+			set["tone"] = models.Tone(mean)
+		}
+		if beheaded[id] {
+			set["headline_weight"] = 0
+			if headliner, ok := storyHeadliner(members[id]); ok {
+				set["headline"] = headliner.Title
+				set["headline_article_id"] = headliner.Id
+				set["headline_weight"] = headliner.Weight
+			}
+		}
+		if len(set) == 0 {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": id}).SetUpdate(bson.M{"$set": set}))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+
+	_, err = ss.storyCollection.BulkWrite(ss.ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// members loads the stored articles of the stories, oldest first
+func (ss StoryServiceImp) members(storyIds []primitive.ObjectID) (map[primitive.ObjectID][]models.Article, error) {
+	projection := bson.M{"title": 1, "source_priority": 1, "story_id": 1, "published_at": 1, "sentiment": 1}
+	cursor, err := ss.articleCollection.Find(ss.ctx, bson.M{"story_id": bson.M{"$in": storyIds}}, options.Find().
+		SetProjection(projection).
+		SetSort(bson.D{{Key: "published_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ss.ctx)
+
+	var articles []models.Article
+	if err := cursor.All(ss.ctx, &articles); err != nil {
+		return nil, err
+	}
+
+	members := make(map[primitive.ObjectID][]models.Article, len(storyIds))
+	for _, article := range articles {
+		members[article.StoryId] = append(members[article.StoryId], article)
+	}
+
+	return members, nil
+}
+
+// storySentiment sums the sentiment of the members and averages it, rounded
+// the way write rounds it
+func storySentiment(members []models.Article) (float64, float64, bool) {
+	if len(members) == 0 {
+		return 0, 0, false
+	}
+
+	sum := 0.0
+	for _, article := range members {
+		sum += article.Sentiment
+	}
+
+	return sum, math.Round(sum/float64(len(members))*1000) / 1000, true
+}
+
+// storyHeadliner picks the member a story is headlined by, the way join does
+// as members arrive: the heaviest source, the first of equal weight
+func storyHeadliner(members []models.Article) (models.Article, bool) {
+	if len(members) == 0 {
+		return models.Article{}, false
+	}
+
+	headliner := members[0]
+	for _, article := range members[1:] {
+		if article.Weight > headliner.Weight {
+			headliner = article
+		}
+	}
+
+	return headliner, true
+}
+
+// toneExpression labels a sentiment field on the server the way models.Tone does
+func toneExpression(field string) bson.M {
+	return bson.M{"$switch": bson.M{
+		"branches": bson.A{
+			bson.M{"case": bson.M{"$lte": bson.A{field, -models.ToneStrongThreshold}}, "then": models.ToneVeryNegative},
+			bson.M{"case": bson.M{"$lte": bson.A{field, -models.ToneThreshold}}, "then": models.ToneNegative},
+			bson.M{"case": bson.M{"$gte": bson.A{field, models.ToneStrongThreshold}}, "then": models.ToneVeryPositive},
+			bson.M{"case": bson.M{"$gte": bson.A{field, models.ToneThreshold}}, "then": models.TonePositive},
+		},
+		"default": models.ToneNeutral,
+	}}
+}
+
+func (ss StoryServiceImp) createIndexes() error {
+	// Index model for candidate lookups
+	termsIndex := mongo.IndexModel{Keys: bson.D{{Key: "keyword_weights.term", Value: 1}, {Key: "last_published_at", Value: -1}}}
+
+	// Index model for the story listing
+	categoriesIndex := mongo.IndexModel{Keys: bson.D{{Key: "categories", Value: 1}, {Key: "last_published_at", Value: -1}}}
+	recentIndex := mongo.IndexModel{Keys: bson.D{{Key: "last_published_at", Value: -1}}}
+
+	// Index model for detaching deleted articles
+	membersIndex := mongo.IndexModel{Keys: bson.M{"article_ids": 1}}
+
+	if _, err := ss.storyCollection.Indexes().CreateMany(ss.ctx, []mongo.IndexModel{termsIndex, categoriesIndex, recentIndex, membersIndex}); err != nil {
+		return err
+	}
+
+	// Index model for listing the articles of a story
+	_, err := ss.articleCollection.Indexes().CreateOne(ss.ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "story_id", Value: 1}, {Key: "published_at", Value: -1}},
+	})
+	return err
+}
+
+func keywordVector(keywords []models.KeywordWeight) map[string]float64 {
+	vector := make(map[string]float64, len(keywords))
+	for _, keyword := range keywords {
+		vector[keyword.Term] = keyword.Weight
+	}
+
+	return vector
+}
+
+// topKeywords keeps the heaviest terms of a vector, heaviest first
+func topKeywords(vector map[string]float64) []models.KeywordWeight {
+	keywords := make([]models.KeywordWeight, 0, len(vector))
+	for term, weight := range vector {
+		keywords = append(keywords, models.KeywordWeight{Term: term, Weight: math.Round(weight*1000) / 1000})
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].Weight != keywords[j].Weight {
+			return keywords[i].Weight > keywords[j].Weight
+		}
+		return keywords[i].Term < keywords[j].Term
+	})
+
+	if len(keywords) > storyCentroidTerms {
+		keywords = keywords[:storyCentroidTerms]
+	}
+
+	return keywords
+}
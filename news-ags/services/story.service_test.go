@@ -0,0 +1,36 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+)
+
+func TestStoryHeadliner(t *testing.T) {
+	if _, ok := storyHeadliner(nil); ok {
+		t.Error("a story without members has a headliner")
+	}
+
+	members := []models.Article{
+		{Title: "first light", Weight: 300},
+		{Title: "first heavy", Weight: 800},
+		{Title: "second heavy", Weight: 800},
+		{Title: "second light", Weight: 500},
+	}
+	headliner, ok := storyHeadliner(members)
+	if !ok || headliner.Title != "first heavy" {
+		t.Errorf("headliner = %q, want the first of the heaviest members", headliner.Title)
+	}
+}
+
+func TestStorySentiment(t *testing.T) {
+	if _, _, ok := storySentiment(nil); ok {
+		t.Error("a story without members has a sentiment")
+	}
+
+	sum, mean, ok := storySentiment([]models.Article{{Sentiment: 0.9}, {Sentiment: -0.2}, {Sentiment: 0.1}})
+	if !ok || math.Abs(sum-0.8) > 1e-9 || mean != 0.267 {
+		t.Errorf("sentiment = %v / %v, want 0.8 / 0.267", sum, mean)
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+)
+
+// The scraper hands articles to the saver through a redis stream, the saver
+// reads it as a consumer group and acknowledges entries once they are in mongo
+const (
+	articleStream     = "articles:stream"
+	articleSaverGroup = "article-savers"
+	articleField      = "article"
+	// articleFailures keeps the error of the last failed save of every entry
+	// still pending, so a dead lettered entry says why it could not be saved
+	articleFailures = "articles:stream:failures"
+)
+
+// The stream is not capped when entries are added, capping would drop
+// entries no saver has acknowledged yet. The saver trims what it acknowledged
+// once it drained the stream
+type StreamOptions struct {
+	BatchSize int64
+	ClaimIdle time.Duration
+	// MaxDeliveries is how often an entry is read before it is dead lettered
+	// instead, zero retries forever
+	MaxDeliveries int64
+	// DedupWindow limits the stored articles a batch is deduplicated against
+	// to those published this close to it, zero compares against all of them
+	DedupWindow time.Duration
+}
+
+func decodeStreamArticle(values map[string]interface{}) (models.Article, error) {
+	var article models.Article
+
+	raw, ok := values[articleField].(string)
+	if !ok {
+		return article, fmt.Errorf("stream entry has no %s field", articleField)
+	}
+
+	err := json.Unmarshal([]byte(raw), &article)
+	return article, err
+}
+
+// streamPayload is the raw article of a stream entry, or the whole entry when
+// it has none
+func streamPayload(values map[string]interface{}) []byte {
+	if raw, ok := values[articleField].(string); ok {
+		return []byte(raw)
+	}
+
+	payload, _ := json.Marshal(values)
+	return payload
+}
+
+// nextStreamId is the smallest entry id after id, the last id of a range
+// read is not inclusive this way on redis versions without exclusive ranges
+func nextStreamId(id string) string {
+	ms, seq, ok := strings.Cut(id, "-")
+	if !ok {
+		return "+"
+	}
+
+	parsed, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return "+"
+	}
+
+	return ms + "-" + strconv.FormatUint(parsed+1, 10)
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+	"github.com/joey1123455/news-aggregator-service/news-ags/utils"
+)
+
+// summaryMaxSentences bounds the sentences ranked per article, TextRank is
+// quadratic in them and the gist of an article is rarely past its first page
+const summaryMaxSentences = 60
+
+// Summarizer writes extractive summaries, a long one for previews and a short
+// teaser for mobile clients
+type Summarizer struct {
+	long  int
+	short int
+}
+
+func NewSummarizer(long, short int) *Summarizer {
+	if long <= 0 {
+		long = 3
+	}
+	if short <= 0 || short > long {
+		short = 1
+	}
+
+	return &Summarizer{long: long, short: short}
+}
+
+// Summarize sets the summaries of every article from its body, falling back
+// to the description when the body is too short to summarise
+func (s *Summarizer) Summarize(articles []models.Article) {
+	for i := range articles {
+		article := &articles[i]
+
+		sentences := utils.SplitSentences(article.Content)
+		if len(sentences) < s.long {
+			sentences = utils.SplitSentences(article.Description + "\n" + article.Content)
+		}
+		if len(sentences) > summaryMaxSentences {
+			sentences = sentences[:summaryMaxSentences]
+		}
+
+		article.Summary = joinSentences(sentences, utils.Summarize(sentences, s.long))
+		article.SummaryShort = joinSentences(sentences, utils.Summarize(sentences, s.short))
+	}
+}
+
+func joinSentences(sentences []string, picked []int) string {
+	parts := make([]string, 0, len(picked))
+	for _, i := range picked {
+		parts = append(parts, sentences[i])
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/joey1123455/news-aggregator-service/news-ags/models"
+)
+
+// Trending counts are kept in hourly sorted sets per kind and category, the
+// rankings the CMS reads are rebuilt from them on a schedule. Key layout:
+//
+//	trending:counts:<kind>:<category>:<yyyymmddhh>  term -> articles in that hour
+//	trending:rank:<kind>:<window>:<category>        term -> trend score
+//	trending:count:<kind>:<window>:<category>       term -> articles in the window (hash)
+//	trending:entities                               entity id -> entity name (hash)
+const (
+	trendingCountsPrefix  = "trending:counts:"
+	trendingRankPrefix    = "trending:rank:"
+	trendingCountPrefix   = "trending:count:"
+	trendingCategoriesKey = "trending:categories"
+	trendingEntitiesKey   = "trending:entities"
+	trendingWindowsKey    = "trending:windows"
+	trendingUpdatedKey    = "trending:updated_at"
+	trendingHourLayout    = "2006010215"
+
+	// rankings outlive a few missed runs before disappearing
+	trendingRankTTL = 24 * time.Hour
+)
+
+type TrendingOptions struct {
+	Windows  []time.Duration
+	Baseline time.Duration
+	MinCount int
+	Keywords int
+	Size     int
+}
+
+// TrendingService finds the terms whose frequency in a recent window spikes
+// above what the baseline window before it predicts
+type TrendingService interface {
+	Record(articles []models.Article) error
+	Rank() error
+}
+
+type TrendingServiceImp struct {
+	rClient *redis.Client
+	opts    TrendingOptions
+	owner   string
+}
+
+func NewTrendingService(client *redis.Client, opts TrendingOptions) TrendingService {
+	if opts.Baseline < time.Hour {
+		opts.Baseline = 7 * 24 * time.Hour
+	}
+	if opts.MinCount <= 0 {
+		opts.MinCount = 1
+	}
+	if opts.Size <= 0 {
+		opts.Size = 50
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "news-ags"
+	}
+
+	return &TrendingServiceImp{
+		rClient: client,
+		opts:    opts,
+		owner:   fmt.Sprintf("%s:%d", host, os.Getpid()),
+	}
+}
+
+// Record counts newly stored articles in the hour they were published, articles
+// older than the baseline can not affect any ranking and are left out
+func (ts TrendingServiceImp) Record(articles []models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	horizon := ts.horizon()
+	oldest := now.Add(-horizon)
+
+	pipe := ts.rClient.Pipeline()
+	categories := make(map[string]bool)
+	for _, article := range articles {
+		published := article.PublishedAt.UTC()
+		if published.Before(oldest) {
+			continue
+		}
+		if published.After(now) {
+			published = now
+		}
+		hour := published.Format(trendingHourLayout)
+
+		articleCategories := append([]string{models.TrendAll}, article.Category...)
+		for kind, terms := range ts.terms(article) {
+			for _, category := range articleCategories {
+				key := trendingCountsPrefix + kind + ":" + category + ":" + hour
+				for _, term := range terms {
+					pipe.ZIncrBy(key, 1, term)
+				}
+				pipe.Expire(key, horizon+time.Hour)
+			}
+		}
+
+		for _, category := range article.Category {
+			categories[category] = true
+		}
+		for _, entity := range article.Entities {
+			pipe.HSet(trendingEntitiesKey, entity.Id, entity.Name)
+		}
+	}
+
+	for category := range categories {
+		pipe.SAdd(trendingCategoriesKey, category)
+	}
+	pipe.Expire(trendingEntitiesKey, horizon+time.Hour)
+
+	_, err := pipe.Exec()
+	return err
+}
+
+// terms returns the terms an article contributes per kind
+func (ts TrendingServiceImp) terms(article models.Article) map[string][]string {
+	terms := make(map[string][]string)
+
+	keywords := make([]string, 0, ts.opts.Keywords)
+	for _, keyword := range article.KeywordWeights {
+		if len(keywords) >= ts.opts.Keywords {
+			break
+		}
+		keywords = append(keywords, keyword.Term)
+	}
+	if len(keywords) > 0 {
+		terms[models.TrendKeyword] = keywords
+	}
+
+	if !article.StoryId.IsZero() {
+		terms[models.TrendStory] = []string{article.StoryId.Hex()}
+	}
+
+	entities := make([]string, 0, len(article.Entities))
+	for _, entity := range article.Entities {
+		entities = append(entities, entity.Id)
+	}
+	if len(entities) > 0 {
+		terms[models.TrendEntity] = entities
+	}
+
+	return terms
+}
+
+// Rank rebuilds every ranking, each one is written to a temporary key and
+// renamed over the previous ranking so readers never see a partial one
+func (ts TrendingServiceImp) Rank() error {
+	categories, err := ts.rClient.SMembers(trendingCategoriesKey).Result()
+	if err != nil {
+		return err
+	}
+	categories = append(categories, models.TrendAll)
+
+	now := time.Now().UTC()
+	windows := make([]interface{}, 0, len(ts.opts.Windows))
+	for _, window := range ts.opts.Windows {
+		for _, kind := range models.TrendKinds {
+			for _, category := range categories {
+				if err := ts.rank(kind, category, window, now); err != nil {
+					return err
+				}
+			}
+		}
+		windows = append(windows, windowLabel(window))
+	}
+
+	pipe := ts.rClient.TxPipeline()
+	pipe.Del(trendingWindowsKey)
+	pipe.SAdd(trendingWindowsKey, windows...)
+	pipe.Set(trendingUpdatedKey, now.Format(time.RFC3339), 0)
+	_, err = pipe.Exec()
+	return err
+}
+
+type trend struct {
+	term  string
+	count float64
+	score float64
+}
+
+// rank scores every term seen at least MinCount times in the window against the
+// count the baseline predicts for a window of that length. The score grows
+// with the excess and shrinks with the noise expected of the baseline count
+func (ts TrendingServiceImp) rank(kind, category string, window time.Duration, now time.Time) error {
+	suffix := kind + ":" + windowLabel(window) + ":" + category
+	recentKey := "trending:tmp:" + ts.owner + ":recent:" + suffix
+	baselineKey := "trending:tmp:" + ts.owner + ":baseline:" + suffix
+	defer ts.rClient.Del(recentKey, baselineKey)
+
+	// the current, partial hour counts as the last hour of the window
+	prefix := trendingCountsPrefix + kind + ":" + category + ":"
+	current := now.Truncate(time.Hour)
+	recentStart := current.Add(-window + time.Hour)
+	if err := ts.union(recentKey, prefix, recentStart, current); err != nil {
+		return err
+	}
+	if err := ts.union(baselineKey, prefix, recentStart.Add(-ts.opts.Baseline), recentStart.Add(-time.Hour)); err != nil {
+		return err
+	}
+
+	recent, err := ts.rClient.ZRangeByScoreWithScores(recentKey, redis.ZRangeBy{
+		Min: fmt.Sprint(ts.opts.MinCount),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	trends := make([]trend, 0, len(recent))
+	if len(recent) > 0 {
+		pipe := ts.rClient.Pipeline()
+		baselines := make([]*redis.FloatCmd, len(recent))
+		for i, member := range recent {
+			baselines[i] = pipe.ZScore(baselineKey, member.Member.(string))
+		}
+		if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+			return err
+		}
+
+		ratio := window.Hours() / ts.opts.Baseline.Hours()
+		for i, member := range recent {
+			baseline, _ := baselines[i].Result()
+			expected := baseline * ratio
+			score := (member.Score - expected) / math.Sqrt(expected+1)
+			if score <= 0 {
+				continue
+			}
+			trends = append(trends, trend{term: member.Member.(string), count: member.Score, score: score})
+		}
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].score != trends[j].score {
+			return trends[i].score > trends[j].score
+		}
+		return trends[i].term < trends[j].term
+	})
+	if len(trends) > ts.opts.Size {
+		trends = trends[:ts.opts.Size]
+	}
+
+	return ts.publish(suffix, trends)
+}
+
+// union sums the hourly buckets between from and to into key
+func (ts TrendingServiceImp) union(key, prefix string, from, to time.Time) error {
+	keys := make([]string, 0)
+	for hour := from; !hour.After(to); hour = hour.Add(time.Hour) {
+		keys = append(keys, prefix+hour.Format(trendingHourLayout))
+	}
+	if len(keys) == 0 {
+		return ts.rClient.Del(key).Err()
+	}
+
+	return ts.rClient.ZUnionStore(key, redis.ZStore{}, keys...).Err()
+}
+
+func (ts TrendingServiceImp) publish(suffix string, trends []trend) error {
+	rankKey := trendingRankPrefix + suffix
+	countKey := trendingCountPrefix + suffix
+
+	if len(trends) == 0 {
+		return ts.rClient.Del(rankKey, countKey).Err()
+	}
+
+	members := make([]redis.Z, 0, len(trends))
+	counts := make(map[string]interface{}, len(trends))
+	for _, t := range trends {
+		members = append(members, redis.Z{Score: math.Round(t.score*1000) / 1000, Member: t.term})
+		counts[t.term] = t.count
+	}
+
+	tmpRank := "trending:tmp:" + ts.owner + ":rank:" + suffix
+	tmpCount := "trending:tmp:" + ts.owner + ":count:" + suffix
+
+	pipe := ts.rClient.TxPipeline()
+	pipe.Del(tmpRank, tmpCount)
+	pipe.ZAdd(tmpRank, members...)
+	pipe.HMSet(tmpCount, counts)
+	pipe.Rename(tmpRank, rankKey)
+	pipe.Rename(tmpCount, countKey)
+	pipe.Expire(rankKey, trendingRankTTL)
+	pipe.Expire(countKey, trendingRankTTL)
+	_, err := pipe.Exec()
+	return err
+}
+
+// horizon is how far back counts are needed, the largest window plus the baseline
+func (ts TrendingServiceImp) horizon() time.Duration {
+	largest := time.Duration(0)
+	for _, window := range ts.opts.Windows {
+		if window > largest {
+			largest = window
+		}
+	}
+
+	return largest + ts.opts.Baseline
+}
+
+// windowLabel formats a window the way the CMS asks for it, such as 6h
+func windowLabel(window time.Duration) string {
+	return fmt.Sprintf("%dh", int(window.Hours()))
+}
+
+// ParseTrendingWindows reads a comma separated list of windows such as 1h,6h,24h
+func ParseTrendingWindows(value string) ([]time.Duration, error) {
+	windows := make([]time.Duration, 0)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		window, err := time.ParseDuration(field)
+		if err != nil || window < time.Hour || window%time.Hour != 0 {
+			return nil, fmt.Errorf("trending window %q must be a whole number of hours", field)
+		}
+		windows = append(windows, window)
+	}
+
+	return windows, nil
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type UpstreamOptions struct {
+	UserAgent        string
+	Timeout          time.Duration
+	MaxRetries       int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// UpstreamClient is the http client shared by every source, it retries transient
+// failures and keeps one circuit breaker per source
+type UpstreamClient struct {
+	client   *resty.Client
+	opts     UpstreamOptions
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func NewUpstreamClient(opts UpstreamOptions) *UpstreamClient {
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 500 * time.Millisecond
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 30 * time.Second
+	}
+	if opts.BreakerThreshold <= 0 {
+		opts.BreakerThreshold = 5
+	}
+
+	client := resty.New()
+	if opts.UserAgent != "" {
+		client.SetHeader("User-Agent", opts.UserAgent)
+	}
+
+	return &UpstreamClient{
+		client:   client,
+		opts:     opts,
+		breakers: make(map[string]*circuitBreaker),
+	}
+}
+
+// Get sends a GET request on behalf of a source. Responses with a non transient
+// status are returned as is, transient ones are retried and the last response is
+// returned once retries run out
+func (uc *UpstreamClient) Get(ctx context.Context, source, url string, params map[string]string) (*resty.Response, error) {
+	return uc.get(ctx, source, url, params, retryableStatus)
+}
+
+// GetKeyed is Get for upstreams that meter an api key. A 429 there means the
+// key is throttled or out of credits, so it is returned at once for the caller
+// to switch keys instead of being retried and counted against the breaker
+func (uc *UpstreamClient) GetKeyed(ctx context.Context, source, url string, params map[string]string) (*resty.Response, error) {
+	return uc.get(ctx, source, url, params, func(status int) bool {
+		return status != http.StatusTooManyRequests && retryableStatus(status)
+	})
+}
+
+func (uc *UpstreamClient) get(ctx context.Context, source, url string, params map[string]string, retryable func(int) bool) (*resty.Response, error) {
+	breaker := uc.breaker(source)
+	if !breaker.allow() {
+		return nil, fmt.Errorf("%s: %w", source, ErrCircuitOpen)
+	}
+
+	var (
+		resp *resty.Response
+		err  error
+	)
+	for attempt := 0; ; attempt++ {
+		resp, err = uc.do(ctx, url, params)
+		if err == nil && !retryable(resp.StatusCode()) {
+			breaker.success()
+			return resp, nil
+		}
+
+		if attempt >= uc.opts.MaxRetries || ctx.Err() != nil {
+			break
+		}
+
+		wait := uc.backoff(attempt)
+		if err == nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header().Get("Retry-After")); ok {
+				// the upstream asked for longer than we are willing to wait
+				if retryAfter > uc.opts.MaxDelay {
+					break
+				}
+				wait = retryAfter
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			breaker.failure()
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	breaker.failure()
+	return resp, err
+}
+
+func (uc *UpstreamClient) do(ctx context.Context, url string, params map[string]string) (*resty.Response, error) {
+	if uc.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, uc.opts.Timeout)
+		defer cancel()
+	}
+
+	return uc.client.R().
+		SetContext(ctx).
+		SetQueryParams(params).
+		Get(url)
+}
+
+// backoff returns a full jitter exponential delay for the given attempt
+func (uc *UpstreamClient) backoff(attempt int) time.Duration {
+	ceiling := uc.opts.BaseDelay << uint(attempt)
+	if ceiling <= 0 || ceiling > uc.opts.MaxDelay {
+		ceiling = uc.opts.MaxDelay
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling)) + 1)
+}
+
+func (uc *UpstreamClient) breaker(source string) *circuitBreaker {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	cb, ok := uc.breakers[source]
+	if !ok {
+		cb = &circuitBreaker{threshold: uc.opts.BreakerThreshold, cooldown: uc.opts.BreakerCooldown}
+		uc.breakers[source] = cb
+	}
+
+	return cb
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// parseRetryAfter reads both the delay-seconds and http-date forms of Retry-After
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		wait := time.Until(at)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
+
+// circuitBreaker opens after threshold consecutive failures and lets a single
+// trial request through once the cooldown has passed
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.failures < cb.threshold {
+		return true
+	}
+
+	if time.Now().Before(cb.openUntil) || cb.trial {
+		return false
+	}
+
+	// half open
+	cb.trial = true
+	return true
+}
+
+func (cb *circuitBreaker) success() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures = 0
+	cb.trial = false
+}
+
+func (cb *circuitBreaker) failure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.trial = false
+	if cb.failures >= cb.threshold {
+		cb.openUntil = time.Now().Add(cb.cooldown)
+	}
+}
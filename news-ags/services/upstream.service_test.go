@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// upstreamServer answers every request with the status the handler picks for
+// its attempt, counting from one
+func upstreamServer(t *testing.T, handler func(attempt int64, w http.ResponseWriter)) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(hits.Add(1), w)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &hits
+}
+
+func fastUpstream(opts UpstreamOptions) *UpstreamClient {
+	if opts.BaseDelay == 0 {
+		opts.BaseDelay = time.Millisecond
+	}
+	if opts.MaxDelay == 0 {
+		opts.MaxDelay = 10 * time.Millisecond
+	}
+
+	return NewUpstreamClient(opts)
+}
+
+func TestUpstreamRetriesTransientStatus(t *testing.T) {
+	server, hits := upstreamServer(t, func(attempt int64, w http.ResponseWriter) {
+		if attempt < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	client := fastUpstream(UpstreamOptions{MaxRetries: 3})
+
+	resp, err := client.Get(context.Background(), "feed", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusOK || string(resp.Body()) != "ok" || hits.Load() != 3 {
+		t.Errorf("status %d after %d requests, want 200 after 3", resp.StatusCode(), hits.Load())
+	}
+	if failures := client.breaker("feed").failures; failures != 0 {
+		t.Errorf("breaker counted %d failures, want none after a success", failures)
+	}
+}
+
+func TestUpstreamReturnsLastResponseWhenRetriesRunOut(t *testing.T) {
+	server, hits := upstreamServer(t, func(attempt int64, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	client := fastUpstream(UpstreamOptions{MaxRetries: 2})
+
+	resp, err := client.Get(context.Background(), "feed", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusBadGateway || hits.Load() != 3 {
+		t.Errorf("status %d after %d requests, want 502 after 3", resp.StatusCode(), hits.Load())
+	}
+	if failures := client.breaker("feed").failures; failures != 1 {
+		t.Errorf("breaker counted %d failures, want one per call", failures)
+	}
+}
+
+func TestUpstreamDoesNotRetryClientErrors(t *testing.T) {
+	server, hits := upstreamServer(t, func(attempt int64, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	client := fastUpstream(UpstreamOptions{MaxRetries: 3})
+
+	resp, err := client.Get(context.Background(), "feed", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusNotFound || hits.Load() != 1 {
+		t.Errorf("status %d after %d requests, want 404 after 1", resp.StatusCode(), hits.Load())
+	}
+}
+
+func TestUpstreamWaitsForRetryAfter(t *testing.T) {
+	server, hits := upstreamServer(t, func(attempt int64, w http.ResponseWriter) {
+		if attempt == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	client := fastUpstream(UpstreamOptions{MaxRetries: 1, MaxDelay: 2 * time.Second})
+
+	started := time.Now()
+	resp, err := client.Get(context.Background(), "feed", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusOK || hits.Load() != 2 {
+		t.Errorf("status %d after %d requests, want 200 after 2", resp.StatusCode(), hits.Load())
+	}
+	if waited := time.Since(started); waited < time.Second {
+		t.Errorf("retried after %s, want the 1s Retry-After waited out", waited)
+	}
+}
+
+func TestUpstreamGivesUpWhenRetryAfterExceedsMaxDelay(t *testing.T) {
+	server, hits := upstreamServer(t, func(attempt int64, w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	client := fastUpstream(UpstreamOptions{MaxRetries: 3})
+
+	started := time.Now()
+	resp, err := client.Get(context.Background(), "feed", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusServiceUnavailable || hits.Load() != 1 {
+		t.Errorf("status %d after %d requests, want 503 after 1", resp.StatusCode(), hits.Load())
+	}
+	if waited := time.Since(started); waited > time.Second {
+		t.Errorf("gave up after %s, want at once", waited)
+	}
+	if failures := client.breaker("feed").failures; failures != 1 {
+		t.Errorf("breaker counted %d failures, want 1", failures)
+	}
+}
+
+func TestUpstreamStopsWaitingWhenCancelled(t *testing.T) {
+	server, _ := upstreamServer(t, func(attempt int64, w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	client := fastUpstream(UpstreamOptions{MaxRetries: 3, MaxDelay: 10 * time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Get(ctx, "feed", server.URL, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context deadline", err)
+	}
+}
+
+func TestUpstreamBreakerOpensAndHalfOpens(t *testing.T) {
+	var healthy atomic.Bool
+	release := make(chan struct{})
+	trial := make(chan struct{}, 1)
+	server, hits := upstreamServer(t, func(attempt int64, w http.ResponseWriter) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		trial <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	client := fastUpstream(UpstreamOptions{BreakerThreshold: 2, BreakerCooldown: 50 * time.Millisecond})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), "feed", server.URL, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.Get(context.Background(), "feed", server.URL, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want the breaker open after 2 failures", err)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("%d requests reached the upstream, want none while open", hits.Load())
+	}
+	// other sources keep their own breaker
+	if _, err := client.Get(context.Background(), "other", server.URL, nil); err != nil {
+		t.Errorf("err = %v, want another source unaffected", err)
+	}
+
+	// a failed trial opens the breaker for another cooldown
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.Get(context.Background(), "feed", server.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(context.Background(), "feed", server.URL, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want the breaker open after a failed trial", err)
+	}
+
+	// half open lets one trial through and closes on its success
+	time.Sleep(60 * time.Millisecond)
+	healthy.Store(true)
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Get(context.Background(), "feed", server.URL, nil)
+		done <- err
+	}()
+	<-trial
+	if _, err := client.Get(context.Background(), "feed", server.URL, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("err = %v, want a second request refused during the trial", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	go func() { <-trial }()
+	if resp, err := client.Get(context.Background(), "feed", server.URL, nil); err != nil || resp.StatusCode() != http.StatusOK {
+		t.Errorf("err = %v, want the breaker closed after a successful trial", err)
+	}
+}
+
+func TestUpstreamKeyedPassesTooManyRequestsThrough(t *testing.T) {
+	server, hits := upstreamServer(t, func(attempt int64, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	client := fastUpstream(UpstreamOptions{MaxRetries: 3, BreakerThreshold: 1})
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.GetKeyed(context.Background(), "newsdata", server.URL, nil)
+		if err != nil {
+			t.Fatalf("err = %v, want the 429 returned and the breaker left closed", err)
+		}
+		if resp.StatusCode() != http.StatusTooManyRequests {
+			t.Errorf("status %d, want 429", resp.StatusCode())
+		}
+	}
+	if hits.Load() != 2 {
+		t.Errorf("%d requests, want one per call", hits.Load())
+	}
+
+	// the unkeyed client retries the same answer and counts it as a failure
+	hits.Store(0)
+	if _, err := client.Get(context.Background(), "feed", server.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	if hits.Load() != 4 || client.breaker("feed").failures != 1 {
+		t.Errorf("%d requests and %d failures, want 4 and 1", hits.Load(), client.breaker("feed").failures)
+	}
+}
+
+func TestUpstreamKeyedRetriesServerErrors(t *testing.T) {
+	server, hits := upstreamServer(t, func(attempt int64, w http.ResponseWriter) {
+		if attempt == 1 {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	client := fastUpstream(UpstreamOptions{MaxRetries: 2})
+
+	resp, err := client.GetKeyed(context.Background(), "newsdata", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusOK || hits.Load() != 2 {
+		t.Errorf("status %d after %d requests, want 200 after 2", resp.StatusCode(), hits.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{value: "", ok: false},
+		{value: "0", want: 0, ok: true},
+		{value: "120", want: 2 * time.Minute, ok: true},
+		{value: "-5", ok: false},
+		{value: "soon", ok: false},
+		{value: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0, ok: true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// an http date in the future waits until then
+	at := time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+	if got, ok := parseRetryAfter(at); !ok || got < 80*time.Second || got > 90*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %s, %v, want about 90s", at, got, ok)
+	}
+}
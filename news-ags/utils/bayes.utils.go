@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type bayesClass struct {
+	Documents int            `json:"documents"`
+	Tokens    int            `json:"tokens"`
+	Counts    map[string]int `json:"counts"`
+}
+
+// NaiveBayes is a multinomial naive bayes text classifier with laplace smoothing
+type NaiveBayes struct {
+	Documents  int                    `json:"documents"`
+	Vocabulary int                    `json:"vocabulary"`
+	Classes    map[string]*bayesClass `json:"classes"`
+
+	// words is the set of tokens any class has counted, rebuilt whenever
+	// the counts change so prediction does not scan every class per token
+	words map[string]struct{}
+}
+
+// Prediction is the posterior probability of every class for a document
+type Prediction map[string]float64
+
+func NewNaiveBayes() *NaiveBayes {
+	return &NaiveBayes{Classes: make(map[string]*bayesClass), words: make(map[string]struct{})}
+}
+
+// Train adds a labelled document, call Finalize once every document was added
+func (nb *NaiveBayes) Train(class string, tokens []string) {
+	c, ok := nb.Classes[class]
+	if !ok {
+		c = &bayesClass{Counts: make(map[string]int)}
+		nb.Classes[class] = c
+	}
+
+	nb.Documents++
+	c.Documents++
+	for _, token := range tokens {
+		c.Counts[token]++
+		c.Tokens++
+		nb.words[token] = struct{}{}
+	}
+}
+
+// Remove drops a class, used for classes with too few documents to learn from
+func (nb *NaiveBayes) Remove(class string) {
+	if c, ok := nb.Classes[class]; ok {
+		nb.Documents -= c.Documents
+		delete(nb.Classes, class)
+		nb.index()
+	}
+}
+
+// Finalize drops tokens seen fewer than minCount times across all classes,
+// they mostly add noise and size to the model file
+func (nb *NaiveBayes) Finalize(minCount int) {
+	totals := make(map[string]int)
+	for _, c := range nb.Classes {
+		for token, count := range c.Counts {
+			totals[token] += count
+		}
+	}
+
+	vocabulary := 0
+	for token, total := range totals {
+		if total >= minCount {
+			vocabulary++
+			continue
+		}
+		for _, c := range nb.Classes {
+			if count, ok := c.Counts[token]; ok {
+				c.Tokens -= count
+				delete(c.Counts, token)
+			}
+		}
+	}
+	nb.Vocabulary = vocabulary
+	nb.index()
+}
+
+// Predict returns the posterior of every class, tokens outside the vocabulary are ignored
+func (nb *NaiveBayes) Predict(tokens []string) Prediction {
+	prediction := make(Prediction, len(nb.Classes))
+	if nb.Documents == 0 {
+		return prediction
+	}
+
+	logs := make(map[string]float64, len(nb.Classes))
+	best := math.Inf(-1)
+	for name, c := range nb.Classes {
+		score := math.Log(float64(c.Documents) / float64(nb.Documents))
+		denominator := math.Log(float64(c.Tokens + nb.Vocabulary))
+		for _, token := range tokens {
+			if _, ok := nb.words[token]; !ok {
+				continue
+			}
+			score += math.Log(float64(c.Counts[token]+1)) - denominator
+		}
+
+		logs[name] = score
+		best = math.Max(best, score)
+	}
+
+	// softmax shifted by the best score to stay within float range
+	total := 0.0
+	for name, score := range logs {
+		prediction[name] = math.Exp(score - best)
+		total += prediction[name]
+	}
+	for name := range prediction {
+		prediction[name] /= total
+	}
+
+	return prediction
+}
+
+func (nb *NaiveBayes) index() {
+	nb.words = make(map[string]struct{})
+	for _, c := range nb.Classes {
+		for token := range c.Counts {
+			nb.words[token] = struct{}{}
+		}
+	}
+}
+
+// Best returns the most probable class and its probability
+func (p Prediction) Best() (string, float64) {
+	classes := make([]string, 0, len(p))
+	for class := range p {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	best, probability := "", 0.0
+	for _, class := range classes {
+		if p[class] > probability {
+			best, probability = class, p[class]
+		}
+	}
+
+	return best, probability
+}
+
+// Save writes the model as json, through a temporary file so a running
+// service never reads a half written model
+func (nb *NaiveBayes) Save(path string) error {
+	data, err := json.Marshal(nb)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".classifier-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func LoadNaiveBayes(path string) (*NaiveBayes, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	nb := NewNaiveBayes()
+	if err := json.Unmarshal(data, nb); err != nil {
+		return nil, err
+	}
+	nb.index()
+
+	return nb, nil
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func trainedBayes() *NaiveBayes {
+	nb := NewNaiveBayes()
+	for i := 0; i < 3; i++ {
+		nb.Train("sport", []string{"goal", "match", "striker", "league"})
+		nb.Train("politics", []string{"senate", "vote", "bill", "minister"})
+	}
+	nb.Train("politics", []string{"senate", "budget"})
+	nb.Finalize(2)
+
+	return nb
+}
+
+func TestNaiveBayesPredict(t *testing.T) {
+	nb := trainedBayes()
+
+	prediction := nb.Predict([]string{"striker", "goal", "unknown"})
+	if class, probability := prediction.Best(); class != "sport" || probability < 0.9 {
+		t.Errorf("Best() = %s %.3f, want sport above 0.9", class, probability)
+	}
+	if total := prediction["sport"] + prediction["politics"]; math.Abs(total-1) > 1e-9 {
+		t.Errorf("probabilities sum to %f, want 1", total)
+	}
+
+	// only unknown tokens leave the class priors, 4 of 7 documents are politics
+	prior := nb.Predict([]string{"weather", "budget"})
+	if math.Abs(prior["politics"]-4.0/7) > 1e-9 {
+		t.Errorf("politics = %f, want the prior 4/7", prior["politics"])
+	}
+
+	if empty := NewNaiveBayes().Predict([]string{"goal"}); len(empty) != 0 {
+		t.Errorf("untrained Predict = %v, want no classes", empty)
+	}
+}
+
+func TestNaiveBayesFinalize(t *testing.T) {
+	nb := trainedBayes()
+
+	// budget was seen once and is dropped from the counts and the vocabulary
+	politics := nb.Classes["politics"]
+	if _, ok := politics.Counts["budget"]; ok || politics.Tokens != 13 {
+		t.Errorf("politics counts = %v with %d tokens, want budget dropped and 13 tokens", politics.Counts, politics.Tokens)
+	}
+	if nb.Vocabulary != 8 {
+		t.Errorf("vocabulary = %d, want 8", nb.Vocabulary)
+	}
+}
+
+func TestNaiveBayesRemove(t *testing.T) {
+	nb := trainedBayes()
+	nb.Remove("politics")
+	nb.Remove("missing")
+
+	if nb.Documents != 3 || len(nb.Classes) != 1 {
+		t.Errorf("documents = %d, classes = %d, want 3 and 1", nb.Documents, len(nb.Classes))
+	}
+	if prediction := nb.Predict([]string{"senate"}); prediction["sport"] != 1 {
+		t.Errorf("Predict = %v, want only sport", prediction)
+	}
+}
+
+func TestNaiveBayesSaveLoad(t *testing.T) {
+	nb := trainedBayes()
+	path := filepath.Join(t.TempDir(), "classifier.json")
+	if err := nb.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadNaiveBayes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Documents != nb.Documents || loaded.Vocabulary != nb.Vocabulary || len(loaded.Classes) != len(nb.Classes) {
+		t.Fatalf("loaded %+v, want %+v", loaded, nb)
+	}
+
+	tokens := []string{"vote", "goal", "bill", "unknown"}
+	want, got := nb.Predict(tokens), loaded.Predict(tokens)
+	for class, probability := range want {
+		if math.Abs(got[class]-probability) > 1e-12 {
+			t.Errorf("loaded %s = %f, want %f", class, got[class], probability)
+		}
+	}
+
+	if _, err := LoadNaiveBayes(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("loaded a missing model, want an error")
+	}
+}
+
+func TestPredictionBest(t *testing.T) {
+	if class, probability := (Prediction{}).Best(); class != "" || probability != 0 {
+		t.Errorf("empty Best() = %q %f, want nothing", class, probability)
+	}
+	if class, _ := (Prediction{"sport": 0.5, "business": 0.5}).Best(); class != "business" {
+		t.Errorf("tied Best() = %q, want the first class by name", class)
+	}
+}
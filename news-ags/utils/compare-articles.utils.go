@@ -1,39 +1,91 @@
 package utils
 
-import "github.com/blevesearch/bleve"
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+)
 
-func CalculateSimilarity(string1, string2 string) float64 {
-	// Create a new index mapping
-	mapping := bleve.NewIndexMapping()
+const (
+	// NearDuplicateDistance is the largest hamming distance between two
+	// fingerprints that still counts as the same article
+	NearDuplicateDistance = 3
 
-	// Create a new index
-	index, err := bleve.NewMemOnly(mapping)
-	if err != nil {
-		panic(err)
+	// fingerprintBands splits a fingerprint for LSH bucketing, with more bands
+	// than NearDuplicateDistance two near duplicates always share a band
+	fingerprintBands = 4
+	shingleSize      = 3
+)
+
+// SimHash computes a 64 bit fingerprint from the word shingles of a text
+func SimHash(text string) uint64 {
+	tokens := Tokenize(text)
+	if len(tokens) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	for _, shingle := range shingles(tokens) {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		sum := h.Sum64()
+
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
 	}
 
-	// Index the strings
-	index.Index("string1", map[string]interface{}{
-		"text": string1,
-	})
-	index.Index("string2", map[string]interface{}{
-		"text": string2,
-	})
+	var fingerprint uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			fingerprint |= 1 << uint(bit)
+		}
+	}
 
-	// Create a new query
-	query := bleve.NewMatchQuery(string1)
+	return fingerprint
+}
 
-	// Create a new search request
-	request := bleve.NewSearchRequest(query)
+func shingles(tokens []string) []string {
+	if len(tokens) < shingleSize {
+		return []string{strings.Join(tokens, " ")}
+	}
 
-	// Search the index
-	result, err := index.Search(request)
-	if err != nil {
-		panic(err)
+	result := make([]string, 0, len(tokens)-shingleSize+1)
+	for i := 0; i+shingleSize <= len(tokens); i++ {
+		result = append(result, strings.Join(tokens[i:i+shingleSize], " "))
 	}
 
-	// Get the score of the second string
-	score := result.Hits[1].Score
+	return result
+}
+
+// HammingDistance counts the differing bits between two fingerprints
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// FormatFingerprint encodes a fingerprint as fixed width hex, mongo has no unsigned 64 bit type
+func FormatFingerprint(fingerprint uint64) string {
+	return fmt.Sprintf("%016x", fingerprint)
+}
+
+func ParseFingerprint(fingerprint string) (uint64, error) {
+	return strconv.ParseUint(fingerprint, 16, 64)
+}
+
+// FingerprintBands returns the LSH bucket keys of a fingerprint
+func FingerprintBands(fingerprint uint64) []string {
+	width := 64 / fingerprintBands
+	bands := make([]string, 0, fingerprintBands)
+	for band := 0; band < fingerprintBands; band++ {
+		value := (fingerprint >> uint(band*width)) & (1<<uint(width) - 1)
+		bands = append(bands, fmt.Sprintf("%d:%04x", band, value))
+	}
 
-	return score
+	return bands
 }
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// publishLayouts covers the date formats seen in newsdata.io, RSS, Atom and JSON Feed
+var publishLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// rfc822Zones are the zone names RFC 822 defines besides UT and GMT, time.Parse
+// takes any name it does not know as UTC
+var rfc822Zones = map[string]int{
+	"EST": -5 * 3600, "EDT": -4 * 3600,
+	"CST": -6 * 3600, "CDT": -5 * 3600,
+	"MST": -7 * 3600, "MDT": -6 * 3600,
+	"PST": -8 * 3600, "PDT": -7 * 3600,
+}
+
+// ParsePublishDate parses an upstream publish date into UTC, dates without a
+// zone are taken as UTC. Zone names other than the RFC 822 ones are too
+// ambiguous to trust, such dates are unrecognised
+func ParsePublishDate(raw string) (time.Time, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty publish date")
+	}
+
+	for _, layout := range publishLayouts {
+		parsed, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(layout, "MST") {
+			return parsed.UTC(), nil
+		}
+
+		name, _ := parsed.Zone()
+		if offset, ok := rfc822Zones[name]; ok {
+			year, month, day := parsed.Date()
+			hour, min, sec := parsed.Clock()
+			return time.Date(year, month, day, hour, min, sec, parsed.Nanosecond(), time.FixedZone(name, offset)).UTC(), nil
+		}
+		if name == "UTC" || name == "GMT" || name == "UT" || name == "Z" {
+			return parsed.UTC(), nil
+		}
+
+		return time.Time{}, fmt.Errorf("unknown zone %q in publish date %q", name, raw)
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognised publish date %q", raw)
+}
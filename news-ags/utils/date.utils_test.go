@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePublishDate(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want time.Time
+		err  bool
+	}{
+		{name: "rfc1123z", raw: "Tue, 10 Jun 2003 04:00:00 -0500", want: time.Date(2003, 6, 10, 9, 0, 0, 0, time.UTC)},
+		{name: "rfc1123z single digit day", raw: "Tue, 3 Jun 2003 04:00:00 +0100", want: time.Date(2003, 6, 3, 3, 0, 0, 0, time.UTC)},
+		{name: "rfc1123 est", raw: "Tue, 10 Jun 2003 04:00:00 EST", want: time.Date(2003, 6, 10, 9, 0, 0, 0, time.UTC)},
+		{name: "rfc1123 pdt", raw: "Tue, 10 Jun 2003 04:00:00 PDT", want: time.Date(2003, 6, 10, 11, 0, 0, 0, time.UTC)},
+		{name: "rfc1123 cdt", raw: "Tue, 10 Jun 2003 04:00:00 CDT", want: time.Date(2003, 6, 10, 9, 0, 0, 0, time.UTC)},
+		{name: "rfc1123 gmt", raw: "Tue, 10 Jun 2003 04:00:00 GMT", want: time.Date(2003, 6, 10, 4, 0, 0, 0, time.UTC)},
+		{name: "rfc1123 utc", raw: "Tue, 10 Jun 2003 04:00:00 UTC", want: time.Date(2003, 6, 10, 4, 0, 0, 0, time.UTC)},
+		{name: "rfc822 mst", raw: "10 Jun 03 04:00 MST", want: time.Date(2003, 6, 10, 11, 0, 0, 0, time.UTC)},
+		{name: "rfc1123 unknown zone", raw: "Tue, 10 Jun 2003 04:00:00 CET", err: true},
+		{name: "rfc1123 ambiguous zone", raw: "Tue, 10 Jun 2003 04:00:00 IST", err: true},
+		{name: "rfc3339", raw: "2023-11-30T18:45:00+01:00", want: time.Date(2023, 11, 30, 17, 45, 0, 0, time.UTC)},
+		{name: "rfc3339 nano", raw: "2023-11-30T18:45:00.250Z", want: time.Date(2023, 11, 30, 18, 45, 0, 250000000, time.UTC)},
+		{name: "newsdata", raw: "2023-11-30 18:45:00", want: time.Date(2023, 11, 30, 18, 45, 0, 0, time.UTC)},
+		{name: "date only", raw: " 2023-11-30 ", want: time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC)},
+		{name: "empty", raw: "  ", err: true},
+		{name: "garbage", raw: "yesterday", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePublishDate(tt.raw)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("ParsePublishDate(%q) = %v, want an error", tt.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("ParsePublishDate(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
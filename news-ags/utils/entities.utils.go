@@ -0,0 +1,484 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	entityPerson       = "person"
+	entityOrganisation = "organisation"
+	entityLocation     = "location"
+
+	entityGazetteer = "gazetteer"
+	entityRule      = "rule"
+
+	// entityMaxSpan bounds the words of a name found by the rules
+	entityMaxSpan = 6
+)
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+
+	return set
+}
+
+var (
+	// personTitles mark the name after them as a person. Words that also start
+	// ordinary names, such as general in General Motors or premier in Premier
+	// League, are left out
+	personTitles = wordSet(`mr mrs ms miss dr prof professor sir dame lady lord president vice-president
+		senator sen governor gov minister chancellor king queen prince princess pope pastor bishop imam
+		sheikh rep representative hon honourable gen colonel col captain capt lieutenant lt sergeant sgt
+		judge mayor ambassador secretary chairman chairwoman ceo coach`)
+
+	// abbreviatedTitles are followed by a full stop that does not end the sentence
+	abbreviatedTitles = wordSet(`mr mrs ms dr prof sen gov rep hon gen col capt lt sgt st jr sr`)
+
+	// organisationSuffixes end the name of an organisation
+	organisationSuffixes = wordSet(`inc ltd limited plc corp corporation company co llc group holdings bank
+		ministry university college institute foundation association commission agency authority council
+		committee party union federation organisation organization board bureau service services court
+		assembly senate parliament congress police army navy force forces club fc united airlines airways
+		network news times post press exchange fund hospital school church league motors`)
+
+	// organisationHeads start the name of an organisation
+	organisationHeads = wordSet(`ministry university bank department bureau office house church court
+		federation league association`)
+
+	// locationPrepositions mark the name after them as a place
+	locationPrepositions = wordSet(`in at from near across throughout outside inside around towards`)
+
+	// entityConnectors may join the words of a name, as in Bank of England
+	entityConnectors = wordSet(`of and for the de da del van von al`)
+
+	// entityExcluded are capitalized words that never start a name on their own
+	entityExcluded = wordSet(`january february march april may june july august september october november
+		december monday tuesday wednesday thursday friday saturday sunday the a an this that these those it
+		he she they we i you his her their our its there here but and or if when while after before as at in
+		on for by with from of to what who why how where read more also however meanwhile according breaking
+		update live watch photo video`)
+)
+
+// EntityMention is an entity found in a text and the times it was mentioned
+type EntityMention struct {
+	Id       string
+	Type     string
+	Name     string
+	Source   string
+	Mentions int
+}
+
+type entityName struct {
+	id   string
+	kind string
+	name string
+	// exact is set for acronyms, which only match in capitals so WHO is not who
+	exact string
+}
+
+// EntityMatcher finds the entities of a text, names listed in the gazetteer
+// are matched first and the remaining capitalized names are typed by rules
+type EntityMatcher struct {
+	names    map[string]entityName
+	maxWords int
+}
+
+func NewEntityMatcher() *EntityMatcher {
+	return &EntityMatcher{names: make(map[string]entityName)}
+}
+
+// Add lists a known entity under its name and aliases, matching ignores case
+// except for acronyms
+func (em *EntityMatcher) Add(id, kind, name string, aliases []string) {
+	for _, alias := range append([]string{name}, aliases...) {
+		words := entityWords(alias)
+		if len(words) == 0 {
+			continue
+		}
+
+		known := entityName{id: id, kind: kind, name: name}
+		if upper := strings.ToUpper(alias); upper == alias && strings.ToLower(alias) != alias {
+			known.exact = spanName(words, 0, len(words))
+		}
+
+		key := make([]string, 0, len(words))
+		for _, word := range words {
+			key = append(key, strings.ToLower(word.text))
+		}
+		em.names[strings.Join(key, " ")] = known
+		if len(words) > em.maxWords {
+			em.maxWords = len(words)
+		}
+	}
+}
+
+type entityWord struct {
+	text string
+	// sentence is set on the first word of a sentence, whose capital says nothing
+	sentence bool
+	// broken is set when punctuation separates the word from the one before it
+	broken bool
+}
+
+type entityCount struct {
+	mention EntityMention
+	strong  bool
+}
+
+// Extract returns the entities of text, most mentioned first. Names typed by a
+// weak rule are only kept when mentioned more than once
+func (em *EntityMatcher) Extract(text string) []EntityMention {
+	words := entityWords(text)
+	used := make([]bool, len(words))
+	found := make(map[string]*entityCount)
+	order := make([]string, 0)
+
+	count := func(id, kind, name, source string, strong bool) {
+		entry, ok := found[id]
+		if !ok {
+			entry = &entityCount{mention: EntityMention{Id: id, Type: kind, Name: name, Source: source}}
+			found[id] = entry
+			order = append(order, id)
+		}
+		entry.mention.Mentions++
+		entry.strong = entry.strong || strong
+	}
+
+	for i := 0; i < len(words); i++ {
+		if !capitalized(words[i].text) {
+			continue
+		}
+		for n := min(em.maxWords, len(words)-i); n > 0; n-- {
+			known, ok := em.names[spanKey(words, i, n)]
+			if ok && (known.exact == "" || known.exact == spanName(words, i, i+n)) {
+				count(known.id, known.kind, known.name, entityGazetteer, true)
+				for j := i; j < i+n; j++ {
+					used[j] = true
+				}
+				i += n - 1
+				break
+			}
+		}
+	}
+
+	// single capitalized words left unexplained may refer back to a person
+	// named in full, such as Smith after John Smith
+	loose := make([]string, 0)
+	for i := 0; i < len(words); {
+		start, end := nameSpan(words, used, i)
+		if start < 0 {
+			break
+		}
+		i = end
+
+		kind, strong, name := classifySpan(words, start, end)
+		if kind == "" {
+			if end-start == 1 && !entityExcluded[strings.ToLower(words[start].text)] {
+				loose = append(loose, words[start].text)
+			}
+			continue
+		}
+		count(EntityId(kind, name), kind, name, entityRule, strong)
+	}
+
+	surnames := make(map[string]string)
+	for _, id := range order {
+		mention := found[id].mention
+		if mention.Type != entityPerson {
+			continue
+		}
+		if parts := strings.Fields(mention.Name); len(parts) > 1 {
+			surnames[strings.ToLower(parts[len(parts)-1])] = id
+		}
+	}
+	for _, word := range loose {
+		if id, ok := surnames[strings.ToLower(word)]; ok {
+			found[id].mention.Mentions++
+		}
+	}
+
+	// a person named by title and surname alone is the person named in full
+	for _, id := range order {
+		entry := found[id]
+		if entry.mention.Type != entityPerson || entry.mention.Source != entityRule || strings.Contains(entry.mention.Name, " ") {
+			continue
+		}
+		if full, ok := surnames[strings.ToLower(entry.mention.Name)]; ok && full != id {
+			found[full].mention.Mentions += entry.mention.Mentions
+			found[full].strong = found[full].strong || entry.strong
+			delete(found, id)
+		}
+	}
+
+	mentions := make([]EntityMention, 0, len(found))
+	for _, entry := range found {
+		if !entry.strong && entry.mention.Mentions < 2 {
+			continue
+		}
+		mentions = append(mentions, entry.mention)
+	}
+	sort.Slice(mentions, func(i, j int) bool {
+		if mentions[i].Mentions != mentions[j].Mentions {
+			return mentions[i].Mentions > mentions[j].Mentions
+		}
+		return mentions[i].Id < mentions[j].Id
+	})
+
+	return mentions
+}
+
+// nameSpan finds the next run of capitalized words from i, which may be joined
+// by connectors. It returns -1 when the text has no further capitalized word
+func nameSpan(words []entityWord, used []bool, i int) (int, int) {
+	for ; i < len(words); i++ {
+		if !used[i] && capitalized(words[i].text) {
+			break
+		}
+	}
+	if i >= len(words) {
+		return -1, -1
+	}
+
+	start, end := i, i+1
+	for end < len(words) && end-start < entityMaxSpan {
+		word := words[end]
+		if used[end] || word.sentence || word.broken {
+			break
+		}
+		if capitalized(word.text) {
+			end++
+			continue
+		}
+		// a connector only belongs to the name when a capitalized word follows
+		if entityConnectors[strings.ToLower(word.text)] && end+1 < len(words) && !used[end+1] &&
+			!words[end+1].broken && capitalized(words[end+1].text) {
+			end += 2
+			continue
+		}
+		break
+	}
+
+	return start, end
+}
+
+// classifySpan types the capitalized words between start and end. Titles,
+// organisation suffixes and place prepositions are strong evidence, a name of
+// two or three capitalized words inside a sentence is weak evidence of a person
+func classifySpan(words []entityWord, start, end int) (string, bool, string) {
+	lower := make([]string, 0, end-start)
+	for _, word := range words[start:end] {
+		lower = append(lower, strings.ToLower(word.text))
+	}
+
+	previous := ""
+	if start > 0 && !words[start].broken {
+		previous = strings.ToLower(words[start-1].text)
+	}
+
+	// titles are capitalized too and lead the span
+	titled := personTitles[previous]
+	first := 0
+	for first < len(lower)-1 && personTitles[lower[first]] {
+		titled = true
+		first++
+	}
+	// a title does not make a person of an organisation, as in Queen Mary
+	// University of London
+	if titled && !entityExcluded[lower[first]] && end-start-first <= 4 && !organisationName(lower[first:]) {
+		return entityPerson, true, spanName(words, start+first, end)
+	}
+
+	if entityExcluded[lower[0]] && end-start == 1 {
+		return "", false, ""
+	}
+	if entityExcluded[lower[0]] {
+		start++
+		lower = lower[1:]
+		if entityConnectors[lower[0]] {
+			return "", false, ""
+		}
+	}
+
+	if organisationSuffixes[lower[len(lower)-1]] && (len(lower) > 1 || !words[start].sentence) {
+		return entityOrganisation, true, spanName(words, start, end)
+	}
+	if len(lower) > 1 && organisationName(lower) {
+		return entityOrganisation, true, spanName(words, start, end)
+	}
+
+	if len(lower) == 1 && acronym(words[start].text) {
+		return entityOrganisation, false, words[start].text
+	}
+
+	if locationPrepositions[previous] && len(lower) <= 3 {
+		return entityLocation, true, spanName(words, start, end)
+	}
+
+	if !words[start].sentence && len(lower) >= 2 && len(lower) <= 3 {
+		for _, word := range lower {
+			if entityConnectors[word] {
+				return "", false, ""
+			}
+		}
+		return entityPerson, false, spanName(words, start, end)
+	}
+
+	return "", false, ""
+}
+
+// organisationName reports whether the words of a span follow an organisation
+// pattern: a suffix at the end, a head at the start or a suffix followed by a
+// connector, as in Central Bank of Nigeria
+func organisationName(lower []string) bool {
+	if organisationSuffixes[lower[len(lower)-1]] || organisationHeads[lower[0]] && len(lower) > 1 {
+		return true
+	}
+	for i := 1; i < len(lower)-1; i++ {
+		if organisationSuffixes[lower[i]] && entityConnectors[lower[i+1]] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// entityWords splits text into words, remembering where sentences start and
+// where punctuation breaks a name. Possessive endings are dropped
+func entityWords(text string) []entityWord {
+	words := make([]entityWord, 0)
+	sentence, broken := true, false
+
+	var current []rune
+	flush := func() {
+		word := strings.Trim(string(current), "-'’")
+		current = current[:0]
+		word = strings.TrimSuffix(strings.TrimSuffix(word, "'s"), "’s")
+		if word == "" {
+			return
+		}
+
+		words = append(words, entityWord{text: word, sentence: sentence, broken: broken})
+		sentence, broken = false, false
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-' || r == '\'' || r == '’':
+			current = append(current, r)
+		case unicode.IsSpace(r):
+			if len(current) > 0 {
+				flush()
+			}
+			if r == '\n' {
+				sentence = true
+			}
+		case r == '.' || r == '!' || r == '?':
+			word := strings.ToLower(string(current))
+			// initials and abbreviated titles do not end the sentence
+			abbreviation := r == '.' && (abbreviatedTitles[word] || len([]rune(word)) == 1)
+			if len(current) > 0 {
+				flush()
+			}
+			if !abbreviation {
+				sentence = true
+			}
+		default:
+			if len(current) > 0 {
+				flush()
+			}
+			broken = true
+		}
+	}
+	if len(current) > 0 {
+		flush()
+	}
+
+	// the first word of a sentence is also separated from the previous one
+	for i := range words {
+		if words[i].sentence {
+			words[i].broken = true
+		}
+	}
+
+	return words
+}
+
+func spanKey(words []entityWord, start, n int) string {
+	key := make([]string, 0, n)
+	for i := start; i < start+n; i++ {
+		if i > start && words[i].broken {
+			return ""
+		}
+		key = append(key, strings.ToLower(words[i].text))
+	}
+
+	return strings.Join(key, " ")
+}
+
+func spanName(words []entityWord, start, end int) string {
+	parts := make([]string, 0, end-start)
+	for _, word := range words[start:end] {
+		parts = append(parts, word.text)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func capitalized(word string) bool {
+	for _, r := range word {
+		return unicode.IsUpper(r)
+	}
+
+	return false
+}
+
+func acronym(word string) bool {
+	letters := []rune(word)
+	if len(letters) < 2 || len(letters) > 6 {
+		return false
+	}
+	for _, r := range letters {
+		if !unicode.IsUpper(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// EntityNameKeys lists the normalized name starting from each of its words,
+// so a prefix lookup finds Bola Tinubu under bola and tinubu alike
+func EntityNameKeys(name string) []string {
+	words := strings.Fields(TitleKey(name))
+
+	keys := make([]string, 0, len(words))
+	for i := range words {
+		keys = append(keys, strings.Join(words[i:], " "))
+	}
+
+	return keys
+}
+
+// EntityId normalizes a name into an id such as person:bola-tinubu
+func EntityId(kind, name string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			slug.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && slug.Len() > 0 {
+			slug.WriteRune('-')
+			dash = true
+		}
+	}
+
+	return kind + ":" + strings.TrimSuffix(slug.String(), "-")
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEntityNameKeys(t *testing.T) {
+	tests := map[string][]string{
+		"Bola Tinubu":                {"bola tinubu", "tinubu"},
+		"  Central Bank of  Nigeria": {"central bank of nigeria", "bank of nigeria", "of nigeria", "nigeria"},
+		"U.S.":                       {"u s", "s"},
+		"Lagos":                      {"lagos"},
+		"":                           {},
+	}
+	for name, want := range tests {
+		if got := EntityNameKeys(name); !slices.Equal(got, want) {
+			t.Errorf("EntityNameKeys(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestEntityMatcherExtract(t *testing.T) {
+	matcher := NewEntityMatcher()
+	matcher.Add("location:lagos", "location", "Lagos", []string{"Eko"})
+
+	tests := []struct {
+		text    string
+		want    []string
+		without []string
+	}{
+		{
+			text: "President Bola Tinubu spoke in Abuja on Monday.",
+			want: []string{"person:bola-tinubu", "location:abuja"},
+		},
+		{
+			text:    "Arsenal lead the Premier League title race.",
+			want:    []string{"organisation:premier-league"},
+			without: []string{"person:league"},
+		},
+		{
+			text:    "Investors sold General Motors on Tuesday.",
+			want:    []string{"organisation:general-motors"},
+			without: []string{"person:motors"},
+		},
+		{
+			text:    "She studied at Queen Mary University of London.",
+			want:    []string{"organisation:queen-mary-university-of-london"},
+			without: []string{"person:mary-university-of-london"},
+		},
+		{
+			text: "The Central Bank of Nigeria raised rates.",
+			want: []string{"organisation:central-bank-of-nigeria"},
+		},
+		{
+			text: "Traffic in Eko eased.",
+			want: []string{"location:lagos"},
+		},
+	}
+	for _, test := range tests {
+		got := make([]string, 0)
+		for _, mention := range matcher.Extract(test.text) {
+			got = append(got, mention.Id)
+		}
+		for _, id := range test.want {
+			if !slices.Contains(got, id) {
+				t.Errorf("Extract(%q) = %q, want %s", test.text, got, id)
+			}
+		}
+		for _, id := range test.without {
+			if slices.Contains(got, id) {
+				t.Errorf("Extract(%q) = %q, want no %s", test.text, got, id)
+			}
+		}
+	}
+}
+
+func TestEntityMatcherExtractKeepsWeakNamesMentionedTwice(t *testing.T) {
+	matcher := NewEntityMatcher()
+
+	once := matcher.Extract("Fans cheered as Tom Brady arrived.")
+	if len(once) != 0 {
+		t.Errorf("weak name mentioned once = %+v, want none", once)
+	}
+
+	twice := matcher.Extract("Fans cheered as Tom Brady arrived, and Brady waved.")
+	if len(twice) != 1 || twice[0].Id != "person:tom-brady" || twice[0].Mentions != 2 || twice[0].Source != entityRule {
+		t.Errorf("weak name mentioned twice = %+v, want person:tom-brady twice", twice)
+	}
+}
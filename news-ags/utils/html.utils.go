@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	cdataPattern      = regexp.MustCompile(`(?s)<!\[CDATA\[(.*?)\]\]>`)
+	scriptPattern     = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	tagPattern        = regexp.MustCompile(`(?s)<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+	imgPattern        = regexp.MustCompile(`(?i)<img[^>]+src=["']([^"']+)["']`)
+)
+
+// CleanText turns a feed body (plain, escaped html, cdata or xhtml) into plain text
+func CleanText(raw string) string {
+	text := cdataPattern.ReplaceAllString(raw, "$1")
+	text = html.UnescapeString(text)
+	text = scriptPattern.ReplaceAllString(text, " ")
+	text = tagPattern.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	text = whitespacePattern.ReplaceAllString(text, " ")
+
+	return strings.TrimSpace(text)
+}
+
+// FirstImage returns the source of the first image embedded in an html body
+func FirstImage(raw string) string {
+	text := html.UnescapeString(cdataPattern.ReplaceAllString(raw, "$1"))
+	match := imgPattern.FindStringSubmatch(text)
+	if match == nil {
+		return ""
+	}
+
+	return match[1]
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+const (
+	// perceptual hashes are taken from the low frequencies of a small grayscale copy
+	phashSize     = 32
+	phashFreqSize = 8
+)
+
+// FlattenImage draws an image onto a white background, jpeg has no alpha so
+// transparent pixels would otherwise turn black
+func FlattenImage(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+
+	return flat
+}
+
+// ResizeImage scales an image to the given size by averaging the source pixels
+// every target pixel covers, which keeps downscaled photos free of aliasing
+func ResizeImage(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	if width <= 0 || height <= 0 || srcWidth == 0 || srcHeight == 0 {
+		return dst
+	}
+
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max((y+1)*srcHeight/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max((x+1)*srcWidth/width, x0+1)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+// FitWidth returns the size of an image scaled to the width, keeping its aspect ratio
+func FitWidth(width, height, target int) (int, int) {
+	if width <= 0 {
+		return 0, 0
+	}
+
+	return target, max(int(math.Round(float64(height)*float64(target)/float64(width))), 1)
+}
+
+// PerceptualHash fingerprints what an image looks like rather than its bytes.
+// The image is shrunk to a grayscale square and every bit tells whether a low
+// frequency of its cosine transform is above the median, so recompressed,
+// resized or lightly edited copies land within a few bits of each other
+func PerceptualHash(img *image.RGBA) uint64 {
+	small := ResizeImage(img, phashSize, phashSize)
+
+	pixels := make([][]float64, phashSize)
+	for y := range pixels {
+		pixels[y] = make([]float64, phashSize)
+		for x := range pixels[y] {
+			i := small.PixOffset(x, y)
+			pixels[y][x] = 0.299*float64(small.Pix[i]) + 0.587*float64(small.Pix[i+1]) + 0.114*float64(small.Pix[i+2])
+		}
+	}
+
+	// separable dct, only the frequencies kept in the hash are computed
+	cosines := make([][]float64, phashFreqSize)
+	for u := range cosines {
+		cosines[u] = make([]float64, phashSize)
+		for x := range cosines[u] {
+			cosines[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * phashSize))
+		}
+	}
+
+	rows := make([][]float64, phashSize)
+	for y := range rows {
+		rows[y] = make([]float64, phashFreqSize)
+		for u := 0; u < phashFreqSize; u++ {
+			for x := 0; x < phashSize; x++ {
+				rows[y][u] += pixels[y][x] * cosines[u][x]
+			}
+		}
+	}
+
+	coefficients := make([]float64, 0, phashFreqSize*phashFreqSize)
+	for v := 0; v < phashFreqSize; v++ {
+		for u := 0; u < phashFreqSize; u++ {
+			sum := 0.0
+			for y := 0; y < phashSize; y++ {
+				sum += rows[y][u] * cosines[v][y]
+			}
+			coefficients = append(coefficients, sum)
+		}
+	}
+
+	// the first coefficient is the mean brightness, it says nothing about the
+	// picture and would dominate the median
+	sorted := append([]float64(nil), coefficients[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var hash uint64
+	for i, coefficient := range coefficients[1:] {
+		if coefficient > median {
+			hash |= 1 << uint(i)
+		}
+	}
+
+	return hash
+}
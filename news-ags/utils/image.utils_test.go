@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// testPicture draws a shaded frame with a dark block, the block and the light
+// source sit in the opposite corners of the flipped picture
+func testPicture(width, height int, flipped bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			px, py := x, y
+			if flipped {
+				px, py = width-1-x, height-1-y
+			}
+
+			shade := uint8(40 + 180*px/width)
+			if px < width/3 && py < height/2 {
+				shade = 20
+			}
+			if (px-2*width/3)*(px-2*width/3)+(py-2*height/3)*(py-2*height/3) < height*height/25 {
+				shade = 250
+			}
+			img.Set(x, y, color.RGBA{R: shade, G: shade / 2, B: 255 - shade, A: 255})
+		}
+	}
+
+	return img
+}
+
+func TestFitWidth(t *testing.T) {
+	tests := []struct {
+		name                  string
+		width, height, target int
+		wantWidth, wantHeight int
+	}{
+		{name: "landscape", width: 1200, height: 800, target: 300, wantWidth: 300, wantHeight: 200},
+		{name: "rounds the height", width: 1000, height: 333, target: 320, wantWidth: 320, wantHeight: 107},
+		{name: "portrait", width: 600, height: 900, target: 160, wantWidth: 160, wantHeight: 240},
+		{name: "never collapses to nothing", width: 4000, height: 10, target: 100, wantWidth: 100, wantHeight: 1},
+		{name: "no width", width: 0, height: 100, target: 160, wantWidth: 0, wantHeight: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height := FitWidth(tt.width, tt.height, tt.target)
+			if width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("FitWidth(%d, %d, %d) = %dx%d, want %dx%d", tt.width, tt.height, tt.target, width, height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestPerceptualHash(t *testing.T) {
+	// the distance the image service treats as the same picture
+	const nearDuplicate = 6
+
+	original := testPicture(640, 400, false)
+	hash := PerceptualHash(original)
+	if hash == 0 {
+		t.Fatal("hash of a picture is empty")
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, original, &jpeg.Options{Quality: 40}); err != nil {
+		t.Fatal(err)
+	}
+	recompressed, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copies := map[string]*image.RGBA{
+		"same pixels":  testPicture(640, 400, false),
+		"resized":      ResizeImage(original, 200, 125),
+		"recompressed": FlattenImage(recompressed),
+	}
+	for name, img := range copies {
+		if d := HammingDistance(hash, PerceptualHash(img)); d > nearDuplicate {
+			t.Errorf("%s copy is %d bits away, want at most %d", name, d, nearDuplicate)
+		}
+	}
+
+	if d := HammingDistance(hash, PerceptualHash(testPicture(640, 400, true))); d <= nearDuplicate {
+		t.Errorf("a different picture is only %d bits away", d)
+	}
+}
+
+func TestFlattenImageOnWhite(t *testing.T) {
+	transparent := image.NewNRGBA(image.Rect(10, 10, 14, 14))
+	transparent.Set(11, 11, color.NRGBA{R: 255, A: 255})
+
+	flat := FlattenImage(transparent)
+	if flat.Bounds() != image.Rect(0, 0, 4, 4) {
+		t.Fatalf("bounds = %v, want the image moved to the origin", flat.Bounds())
+	}
+	if got := flat.RGBAAt(0, 0); got != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("transparent pixel = %v, want white", got)
+	}
+	if got := flat.RGBAAt(1, 1); got != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("opaque pixel = %v, want red", got)
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// phraseBreak splits text where a keyphrase can not continue
+var phraseBreak = regexp.MustCompile(`[.,;:!?()\[\]{}"“”|\n]+`)
+
+// Terms lists the candidate keywords of a text, single words and two word
+// phrases that do not span a stopword or punctuation. Numbers are left out
+func Terms(text string) []string {
+	terms := make([]string, 0)
+
+	for _, fragment := range phraseBreak.Split(strings.ToLower(text), -1) {
+		words := strings.FieldsFunc(fragment, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '-' && r != '\''
+		})
+
+		previous := ""
+		for _, word := range words {
+			word = strings.Trim(word, "-'")
+			if !keywordCandidate(word) {
+				previous = ""
+				continue
+			}
+
+			terms = append(terms, word)
+			if previous != "" {
+				terms = append(terms, previous+" "+word)
+			}
+			previous = word
+		}
+	}
+
+	return terms
+}
+
+func keywordCandidate(word string) bool {
+	if len([]rune(word)) < 3 {
+		return false
+	}
+	if _, ok := stopwords[word]; ok {
+		return false
+	}
+
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TermFrequencies counts the terms of a text, every occurrence adds weight
+func TermFrequencies(text string, weight float64, counts map[string]float64) map[string]float64 {
+	if counts == nil {
+		counts = make(map[string]float64)
+	}
+
+	for _, term := range Terms(text) {
+		counts[term] += weight
+	}
+
+	return counts
+}
+
+// TFIDF scores terms with sublinear term frequency and smoothed inverse
+// document frequency, documents is the corpus size and df the number of
+// corpus documents containing each term
+func TFIDF(tf map[string]float64, df map[string]int, documents int) map[string]float64 {
+	scores := make(map[string]float64, len(tf))
+	for term, freq := range tf {
+		if freq <= 0 {
+			continue
+		}
+
+		idf := math.Log(float64(1+documents)/float64(1+df[term])) + 1
+		score := (1 + math.Log(freq)) * idf
+
+		// phrases are rarer than their words and carry more meaning
+		if strings.Contains(term, " ") {
+			score *= 1.5
+		}
+		scores[term] = score
+	}
+
+	return scores
+}
@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// languageSampleLetters bounds the letters of a text looked at, the opening
+	// of an article is plenty to tell its language
+	languageSampleLetters = 1000
+	// languageMinLetters is the shortest text worth guessing at
+	languageMinLetters = 20
+	// trigramSmoothing is the share of probability left for unseen trigrams
+	trigramSmoothing = 0.1
+)
+
+// languageSeeds are short news style texts the trigram profiles are learned from
+var languageSeeds = map[string]string{
+	"en": `The government said on Monday that it would increase spending on health and education after the
+		election. The president told reporters that the new policy will help people who have been affected by
+		the crisis. Prices of food and fuel have risen sharply this year, and many families are struggling to
+		pay their bills. According to the report, the number of people without work fell in the last three
+		months. Police said they were investigating the attack, which happened late on Sunday night in the
+		capital. The company announced that its profits were higher than expected and that it would hire more
+		workers.`,
+	"fr": `Le gouvernement a annoncé lundi qu'il allait augmenter les dépenses de santé et d'éducation après
+		les élections. Le président a déclaré aux journalistes que la nouvelle politique aidera les personnes
+		qui ont été touchées par la crise. Les prix de la nourriture et du carburant ont fortement augmenté
+		cette année, et de nombreuses familles ont du mal à payer leurs factures. Selon le rapport, le nombre
+		de personnes sans emploi a diminué au cours des trois derniers mois. La police a indiqué qu'elle
+		enquêtait sur l'attaque, qui s'est produite tard dimanche soir dans la capitale. L'entreprise a
+		annoncé que ses bénéfices étaient plus élevés que prévu et qu'elle embaucherait davantage de
+		travailleurs.`,
+	"es": `El gobierno anunció el lunes que aumentará el gasto en salud y educación después de las
+		elecciones. El presidente dijo a los periodistas que la nueva política ayudará a las personas que han
+		sido afectadas por la crisis. Los precios de los alimentos y del combustible han subido mucho este
+		año, y muchas familias tienen dificultades para pagar sus cuentas. Según el informe, el número de
+		personas sin trabajo bajó en los últimos tres meses. La policía dijo que estaba investigando el
+		ataque, que ocurrió el domingo por la noche en la capital. La empresa anunció que sus ganancias fueron
+		mayores de lo esperado y que contratará a más trabajadores.`,
+	"pt": `O governo anunciou na segunda-feira que vai aumentar os gastos com saúde e educação depois das
+		eleições. O presidente disse aos jornalistas que a nova política vai ajudar as pessoas que foram
+		afetadas pela crise. Os preços dos alimentos e do combustível subiram muito este ano, e muitas
+		famílias têm dificuldade em pagar as suas contas. De acordo com o relatório, o número de pessoas sem
+		emprego caiu nos últimos três meses. A polícia disse que está a investigar o ataque, que aconteceu no
+		domingo à noite na capital. A empresa anunciou que os seus lucros foram maiores do que o esperado e
+		que vai contratar mais trabalhadores.`,
+	"de": `Die Regierung kündigte am Montag an, dass sie nach der Wahl die Ausgaben für Gesundheit und
+		Bildung erhöhen wird. Der Präsident sagte den Journalisten, dass die neue Politik den Menschen helfen
+		werde, die von der Krise betroffen sind. Die Preise für Lebensmittel und Kraftstoff sind in diesem Jahr
+		stark gestiegen, und viele Familien haben Mühe, ihre Rechnungen zu bezahlen. Laut dem Bericht ist die
+		Zahl der Menschen ohne Arbeit in den letzten drei Monaten gesunken. Die Polizei teilte mit, dass sie
+		den Angriff untersucht, der sich am späten Sonntagabend in der Hauptstadt ereignete. Das Unternehmen
+		gab bekannt, dass seine Gewinne höher als erwartet waren und dass es mehr Mitarbeiter einstellen wird.`,
+	"it": `Il governo ha annunciato lunedì che aumenterà la spesa per la sanità e l'istruzione dopo le
+		elezioni. Il presidente ha detto ai giornalisti che la nuova politica aiuterà le persone che sono
+		state colpite dalla crisi. I prezzi del cibo e del carburante sono aumentati molto quest'anno, e molte
+		famiglie hanno difficoltà a pagare le bollette. Secondo il rapporto, il numero delle persone senza
+		lavoro è diminuito negli ultimi tre mesi. La polizia ha detto che sta indagando sull'attacco, avvenuto
+		domenica sera tardi nella capitale. L'azienda ha annunciato che i suoi profitti sono stati più alti
+		del previsto e che assumerà altri lavoratori.`,
+	"nl": `De regering heeft maandag aangekondigd dat zij na de verkiezingen meer geld zal uitgeven aan
+		gezondheidszorg en onderwijs. De president zei tegen journalisten dat het nieuwe beleid de mensen zal
+		helpen die door de crisis zijn getroffen. De prijzen van voedsel en brandstof zijn dit jaar sterk
+		gestegen, en veel gezinnen hebben moeite om hun rekeningen te betalen. Volgens het rapport is het
+		aantal mensen zonder werk de afgelopen drie maanden gedaald. De politie zei dat zij de aanval
+		onderzoekt, die zondagavond laat in de hoofdstad plaatsvond. Het bedrijf maakte bekend dat de winst
+		hoger was dan verwacht en dat het meer werknemers zal aannemen.`,
+	"sw": `Serikali ilitangaza Jumatatu kwamba itaongeza matumizi katika afya na elimu baada ya uchaguzi.
+		Rais aliwaambia waandishi wa habari kwamba sera mpya itawasaidia watu ambao wameathiriwa na mgogoro
+		huo. Bei za chakula na mafuta zimepanda sana mwaka huu, na familia nyingi zinatatizika kulipa bili
+		zao. Kwa mujibu wa ripoti hiyo, idadi ya watu wasio na kazi ilipungua katika miezi mitatu iliyopita.
+		Polisi walisema wanachunguza shambulio hilo, lililotokea usiku wa Jumapili katika mji mkuu. Kampuni
+		hiyo ilitangaza kwamba faida yake ilikuwa kubwa kuliko ilivyotarajiwa na kwamba itaajiri wafanyakazi
+		zaidi.`,
+	"ha": `Gwamnati ta sanar a ranar Litinin cewa za ta kara kashe kudi a fannin lafiya da ilimi bayan
+		zabe. Shugaban kasa ya shaida wa manema labarai cewa sabuwar manufar za ta taimaka wa mutanen da
+		rikicin ya shafa. Farashin abinci da man fetur ya tashi sosai a wannan shekara, kuma iyalai da dama
+		suna fama wajen biyan kudaden su. A cewar rahoton, yawan mutanen da ba su da aikin yi ya ragu a cikin
+		watanni uku da suka gabata. 'Yan sanda sun ce suna bincike kan harin, wanda ya faru a daren ranar
+		Lahadi a babban birnin kasar. Kamfanin ya sanar cewa ribar sa ta fi yadda ake tsammani kuma zai dauki
+		karin ma'aikata.`,
+	"yo": `Ìjọba kéde ní ọjọ́ Ajé pé òun yóò ṣe àfikún owó tí wọ́n ń ná lórí ìlera àti ẹ̀kọ́ lẹ́yìn ìdìbò.
+		Ààrẹ sọ fún àwọn oníròyìn pé ètò tuntun náà yóò ran àwọn ènìyàn tí ìṣòro náà kàn lọ́wọ́. Iye owó
+		oúnjẹ àti epo ti gbé sókè gidigidi ní ọdún yìí, ọ̀pọ̀lọpọ̀ ìdílé sì ń jìjàdù láti san owó wọn. Gẹ́gẹ́
+		bí ìròyìn náà ṣe sọ, iye àwọn tí kò ní iṣẹ́ dín kù ní oṣù mẹ́ta sẹ́yìn. Àwọn ọlọ́pàá sọ pé àwọn ń ṣe
+		ìwádìí nípa ìkọlù náà, èyí tí ó ṣẹlẹ̀ ní alẹ́ ọjọ́ Àìkú ní olú ìlú.`,
+}
+
+// languageScripts are scripts that settle the language on their own
+var languageScripts = []struct {
+	language string
+	table    *unicode.RangeTable
+}{
+	{"ar", unicode.Arabic},
+	{"ru", unicode.Cyrillic},
+	{"el", unicode.Greek},
+	{"hi", unicode.Devanagari},
+	{"ko", unicode.Hangul},
+	{"ja", unicode.Hiragana},
+	{"ja", unicode.Katakana},
+	{"zh", unicode.Han},
+}
+
+type trigramProfile struct {
+	counts map[string]int
+	total  int
+}
+
+var (
+	languageProfiles = map[string]*trigramProfile{}
+	// languageTrigrams is the number of distinct trigrams across every seed
+	languageTrigrams int
+)
+
+func init() {
+	distinct := make(map[string]bool)
+	for language, seed := range languageSeeds {
+		profile := &trigramProfile{counts: make(map[string]int)}
+		for _, trigram := range trigrams(seed, 0) {
+			profile.counts[trigram]++
+			profile.total++
+			distinct[trigram] = true
+		}
+		languageProfiles[language] = profile
+	}
+	languageTrigrams = len(distinct)
+}
+
+// DetectLanguage guesses the ISO 639-1 code of a text with a naive bayes model
+// over character trigrams, texts in a script used by a single language are
+// settled by their script. The confidence is the posterior of the best
+// language, an empty language means the text was too short to tell
+func DetectLanguage(text string) (string, float64) {
+	scripts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range languageScripts {
+			if unicode.Is(script.table, r) {
+				scripts[script.language]++
+				break
+			}
+		}
+		if letters >= languageSampleLetters {
+			break
+		}
+	}
+	if letters < languageMinLetters {
+		return "", 0
+	}
+
+	// kana mixed with kanji is japanese, not chinese
+	if scripts["ja"] > 0 && scripts["zh"] > 0 {
+		scripts["ja"] += scripts["zh"]
+		delete(scripts, "zh")
+	}
+	for language, count := range scripts {
+		if count*2 > letters {
+			return language, float64(count) / float64(letters)
+		}
+	}
+
+	grams := trigrams(text, languageSampleLetters)
+	scores := make(map[string]float64, len(languageProfiles))
+	best := math.Inf(-1)
+	for language, profile := range languageProfiles {
+		// interpolating with a floor shared by every language keeps short
+		// seeds from winning on the trigrams no seed contains
+		score := 0.0
+		for _, trigram := range grams {
+			seen := float64(profile.counts[trigram]) / float64(profile.total)
+			score += math.Log((1-trigramSmoothing)*seen + trigramSmoothing/float64(languageTrigrams))
+		}
+		scores[language] = score
+		best = math.Max(best, score)
+	}
+
+	languages := make([]string, 0, len(scores))
+	for language := range scores {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	total := 0.0
+	detected, top := "", 0.0
+	for _, language := range languages {
+		weight := math.Exp(scores[language] - best)
+		total += weight
+		if weight > top {
+			detected, top = language, weight
+		}
+	}
+
+	return detected, top / total
+}
+
+// languageNames maps the language names upstream feeds use to their codes,
+// newsdata reports english where a feed would say en or en-US
+var languageNames = map[string]string{
+	"arabic": "ar", "chinese": "zh", "danish": "da", "dutch": "nl", "english": "en",
+	"finnish": "fi", "french": "fr", "german": "de", "greek": "el", "hausa": "ha",
+	"hindi": "hi", "hungarian": "hu", "igbo": "ig", "italian": "it", "japanese": "ja",
+	"korean": "ko", "norwegian": "nb", "portuguese": "pt", "romanian": "ro", "russian": "ru",
+	"spanish": "es", "swahili": "sw", "swedish": "sv", "turkish": "tr", "yoruba": "yo",
+}
+
+// NormalizeLanguage turns a language name or tag into its ISO 639-1 code,
+// anything it can not read gives an empty language
+func NormalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := languageNames[language]; ok {
+		return code
+	}
+
+	// a tag such as en-US or pt_BR keeps its primary language
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	if len(language) != 2 || strings.IndexFunc(language, func(r rune) bool { return r < 'a' || r > 'z' }) >= 0 {
+		return ""
+	}
+
+	return language
+}
+
+// trigrams lists the character trigrams of the words of a text, padded with a
+// space on both sides so word starts and ends are told apart
+func trigrams(text string, limit int) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r)
+	})
+
+	grams := make([]string, 0)
+	letters := 0
+	for _, word := range words {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			grams = append(grams, string(runes[i:i+3]))
+		}
+
+		letters += len(runes) - 2
+		if limit > 0 && letters >= limit {
+			break
+		}
+	}
+
+	return grams
+}
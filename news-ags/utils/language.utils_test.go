@@ -0,0 +1,22 @@
+package utils
+
+import "testing"
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := map[string]string{
+		"english":  "en",
+		" French ": "fr",
+		"en":       "en",
+		"EN-us":    "en",
+		"pt_BR":    "pt",
+		"":         "",
+		"klingon":  "",
+		"eng":      "",
+		"e1":       "",
+	}
+	for language, want := range tests {
+		if got := NormalizeLanguage(language); got != want {
+			t.Errorf("NormalizeLanguage(%q) = %q, want %q", language, got, want)
+		}
+	}
+}
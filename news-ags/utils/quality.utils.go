@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TitleKey normalizes a headline so copies differing only in case,
+// punctuation or spacing compare equal
+func TitleKey(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(words, " ")
+}
+
+// MatchDomain tells whether the link points at one of the domains or a subdomain of it
+func MatchDomain(link string, domains []string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || parsed.Hostname() == "" {
+		return false
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	for _, domain := range domains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "www.")
+		if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PhraseMatcher finds any of a list of words or phrases in text, whole words only
+type PhraseMatcher struct {
+	pattern *regexp.Regexp
+}
+
+// NewPhraseMatcher compiles the phrases, it never matches when they are all empty
+func NewPhraseMatcher(phrases []string) *PhraseMatcher {
+	quoted := make([]string, 0, len(phrases))
+	for _, phrase := range phrases {
+		if words := strings.Fields(phrase); len(words) > 0 {
+			for i, word := range words {
+				words[i] = regexp.QuoteMeta(word)
+			}
+			quoted = append(quoted, strings.Join(words, `\s+`))
+		}
+	}
+	if len(quoted) == 0 {
+		return &PhraseMatcher{}
+	}
+
+	// the first alternative wins at a position, longer phrases go first so a
+	// phrase is not lost to a shorter one it starts with that fails the boundary
+	sort.SliceStable(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+
+	return &PhraseMatcher{pattern: regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)}
+}
+
+func (pm *PhraseMatcher) Match(text string) bool {
+	return len(pm.find(text, 1)) > 0
+}
+
+// Strip removes every phrase from the text
+func (pm *PhraseMatcher) Strip(text string) string {
+	matches := pm.find(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var stripped strings.Builder
+	last := 0
+	for _, match := range matches {
+		stripped.WriteString(text[last:match[0]])
+		stripped.WriteString(" ")
+		last = match[1]
+	}
+	stripped.WriteString(text[last:])
+
+	return stripped.String()
+}
+
+// find returns up to n whole word matches, all of them when n is negative.
+// RE2 has no lookarounds, so the boundaries are checked around each match
+// instead of being matched, which leaves the character between two adjacent
+// phrases for the second one
+func (pm *PhraseMatcher) find(text string, n int) [][]int {
+	if pm.pattern == nil {
+		return nil
+	}
+
+	var matches [][]int
+	for offset := 0; offset < len(text) && n != 0; {
+		loc := pm.pattern.FindStringIndex(text[offset:])
+		if loc == nil {
+			break
+		}
+		start, end := offset+loc[0], offset+loc[1]
+
+		if isWordBoundary(text, start, end) {
+			matches = append(matches, []int{start, end})
+			offset = end
+			n--
+			continue
+		}
+
+		// try again from the next character
+		_, size := utf8.DecodeRuneInString(text[start:])
+		offset = start + size
+	}
+
+	return matches
+}
+
+func isWordBoundary(text string, start, end int) bool {
+	if before, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(after) {
+		return false
+	}
+
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
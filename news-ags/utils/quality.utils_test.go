@@ -0,0 +1,82 @@
+package utils
+
+import "testing"
+
+func TestPhraseMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		phrases  []string
+		text     string
+		match    bool
+		stripped string
+	}{
+		{
+			name:     "no phrases",
+			phrases:  []string{"", "  "},
+			text:     "Subscribe now",
+			match:    false,
+			stripped: "Subscribe now",
+		},
+		{
+			name:     "whole words only",
+			phrases:  []string{"war"},
+			text:     "Wartime art of software",
+			match:    false,
+			stripped: "Wartime art of software",
+		},
+		{
+			name:     "case and spacing",
+			phrases:  []string{"read more"},
+			text:     "The story. READ   More",
+			match:    true,
+			stripped: "The story.  ",
+		},
+		{
+			name:     "adjacent phrases share a separator",
+			phrases:  []string{"subscribe", "share"},
+			text:     "subscribe share",
+			match:    true,
+			stripped: "   ",
+		},
+		{
+			name:     "adjacent repeats of a phrase",
+			phrases:  []string{"advertisement"},
+			text:     "Body advertisement advertisement advertisement end",
+			match:    true,
+			stripped: "Body       end",
+		},
+		{
+			name:     "longer phrase wins over its prefix",
+			phrases:  []string{"sign", "sign up"},
+			text:     "Please sign up today",
+			match:    true,
+			stripped: "Please   today",
+		},
+		{
+			name:     "phrase found after a failed boundary",
+			phrases:  []string{"ad"},
+			text:     "adverts ad",
+			match:    true,
+			stripped: "adverts  ",
+		},
+		{
+			name:     "unicode letters are word characters",
+			phrases:  []string{"café"},
+			text:     "cafés café",
+			match:    true,
+			stripped: "cafés  ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher := NewPhraseMatcher(tt.phrases)
+			if got := matcher.Match(tt.text); got != tt.match {
+				t.Errorf("Match(%q) = %v, want %v", tt.text, got, tt.match)
+			}
+			if got := matcher.Strip(tt.text); got != tt.stripped {
+				t.Errorf("Strip(%q) = %q, want %q", tt.text, got, tt.stripped)
+			}
+		})
+	}
+}
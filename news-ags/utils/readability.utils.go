@@ -0,0 +1,361 @@
+package utils
+
+import (
+	"bytes"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	unlikelyPattern = regexp.MustCompile(`(?i)comment|sidebar|footer|nav|menu|share|social|related|promo|advert|ad-|banner|cookie|subscribe|newsletter|popup|masthead|breadcrumb`)
+	likelyPattern   = regexp.MustCompile(`(?i)article|body|content|main|post|story|text|entry`)
+	positivePattern = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story`)
+	negativePattern = regexp.MustCompile(`(?i)hidden|combx|comment|com-|contact|foot|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|shoutbox|sidebar|sponsor|shopping|tags|tool|widget`)
+	bylinePattern   = regexp.MustCompile(`(?i)byline|author|writtenby|p-author`)
+)
+
+// minParagraphLength is the shortest paragraph that counts towards a candidate score
+const minParagraphLength = 25
+
+// ExtractedArticle is the readable part of an article page
+type ExtractedArticle struct {
+	Title     string
+	Content   string
+	Image     string
+	Byline    string
+	Canonical string
+}
+
+// ExtractArticle finds the main body, lead image, byline and canonical link of
+// an article page using readability style paragraph scoring. Relative links
+// are resolved against pageURL
+func ExtractArticle(page []byte, pageURL string) (*ExtractedArticle, error) {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return nil, err
+	}
+
+	base, _ := url.Parse(pageURL)
+	extracted := &ExtractedArticle{}
+	readMetadata(doc, base, extracted)
+
+	var body *html.Node
+	walkNodes(doc, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Body {
+			body = n
+			return false
+		}
+		return true
+	})
+	if body == nil {
+		body = doc
+	}
+
+	if extracted.Byline == "" {
+		extracted.Byline = findByline(body)
+	}
+
+	pruneUnlikely(body)
+
+	candidate := topCandidate(body)
+	if candidate == nil {
+		return extracted, nil
+	}
+
+	paragraphs := make([]string, 0)
+	walkNodes(candidate, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		switch n.DataAtom {
+		case atom.P, atom.H2, atom.H3, atom.Blockquote, atom.Li, atom.Pre:
+			if text := nodeText(n); len(text) >= minParagraphLength || n.DataAtom != atom.P && text != "" {
+				paragraphs = append(paragraphs, text)
+			}
+			return false
+		}
+		return true
+	})
+	if len(paragraphs) == 0 {
+		paragraphs = append(paragraphs, nodeText(candidate))
+	}
+	extracted.Content = strings.Join(paragraphs, "\n\n")
+
+	if extracted.Image == "" {
+		walkNodes(candidate, func(n *html.Node) bool {
+			if n.Type == html.ElementNode && n.DataAtom == atom.Img {
+				extracted.Image = resolveURL(base, attr(n, "src"))
+				return extracted.Image == ""
+			}
+			return true
+		})
+	}
+
+	return extracted, nil
+}
+
+// readMetadata reads the open graph, twitter and link metadata of the page
+func readMetadata(doc *html.Node, base *url.URL, extracted *ExtractedArticle) {
+	walkNodes(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+
+		switch n.DataAtom {
+		case atom.Body:
+			return false
+		case atom.Title:
+			if extracted.Title == "" {
+				extracted.Title = nodeText(n)
+			}
+		case atom.Link:
+			if strings.EqualFold(attr(n, "rel"), "canonical") {
+				extracted.Canonical = resolveURL(base, attr(n, "href"))
+			}
+		case atom.Meta:
+			key := strings.ToLower(attr(n, "property"))
+			if key == "" {
+				key = strings.ToLower(attr(n, "name"))
+			}
+			content := strings.TrimSpace(attr(n, "content"))
+			if content == "" {
+				return true
+			}
+
+			switch key {
+			case "og:title":
+				extracted.Title = content
+			case "og:image", "og:image:url", "twitter:image", "twitter:image:src":
+				if extracted.Image == "" {
+					extracted.Image = resolveURL(base, content)
+				}
+			case "og:url":
+				if extracted.Canonical == "" {
+					extracted.Canonical = resolveURL(base, content)
+				}
+			case "author", "article:author", "parsely-author", "sailthru.author":
+				if extracted.Byline == "" && !strings.HasPrefix(content, "http") {
+					extracted.Byline = content
+				}
+			}
+		}
+		return true
+	})
+}
+
+func findByline(body *html.Node) string {
+	byline := ""
+	walkNodes(body, func(n *html.Node) bool {
+		if byline != "" {
+			return false
+		}
+		if n.Type != html.ElementNode {
+			return true
+		}
+
+		if strings.EqualFold(attr(n, "rel"), "author") || strings.EqualFold(attr(n, "itemprop"), "author") ||
+			bylinePattern.MatchString(attr(n, "class")+" "+attr(n, "id")) {
+			text := nodeText(n)
+			if text != "" && len(text) < 100 {
+				byline = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(text, "By "), "by "))
+				return false
+			}
+		}
+		return true
+	})
+
+	return byline
+}
+
+// pruneUnlikely removes boilerplate elements before scoring
+func pruneUnlikely(root *html.Node) {
+	var remove []*html.Node
+	walkNodes(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+
+		switch n.DataAtom {
+		case atom.Script, atom.Style, atom.Noscript, atom.Nav, atom.Header, atom.Footer, atom.Aside,
+			atom.Form, atom.Iframe, atom.Svg, atom.Button, atom.Select, atom.Textarea:
+			remove = append(remove, n)
+			return false
+		case atom.Body, atom.Article, atom.Main:
+			return true
+		}
+
+		match := attr(n, "class") + " " + attr(n, "id")
+		if unlikelyPattern.MatchString(match) && !likelyPattern.MatchString(match) {
+			remove = append(remove, n)
+			return false
+		}
+		return true
+	})
+
+	for _, n := range remove {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// topCandidate scores the parents of every paragraph and returns the best
+// one, the first in the document on a tie
+func topCandidate(root *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+
+	walkNodes(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || (n.DataAtom != atom.P && n.DataAtom != atom.Pre && n.DataAtom != atom.Td) {
+			return true
+		}
+
+		text := nodeText(n)
+		if len(text) < minParagraphLength {
+			return false
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+
+		parent := n.Parent
+		if parent == nil {
+			return false
+		}
+		if _, ok := scores[parent]; !ok {
+			scores[parent] = initialScore(parent)
+		}
+		scores[parent] += score
+
+		if grandparent := parent.Parent; grandparent != nil {
+			if _, ok := scores[grandparent]; !ok {
+				scores[grandparent] = initialScore(grandparent)
+			}
+			scores[grandparent] += score / 2
+		}
+		return false
+	})
+
+	// candidates are compared in document order so equal scores go to the
+	// first, the grandparent of a paragraph may lie above root
+	document := root
+	for document.Parent != nil {
+		document = document.Parent
+	}
+
+	var (
+		best      *html.Node
+		bestScore float64
+	)
+	walkNodes(document, func(n *html.Node) bool {
+		score, ok := scores[n]
+		if !ok {
+			return true
+		}
+		score *= 1 - linkDensity(n)
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+		return true
+	})
+
+	return best
+}
+
+func initialScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.DataAtom {
+	case atom.Article:
+		score += 10
+	case atom.Div, atom.Main, atom.Section:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+
+	match := attr(n, "class") + " " + attr(n, "id")
+	if negativePattern.MatchString(match) {
+		score -= 25
+	}
+	if positivePattern.MatchString(match) {
+		score += 25
+	}
+
+	return score
+}
+
+// linkDensity is the share of a node's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	total := len(nodeText(n))
+	if total == 0 {
+		return 0
+	}
+
+	linked := 0
+	walkNodes(n, func(c *html.Node) bool {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			linked += len(nodeText(c))
+			return false
+		}
+		return true
+	})
+
+	return float64(linked) / float64(total)
+}
+
+// walkNodes visits n and its descendants depth first, returning false skips the children
+func walkNodes(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkNodes(c, visit)
+	}
+}
+
+func nodeText(n *html.Node) string {
+	var buf strings.Builder
+	walkNodes(n, func(c *html.Node) bool {
+		if c.Type == html.ElementNode && (c.DataAtom == atom.Script || c.DataAtom == atom.Style) {
+			return false
+		}
+		if c.Type == html.TextNode {
+			buf.WriteString(c.Data)
+			buf.WriteByte(' ')
+		}
+		return true
+	})
+
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || base == nil {
+		return ref
+	}
+
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+
+	return base.ResolveReference(parsed).String()
+}
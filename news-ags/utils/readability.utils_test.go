@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestExtractArticle(t *testing.T) {
+	tests := []struct {
+		page      string
+		url       string
+		title     string
+		image     string
+		byline    string
+		canonical string
+		contains  []string
+		excludes  []string
+	}{
+		{
+			page:      "metadata.html",
+			url:       "https://dailymetro.example/news/lagos-rail-line-opens?utm_source=feed",
+			title:     "Lagos rail line opens to passengers",
+			image:     "https://dailymetro.example/images/blue-line.jpg",
+			byline:    "Amaka Obi",
+			canonical: "https://dailymetro.example/news/lagos-rail-line-opens",
+			contains:  []string{"first phase of the Lagos Blue Line", "250,000 passengers", "Cowry card"},
+			excludes:  []string{"Most read", "Copyright", "dataLayer", "Sport"},
+		},
+		{
+			page:      "inline.html",
+			url:       "https://northpost.example/2023/12/harmattan-haze-grounds-flights",
+			title:     "Harmattan haze grounds flights in Kano",
+			image:     "https://northpost.example/2023/media/haze.jpg",
+			byline:    "Sani Musa",
+			canonical: "https://northpost.example/2023/12/harmattan-haze-grounds-flights",
+			contains:  []string{"Thick harmattan haze", "through the weekend", "Safety comes first."},
+			excludes:  []string{"Airline adds Abuja route", "North Post"},
+		},
+		{
+			page:      "author-link.html",
+			url:       "https://marketdesk.example/markets/cocoa-prices-record",
+			title:     "Cocoa prices hit a record high",
+			image:     "https://cdn.marketdesk.example/cocoa.png",
+			byline:    "Efua Mensah",
+			canonical: "https://marketdesk.example/markets/cocoa-prices-record",
+			contains:  []string{"Cocoa futures climbed", "festive season"},
+		},
+		{
+			page:  "no-article.html",
+			url:   "https://northpost.example/missing",
+			title: "Page not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			page, err := os.ReadFile(filepath.Join("..", "testdata", "pages", tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			extracted, err := ExtractArticle(page, tt.url)
+			if err != nil {
+				t.Fatalf("ExtractArticle() error = %v", err)
+			}
+
+			if extracted.Title != tt.title {
+				t.Errorf("Title = %q, want %q", extracted.Title, tt.title)
+			}
+			if extracted.Image != tt.image {
+				t.Errorf("Image = %q, want %q", extracted.Image, tt.image)
+			}
+			if extracted.Byline != tt.byline {
+				t.Errorf("Byline = %q, want %q", extracted.Byline, tt.byline)
+			}
+			if extracted.Canonical != tt.canonical {
+				t.Errorf("Canonical = %q, want %q", extracted.Canonical, tt.canonical)
+			}
+			for _, text := range tt.contains {
+				if !strings.Contains(extracted.Content, text) {
+					t.Errorf("Content is missing %q, got %q", text, extracted.Content)
+				}
+			}
+			for _, text := range tt.excludes {
+				if strings.Contains(extracted.Content, text) {
+					t.Errorf("Content has boilerplate %q, got %q", text, extracted.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestTopCandidateBreaksTiesOnDocumentOrder(t *testing.T) {
+	paragraph := func(topic string) string {
+		return "<p>" + strings.Repeat(topic+" news from the wire desk ", 15) + "</p>"
+	}
+	page := `<html><body><div id="first">` + paragraph("Lagos") + `</div><div id="second">` + paragraph("Kano!") + `</div></body></html>`
+
+	for i := 0; i < 50; i++ {
+		doc, err := html.Parse(strings.NewReader(page))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		candidate := topCandidate(doc)
+		if candidate == nil || attr(candidate, "id") != "first" {
+			t.Fatalf("topCandidate() = %v, want the first of two equal divs", candidate)
+		}
+	}
+}
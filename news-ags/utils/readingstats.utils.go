@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// ReadingStats describes how long and how hard a text is to read
+type ReadingStats struct {
+	Words     int
+	Sentences int
+	Syllables int
+}
+
+// MeasureText counts the words, sentences and syllables of a text. Syllables
+// are estimated from vowel groups, which holds up for english
+func MeasureText(text string) ReadingStats {
+	stats := ReadingStats{}
+	for _, sentence := range SplitSentences(text) {
+		words := strings.FieldsFunc(sentence, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\'' && r != '’' && r != '-'
+		})
+
+		counted := 0
+		for _, word := range words {
+			if strings.Trim(word, "'’-") == "" {
+				continue
+			}
+			counted++
+			stats.Syllables += syllables(word)
+		}
+		if counted > 0 {
+			stats.Words += counted
+			stats.Sentences++
+		}
+	}
+
+	return stats
+}
+
+// ReadingMinutes estimates the minutes needed to read the text, at least one
+// for any text
+func (rs ReadingStats) ReadingMinutes(wordsPerMinute int) int {
+	if rs.Words == 0 || wordsPerMinute <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(float64(rs.Words) / float64(wordsPerMinute)))
+}
+
+// ReadingEase is the Flesch reading ease, higher is easier and 60 to 70 reads
+// like plain english
+func (rs ReadingStats) ReadingEase() float64 {
+	if rs.Words == 0 || rs.Sentences == 0 {
+		return 0
+	}
+
+	words := float64(rs.Words)
+	return 206.835 - 1.015*words/float64(rs.Sentences) - 84.6*float64(rs.Syllables)/words
+}
+
+// GradeLevel is the Flesch-Kincaid grade, the years of schooling needed to
+// follow the text
+func (rs ReadingStats) GradeLevel() float64 {
+	if rs.Words == 0 || rs.Sentences == 0 {
+		return 0
+	}
+
+	words := float64(rs.Words)
+	return 0.39*words/float64(rs.Sentences) + 11.8*float64(rs.Syllables)/words - 15.59
+}
+
+// syllables estimates the syllables of a word by counting its vowel groups,
+// a silent final e is not counted
+func syllables(word string) int {
+	word = strings.ToLower(word)
+	runes := []rune(word)
+
+	count := 0
+	previousVowel := false
+	for _, r := range runes {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !previousVowel {
+			count++
+		}
+		previousVowel = vowel
+	}
+
+	if len(runes) > 2 && strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") && count > 1 {
+		count--
+	}
+	if count == 0 {
+		count = 1
+	}
+
+	return count
+}
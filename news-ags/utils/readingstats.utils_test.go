@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSyllables(t *testing.T) {
+	tests := map[string]int{
+		"cat":         1,
+		"the":         1,
+		"make":        1,
+		"table":       2,
+		"HELLO":       2,
+		"happy":       2,
+		"beautiful":   3,
+		"readability": 5,
+		"rhythm":      1,
+		"queue":       1,
+		"strengths":   1,
+		"don't":       1,
+		"well-known":  2,
+		"2024":        1,
+	}
+	for word, want := range tests {
+		if got := syllables(word); got != want {
+			t.Errorf("syllables(%q) = %d, want %d", word, got, want)
+		}
+	}
+}
+
+func TestMeasureText(t *testing.T) {
+	tests := []struct {
+		text string
+		want ReadingStats
+	}{
+		{text: "", want: ReadingStats{}},
+		{text: "... !!", want: ReadingStats{}},
+		{text: "The cat sat. It was happy!", want: ReadingStats{Words: 6, Sentences: 2, Syllables: 7}},
+		{text: "Dr. Obi spoke.\nThe well-known table broke", want: ReadingStats{Words: 7, Sentences: 2, Syllables: 10}},
+		// the digits of a decimal number count as words of their own
+		{text: "Prices rose 2.5 percent - again.", want: ReadingStats{Words: 6, Sentences: 1, Syllables: 9}},
+	}
+	for _, tt := range tests {
+		if got := MeasureText(tt.text); got != tt.want {
+			t.Errorf("MeasureText(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestReadingFormulas(t *testing.T) {
+	tests := []struct {
+		stats ReadingStats
+		ease  float64
+		grade float64
+	}{
+		{stats: ReadingStats{}, ease: 0, grade: 0},
+		{stats: ReadingStats{Words: 6}, ease: 0, grade: 0},
+		{stats: ReadingStats{Words: 6, Sentences: 2, Syllables: 7}, ease: 105.09, grade: -0.6533},
+		{stats: ReadingStats{Words: 100, Sentences: 5, Syllables: 150}, ease: 59.635, grade: 9.91},
+	}
+	for _, tt := range tests {
+		if got := tt.stats.ReadingEase(); math.Abs(got-tt.ease) > 0.001 {
+			t.Errorf("%+v.ReadingEase() = %.4f, want %.4f", tt.stats, got, tt.ease)
+		}
+		if got := tt.stats.GradeLevel(); math.Abs(got-tt.grade) > 0.001 {
+			t.Errorf("%+v.GradeLevel() = %.4f, want %.4f", tt.stats, got, tt.grade)
+		}
+	}
+}
+
+func TestReadingMinutes(t *testing.T) {
+	tests := []struct {
+		words, perMinute, want int
+	}{
+		{words: 0, perMinute: 200, want: 0},
+		{words: 1, perMinute: 200, want: 1},
+		{words: 200, perMinute: 200, want: 1},
+		{words: 201, perMinute: 200, want: 2},
+		{words: 500, perMinute: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := (ReadingStats{Words: tt.words}).ReadingMinutes(tt.perMinute); got != tt.want {
+			t.Errorf("ReadingMinutes(%d words at %d) = %d, want %d", tt.words, tt.perMinute, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+	matcher *regexp.Regexp
+}
+
+// RobotsRules holds the robots.txt rules that apply to one user agent
+type RobotsRules struct {
+	rules []robotsRule
+}
+
+// ParseRobots reads a robots.txt body and keeps the group matching the agent,
+// falling back to the * group. A matching group wins even when it has no
+// rules, which allows everything
+func ParseRobots(body []byte, agent string) *RobotsRules {
+	agent = strings.ToLower(agent)
+
+	var (
+		specific, wildcard []robotsRule
+		groupAgents        []string
+		inRules, matched   bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// a user-agent line after rules starts a new group
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgent := strings.ToLower(value)
+			if groupAgent != "" && groupAgent != "*" && strings.Contains(agent, groupAgent) {
+				matched = true
+			}
+			groupAgents = append(groupAgents, groupAgent)
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+
+			rule := robotsRule{allow: key == "allow", pattern: value, matcher: robotsMatcher(value)}
+			for _, groupAgent := range groupAgents {
+				if groupAgent == "*" {
+					wildcard = append(wildcard, rule)
+				} else if groupAgent != "" && strings.Contains(agent, groupAgent) {
+					specific = append(specific, rule)
+				}
+			}
+		}
+	}
+
+	if matched {
+		return &RobotsRules{rules: specific}
+	}
+
+	return &RobotsRules{rules: wildcard}
+}
+
+// Allowed applies the longest matching rule, allow wins a tie
+func (rr *RobotsRules) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+
+	allowed := true
+	longest := -1
+	for _, rule := range rr.rules {
+		if !rule.matcher.MatchString(path) {
+			continue
+		}
+
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			longest = len(rule.pattern)
+			allowed = rule.allow
+		}
+	}
+
+	return allowed
+}
+
+// robotsMatcher turns a robots path pattern with * and $ into a regexp
+func robotsMatcher(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+
+	return regexp.MustCompile(expr)
+}
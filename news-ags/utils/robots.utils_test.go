@@ -0,0 +1,126 @@
+package utils
+
+import "testing"
+
+func TestRobotsAllowed(t *testing.T) {
+	const agent = "NewsAgsBot/1.0 (+https://news-ags.example/bot)"
+
+	tests := []struct {
+		name    string
+		robots  string
+		path    string
+		allowed bool
+	}{
+		{
+			name:    "no rules",
+			robots:  "",
+			path:    "/news/story",
+			allowed: true,
+		},
+		{
+			name:    "wildcard disallow",
+			robots:  "User-agent: *\nDisallow: /private/\n",
+			path:    "/private/draft",
+			allowed: false,
+		},
+		{
+			name:    "longest match allows",
+			robots:  "User-agent: *\nDisallow: /news/\nAllow: /news/public/\n",
+			path:    "/news/public/story",
+			allowed: true,
+		},
+		{
+			name:    "longest match disallows",
+			robots:  "User-agent: *\nAllow: /news/\nDisallow: /news/drafts/\n",
+			path:    "/news/drafts/story",
+			allowed: false,
+		},
+		{
+			name:    "allow wins a tie",
+			robots:  "User-agent: *\nDisallow: /page\nAllow: /page\n",
+			path:    "/page",
+			allowed: true,
+		},
+		{
+			name:    "star matches any run",
+			robots:  "User-agent: *\nDisallow: /*/print\n",
+			path:    "/2023/12/story/print",
+			allowed: false,
+		},
+		{
+			name:    "dollar anchors the end",
+			robots:  "User-agent: *\nDisallow: /*.pdf$\n",
+			path:    "/reports/annual.pdf",
+			allowed: false,
+		},
+		{
+			name:    "dollar does not match a longer path",
+			robots:  "User-agent: *\nDisallow: /*.pdf$\n",
+			path:    "/reports/annual.pdf?download=1",
+			allowed: true,
+		},
+		{
+			name:    "pattern is a prefix without dollar",
+			robots:  "User-agent: *\nDisallow: /search\n",
+			path:    "/search-results",
+			allowed: false,
+		},
+		{
+			name:    "empty path is the root",
+			robots:  "User-agent: *\nDisallow: /\n",
+			path:    "",
+			allowed: false,
+		},
+		{
+			name:    "agent group replaces the wildcard group",
+			robots:  "User-agent: *\nDisallow: /\n\nUser-agent: newsagsbot\nDisallow: /drafts/\n",
+			path:    "/news/story",
+			allowed: true,
+		},
+		{
+			name:    "agent group rules apply",
+			robots:  "User-agent: *\nDisallow: /drafts/\n\nUser-agent: NewsAgsBot\nDisallow: /news/\n",
+			path:    "/news/story",
+			allowed: false,
+		},
+		{
+			name:    "empty agent group allows everything",
+			robots:  "User-agent: *\nDisallow: /\n\nUser-agent: newsagsbot\nDisallow:\n",
+			path:    "/news/story",
+			allowed: true,
+		},
+		{
+			name:    "agent group without rules allows everything",
+			robots:  "User-agent: *\nDisallow: /\n\nUser-agent: newsagsbot\n",
+			path:    "/news/story",
+			allowed: true,
+		},
+		{
+			name:    "other agent group is ignored",
+			robots:  "User-agent: otherbot\nDisallow: /\n\nUser-agent: *\nDisallow: /private/\n",
+			path:    "/news/story",
+			allowed: true,
+		},
+		{
+			name:    "agents share a group",
+			robots:  "User-agent: otherbot\nUser-agent: newsagsbot\nDisallow: /news/\n",
+			path:    "/news/story",
+			allowed: false,
+		},
+		{
+			name:    "comments and case are ignored",
+			robots:  "# crawl rules\nUSER-AGENT: *  # everyone\nDISALLOW: /tmp/ # scratch\n",
+			path:    "/tmp/file",
+			allowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := ParseRobots([]byte(tt.robots), agent)
+			if got := rules.Allowed(tt.path); got != tt.allowed {
+				t.Errorf("Allowed(%q) = %v, want %v", tt.path, got, tt.allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// sentimentSmoothing pulls articles with few opinion words towards neutral
+	sentimentSmoothing = 5
+	// negationReach is how many words back a negation flips a sentiment word
+	negationReach = 3
+	// negatedWeight scales a flipped word, not good is milder than bad
+	negatedWeight     = -0.5
+	intensifierWeight = 1.5
+)
+
+// sentimentLexicon scores words from -5 to 5, word:score pairs in the style of AFINN
+var sentimentLexicon = map[string]float64{}
+
+var (
+	negations    = wordSet(`not no never none nobody nothing neither nor without hardly barely cannot`)
+	intensifiers = wordSet(`very extremely highly deeply seriously severely massively hugely greatly badly
+		particularly especially incredibly totally utterly`)
+)
+
+func init() {
+	for _, pair := range strings.Fields(`
+		abandon:-2 abducted:-3 abduction:-3 abuse:-3 abused:-3 accident:-2 accidents:-2 accused:-2
+		achieve:2 achieved:2 achievement:3 acquitted:2 admire:3 agree:1 agreed:1 agreement:2 alarm:-2
+		alarming:-3 anger:-3 angry:-3 anxiety:-2 anxious:-2 applaud:2 applauded:2 approve:2 approved:2
+		arrest:-2 arrested:-2 arson:-3 assault:-3 attack:-3 attacked:-3 attacks:-3 award:3 awarded:3
+		bad:-3 ban:-2 banned:-2 bankrupt:-3 bankruptcy:-3 beat:1 benefit:2 benefits:2 best:3 better:2
+		bleak:-2 blast:-2 blame:-2 blamed:-2 bloody:-3 bomb:-3 bombing:-3 boost:2 boosted:2 brave:2
+		breakthrough:3 bribe:-3 bribery:-3 bright:1 brutal:-3 burglary:-2 celebrate:3 celebrated:3
+		celebration:3 champion:2 champions:2 chaos:-3 cheer:2 clash:-2 clashes:-2 collapse:-3 collapsed:-3
+		concern:-1 concerned:-1 concerns:-1 condemn:-2 condemned:-2 conflict:-2 confident:2 congratulate:2
+		corrupt:-3 corruption:-3 crash:-3 crashed:-3 crime:-3 crimes:-2 crisis:-3 critical:-2 criticise:-2
+		criticised:-2 criticism:-2 criticize:-2 criticized:-2 cruel:-3 cut:-1 cuts:-1 damage:-3 damaged:-3
+		danger:-2 dangerous:-2 dead:-3 deadly:-3 death:-2 deaths:-2 debt:-2 decline:-2 declined:-2
+		defeat:-2 defeated:-2 deficit:-2 delay:-1 delayed:-1 delight:3 delighted:3 deny:-1 denied:-1
+		desperate:-3 destroy:-3 destroyed:-3 destruction:-3 devastated:-3 devastating:-3 died:-3
+		disappointed:-2 disappointing:-2 disaster:-3 disease:-2 displaced:-2 dispute:-2 disrupt:-2
+		disruption:-2 doubt:-1 downturn:-2 drought:-2 drown:-3 drowned:-3 earthquake:-3 easing:1
+		efficient:2 emergency:-2 encourage:2 encouraging:2 enjoy:2 epidemic:-3 excellent:3 excited:3
+		exciting:3 execution:-3 explosion:-3 fail:-2 failed:-2 failing:-2 failure:-3 fake:-3 famine:-3
+		fatal:-3 fear:-2 fears:-2 fight:-1 fighting:-2 fine:1 fire:-2 flood:-2 flooding:-2 floods:-2
+		fraud:-3 free:1 freed:2 fresh:1 friendly:2 gain:2 gains:2 generous:2 glad:3 good:3 great:3
+		grief:-2 grow:1 growth:2 guilty:-3 gunmen:-3 happy:3 harm:-2 hate:-3 healthy:2 help:2 helped:2
+		hero:2 heroes:2 homeless:-2 hope:2 hopeful:2 hopes:2 hostage:-3 hostages:-3 hurt:-2 illegal:-2
+		improve:2 improved:2 improvement:2 improving:2 inflation:-1 injured:-2 injuries:-2 injury:-2
+		innocent:2 innovation:2 innovative:2 inspire:2 inspiring:3 insecurity:-3 invasion:-3 jail:-2
+		jailed:-2 joy:3 kidnap:-3 kidnapped:-3 kidnapping:-3 kill:-3 killed:-3 killing:-3 killings:-3
+		laid:-1 launch:1 launched:1 layoffs:-2 lead:1 lose:-2 loss:-3 losses:-2 lost:-2 love:3 lucky:3
+		massacre:-3 milestone:2 misery:-3 missing:-2 murder:-3 murdered:-3 negative:-2 optimism:2
+		optimistic:2 outage:-2 outbreak:-3 outrage:-3 pandemic:-3 panic:-3 peace:2 peaceful:2 plunge:-2
+		plunged:-2 pollution:-2 poor:-2 positive:2 poverty:-2 praise:3 praised:3 prosper:2 prosperity:2
+		protect:1 protest:-1 protests:-1 proud:2 rally:1 rallied:2 rape:-4 rebound:2 rebounded:2
+		recession:-3 record:1 recover:2 recovered:2 recovery:2 reform:1 refugee:-1 refugees:-1 reject:-1
+		rejected:-1 relief:2 rescue:2 rescued:2 resign:-1 resigned:-1 restore:2 restored:2 riot:-3
+		riots:-3 rise:1 risk:-2 risks:-2 rob:-2 robbery:-2 safe:1 safety:1 scam:-3 scandal:-3 shock:-2
+		shocked:-2 shooting:-3 shortage:-2 shortages:-2 slump:-2 smile:2 smooth:1 soar:2 soared:2
+		solve:1 solved:1 strike:-1 strikes:-1 strong:2 struggle:-2 struggling:-2 succeed:3 success:2
+		successful:3 suffer:-2 suffering:-2 suicide:-3 support:2 supported:2 surge:1 surged:1
+		suspended:-1 sustainable:2 terror:-3 terrorism:-3 terrorist:-3 terrorists:-3 theft:-2 thrive:2
+		threat:-2 threaten:-2 threatened:-2 threats:-2 torture:-4 tragedy:-3 tragic:-3 triumph:4
+		trouble:-2 unemployment:-2 unrest:-2 upgrade:1 victim:-3 victims:-3 victory:3 violence:-3
+		violent:-3 volatile:-2 vulnerable:-2 war:-2 warn:-2 warned:-2 warning:-3 weak:-2 welcome:2
+		welcomed:2 win:4 winner:4 wins:4 won:3 worried:-3 worry:-3 worse:-3 worst:-3 wound:-2
+		wounded:-2`) {
+		word, score, _ := strings.Cut(pair, ":")
+		value, err := strconv.ParseFloat(score, 64)
+		if err != nil {
+			panic("invalid sentiment lexicon entry " + pair)
+		}
+		sentimentLexicon[word] = value
+	}
+}
+
+// SentimentScore adds up the lexicon scores of a text into raw, the sum of
+// the scores, and magnitude, the sum of their absolute values. Words after a
+// negation are flipped and words after an intensifier amplified
+func SentimentScore(text string, weight float64, limit int) (raw, magnitude float64) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\'' && r != '’'
+	})
+	if limit > 0 && len(words) > limit {
+		words = words[:limit]
+	}
+
+	for i, word := range words {
+		score, ok := sentimentLexicon[word]
+		if !ok {
+			continue
+		}
+
+		for back := 1; back <= negationReach && i-back >= 0; back++ {
+			previous := words[i-back]
+			if negations[previous] || strings.HasSuffix(previous, "n't") || strings.HasSuffix(previous, "n’t") {
+				score *= negatedWeight
+				break
+			}
+		}
+		if i > 0 && intensifiers[words[i-1]] {
+			score *= intensifierWeight
+		}
+
+		raw += score * weight
+		magnitude += math.Abs(score) * weight
+	}
+
+	return raw, magnitude
+}
+
+// Sentiment normalizes a raw score between -1 and 1, a text with few opinion
+// words stays close to neutral
+func Sentiment(raw, magnitude float64) float64 {
+	return raw / (magnitude + sentimentSmoothing)
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	textRankDamping    = 0.85
+	textRankIterations = 50
+	textRankTolerance  = 1e-6
+
+	// leadBias favours the opening sentences, news puts the gist first
+	leadBias = 0.5
+	// minSentenceTokens drops datelines, captions and other fragments
+	minSentenceTokens = 4
+)
+
+// SplitSentences splits text at sentence ends and line breaks. Abbreviated
+// titles, initials and decimal numbers do not end a sentence
+func SplitSentences(text string) []string {
+	sentences := make([]string, 0)
+	runes := []rune(text)
+
+	start := 0
+	emit := func(end int) {
+		sentence := strings.Join(strings.Fields(string(runes[start:end])), " ")
+		if sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = end
+	}
+
+	for i, r := range runes {
+		switch r {
+		case '\n':
+			emit(i)
+		case '.', '!', '?':
+			// keep closing quotes and brackets with the sentence they close
+			end := i + 1
+			for end < len(runes) && strings.ContainsRune(`"'”’)]`, runes[end]) {
+				end++
+			}
+			if end < len(runes) && !unicode.IsSpace(runes[end]) {
+				continue
+			}
+			if r == '.' && !sentenceEnd(runes[start:i]) {
+				continue
+			}
+			// the next sentence starts with a capital, a digit or a quote
+			next := end
+			for next < len(runes) && unicode.IsSpace(runes[next]) {
+				next++
+			}
+			if next < len(runes) && unicode.IsLower(runes[next]) {
+				continue
+			}
+			emit(end)
+		}
+	}
+	emit(len(runes))
+
+	return sentences
+}
+
+// sentenceEnd reports whether a full stop after text ends a sentence, it does
+// not after an abbreviated title or a single letter initial
+func sentenceEnd(text []rune) bool {
+	i := len(text)
+	for i > 0 && unicode.IsLetter(text[i-1]) {
+		i--
+	}
+	word := strings.ToLower(string(text[i:]))
+
+	if abbreviatedTitles[word] {
+		return false
+	}
+	return len([]rune(word)) != 1
+}
+
+// Summarize ranks sentences with TextRank and returns the indexes of the best
+// n in the order they appear. Sentences are linked by the words they share,
+// normalized by their lengths as in the original TextRank paper
+func Summarize(sentences []string, n int) []int {
+	if n <= 0 {
+		return nil
+	}
+
+	tokens := make([][]string, 0, len(sentences))
+	candidates := make([]int, 0, len(sentences))
+	for i, sentence := range sentences {
+		words := Tokenize(sentence)
+		if len(words) < minSentenceTokens {
+			continue
+		}
+		tokens = append(tokens, words)
+		candidates = append(candidates, i)
+	}
+	if len(candidates) <= n {
+		return candidates
+	}
+
+	size := len(candidates)
+	weights := make([][]float64, size)
+	totals := make([]float64, size)
+	for i := range weights {
+		weights[i] = make([]float64, size)
+	}
+	for i := 0; i < size; i++ {
+		for j := i + 1; j < size; j++ {
+			weight := sentenceOverlap(tokens[i], tokens[j])
+			weights[i][j], weights[j][i] = weight, weight
+			totals[i] += weight
+			totals[j] += weight
+		}
+	}
+
+	scores := make([]float64, size)
+	for i := range scores {
+		scores[i] = 1
+	}
+	for iteration := 0; iteration < textRankIterations; iteration++ {
+		change := 0.0
+		next := make([]float64, size)
+		for i := 0; i < size; i++ {
+			rank := 0.0
+			for j := 0; j < size; j++ {
+				if weights[j][i] > 0 {
+					rank += weights[j][i] / totals[j] * scores[j]
+				}
+			}
+			next[i] = 1 - textRankDamping + textRankDamping*rank
+			change += math.Abs(next[i] - scores[i])
+		}
+		scores = next
+		if change < textRankTolerance {
+			break
+		}
+	}
+
+	ranked := make([]int, size)
+	for i := range ranked {
+		ranked[i] = i
+		scores[i] *= 1 + leadBias/float64(i+1)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+
+	best := make([]int, 0, n)
+	for _, i := range ranked[:n] {
+		best = append(best, candidates[i])
+	}
+	sort.Ints(best)
+
+	return best
+}
+
+func sentenceOverlap(a, b []string) float64 {
+	words := make(map[string]bool, len(a))
+	for _, word := range a {
+		words[word] = true
+	}
+
+	shared := 0
+	for _, word := range uniqueTokens(b) {
+		if words[word] {
+			shared++
+		}
+	}
+	if shared == 0 {
+		return 0
+	}
+
+	return float64(shared) / (math.Log(float64(len(a))) + math.Log(float64(len(b))))
+}
+
+func uniqueTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	unique := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			unique = append(unique, token)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+var stopwords = map[string]struct{}{}
+
+func init() {
+	for _, word := range strings.Fields(`a about above after again against all am an and any are as at be because been
+		before being below between both but by can could did do does doing down during each few for from further had
+		has have having he her here hers herself him himself his how i if in into is it its itself just me more most
+		my myself no nor not now of off on once only or other our ours ourselves out over own same she should so some
+		such than that the their theirs them themselves then there these they this those through to too under until
+		up very was we were what when where which while who whom why will with would you your yours yourself
+		yourselves said says also new one two us mr mrs ms`) {
+		stopwords[word] = struct{}{}
+	}
+}
+
+// Tokenize lowercases text and splits it into words, dropping stopwords and single characters
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		if len([]rune(word)) < 2 {
+			continue
+		}
+		if _, ok := stopwords[word]; ok {
+			continue
+		}
+		tokens = append(tokens, word)
+	}
+
+	return tokens
+}
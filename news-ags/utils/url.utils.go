@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// trackingParams are query parameters known to identify the campaign rather
+// than the article. Generic names such as ref or share are kept, some sites
+// use them to address the article itself
+var trackingParams = map[string]struct{}{
+	"fbclid": {}, "gclid": {}, "gbraid": {}, "wbraid": {}, "dclid": {}, "msclkid": {}, "yclid": {},
+	"twclid": {}, "igshid": {}, "mc_cid": {}, "mc_eid": {}, "_hsenc": {}, "_hsmi": {}, "mkt_tok": {},
+	"ref_src": {}, "cmpid": {}, "ocid": {}, "ncid": {}, "smid": {}, "soc_src": {}, "soc_trk": {},
+	"icid": {}, "_ga": {},
+}
+
+// CanonicalURL normalizes an article link so the same article always yields the
+// same key: lowercase host without www, no fragment, no tracking parameters,
+// sorted query and no trailing slash
+func CanonicalURL(raw string) string {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if port := parsed.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	query := parsed.Query()
+	for key := range query {
+		lower := strings.ToLower(key)
+		if _, ok := trackingParams[lower]; ok || strings.HasPrefix(lower, "utm_") {
+			query.Del(key)
+		}
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			values = append(values, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+
+	path := strings.TrimRight(parsed.EscapedPath(), "/")
+	canonical := "https://" + host + path
+	if len(values) > 0 {
+		canonical += "?" + strings.Join(values, "&")
+	}
+
+	return canonical
+}
+
+// CanonicalKey identifies an article by its canonical url, falling back to a
+// hash of the source and title when the link is missing or unusable
+func CanonicalKey(link, source, title string) string {
+	if canonical := CanonicalURL(link); canonical != "" {
+		return canonical
+	}
+
+	normalized := strings.Join(strings.Fields(strings.ToLower(title)), " ")
+	sum := sha1.Sum([]byte(strings.ToLower(source) + "|" + normalized))
+
+	return "hash:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "https://www.Example.com/news/story/", want: "https://example.com/news/story"},
+		{raw: "  http://example.com:80/news/story#comments ", want: "https://example.com/news/story"},
+		{raw: "https://example.com:8443/news/story", want: "https://example.com:8443/news/story"},
+		{raw: "https://example.com/news/story?utm_source=rss&UTM_Medium=feed&fbclid=abc&gclid=def", want: "https://example.com/news/story"},
+		{raw: "https://example.com/news?b=2&a=1&a=0&mc_cid=x&_ga=y", want: "https://example.com/news?a=1&a=0&b=2"},
+		// generic names may address the article and are kept
+		{raw: "https://example.com/read?ref=politics-123&share=abc", want: "https://example.com/read?ref=politics-123&share=abc"},
+		{raw: "https://example.com/read?id=42&ref_src=twsrc", want: "https://example.com/read?id=42"},
+		{raw: "https://example.com/search?q=lagos+rail", want: "https://example.com/search?q=lagos+rail"},
+		{raw: "https://example.com/caf%C3%A9/", want: "https://example.com/caf%C3%A9"},
+		{raw: "/news/story", want: ""},
+		{raw: "not a url", want: ""},
+		{raw: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := CanonicalURL(tt.raw); got != tt.want {
+			t.Errorf("CanonicalURL(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalKey(t *testing.T) {
+	if got := CanonicalKey("https://www.example.com/a/?utm_campaign=x", "punch", "Title"); got != "https://example.com/a" {
+		t.Errorf("CanonicalKey with a link = %q, want the canonical url", got)
+	}
+
+	hashed := CanonicalKey("", "Punch", "  Rail  line OPENS ")
+	if !strings.HasPrefix(hashed, "hash:") || len(hashed) != len("hash:")+40 {
+		t.Fatalf("CanonicalKey without a link = %q, want a sha1 hash", hashed)
+	}
+	if same := CanonicalKey("/relative", "punch", "rail line opens"); same != hashed {
+		t.Errorf("CanonicalKey = %q, want %q for the same source and title in another case and spacing", same, hashed)
+	}
+	if other := CanonicalKey("", "guardian", "rail line opens"); other == hashed {
+		t.Error("CanonicalKey gave two sources the same key for one title")
+	}
+}